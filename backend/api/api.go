@@ -0,0 +1,150 @@
+// Package api holds the request and response bodies of the HTTP API. The handlers encode
+// and decode these types and the client package reuses them, so the two cannot drift.
+// Types owned by internal packages are re-exported as aliases.
+package api
+
+import (
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/featureflags"
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/problem"
+)
+
+type (
+	// User is a user as seen by admins.
+	User = models.User
+	// Category is a listing category.
+	Category = models.Category
+	// AuditEvent is an audit log entry.
+	AuditEvent = audit.StoredEvent
+	// UserFilter selects users for SearchUsers. Zero fields are not applied.
+	UserFilter = models.UserFilter
+	// AuditFilter selects audit events. Zero fields are not applied.
+	AuditFilter = audit.Filter
+	// FeatureFlag is a feature flag and its targeting rules.
+	FeatureFlag = featureflags.Flag
+	// Problem is the RFC 9457 body of every error response.
+	Problem = problem.Problem
+	// FieldError is one invalid field of a validation.failed problem.
+	FieldError = problem.FieldError
+)
+
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	FullName string `json:"full_name"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Email       string `json:"email"`
+	OTP         string `json:"otp"`
+	NewPassword string `json:"new_password"`
+}
+
+// UpdateProfileRequest changes the fields that are set. An empty PhotoURL removes the
+// photo.
+type UpdateProfileRequest struct {
+	FullName *string `json:"full_name"`
+	City     *string `json:"city"`
+	Bio      *string `json:"bio"`
+	PhotoURL *string `json:"photo_url"`
+}
+
+type AuthResponse struct {
+	Token string     `json:"token"`
+	User  PublicUser `json:"user"`
+}
+
+// PublicUser is the signed-in user's own view of their account.
+type PublicUser struct {
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
+	City     string `json:"city,omitempty"`
+	Bio      string `json:"bio,omitempty"`
+	PhotoURL string `json:"photo_url,omitempty"`
+	Role     string `json:"role"`
+}
+
+type UserResponse struct {
+	User PublicUser `json:"user"`
+	// Flags are the feature flags evaluated for the user. Only GET /auth/me sets them.
+	Flags map[string]bool `json:"flags,omitempty"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type CategoryList struct {
+	Categories []Category `json:"categories"`
+}
+
+type CategoryResponse struct {
+	Category Category `json:"category"`
+}
+
+type CreateCategoryRequest struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID string `json:"parent_id"`
+}
+
+// UpdateCategoryRequest changes the fields that are set.
+type UpdateCategoryRequest struct {
+	Name     *string `json:"name"`
+	Slug     *string `json:"slug"`
+	ParentID *string `json:"parent_id"`
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type SuspendUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+type AdminUserResponse struct {
+	User User `json:"user"`
+}
+
+type UserPage struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+type AuditEventPage struct {
+	Events []AuditEvent `json:"events"`
+	Total  int          `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+type FeatureFlagList struct {
+	Flags []FeatureFlag `json:"flags"`
+}
+
+type FeatureFlagResponse struct {
+	Flag FeatureFlag `json:"flag"`
+}
+
+// UpdateFeatureFlagRequest changes the targeting fields that are set. Lists replace the
+// stored ones.
+type UpdateFeatureFlagRequest struct {
+	Enabled           *bool     `json:"enabled"`
+	RolloutPercentage *int      `json:"rollout_percentage"`
+	AllowedUserIDs    *[]string `json:"allowed_user_ids"`
+	Cities            *[]string `json:"cities"`
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"resellution/backend/api"
+)
+
+func (c *Client) CreateCategory(ctx context.Context, req api.CreateCategoryRequest) (api.Category, error) {
+	var resp api.CategoryResponse
+	err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/admin/categories", body: req, auth: true}, &resp)
+	return resp.Category, err
+}
+
+func (c *Client) UpdateCategory(ctx context.Context, id string, req api.UpdateCategoryRequest) (api.Category, error) {
+	var resp api.CategoryResponse
+	err := c.do(ctx, call{method: http.MethodPatch, path: "/api/v1/admin/categories/" + url.PathEscape(id), body: req, auth: true}, &resp)
+	return resp.Category, err
+}
+
+func (c *Client) DeleteCategory(ctx context.Context, id string) error {
+	return c.do(ctx, call{method: http.MethodDelete, path: "/api/v1/admin/categories/" + url.PathEscape(id), auth: true}, nil)
+}
+
+func (c *Client) SearchUsers(ctx context.Context, filter api.UserFilter) (api.UserPage, error) {
+	query := url.Values{}
+	setQuery(query, "email", filter.Email)
+	setQuery(query, "phone", filter.Phone)
+	setQuery(query, "city", filter.City)
+	setQueryTime(query, "created_after", filter.CreatedAfter)
+	setQueryTime(query, "created_before", filter.CreatedBefore)
+	if filter.IncludeDeleted {
+		query.Set("include_deleted", "true")
+	}
+	setPagination(query, filter.Limit, filter.Offset)
+
+	var resp api.UserPage
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/admin/users", query: query, auth: true}, &resp)
+	return resp, err
+}
+
+func (c *Client) GetUser(ctx context.Context, id string) (api.User, error) {
+	var resp api.AdminUserResponse
+	err := c.do(ctx, call{method: http.MethodGet, path: userPath(id, ""), auth: true}, &resp)
+	return resp.User, err
+}
+
+func (c *Client) SetUserRole(ctx context.Context, id, role string) error {
+	return c.do(ctx, call{method: http.MethodPut, path: userPath(id, "/role"), body: api.SetRoleRequest{Role: role}, auth: true}, nil)
+}
+
+func (c *Client) DeactivateUser(ctx context.Context, id string) error {
+	return c.userAction(ctx, id, "/deactivate", nil)
+}
+
+func (c *Client) RestoreUser(ctx context.Context, id string) error {
+	return c.userAction(ctx, id, "/restore", nil)
+}
+
+// SuspendUser suspends the user; reason may be empty.
+func (c *Client) SuspendUser(ctx context.Context, id, reason string) error {
+	return c.userAction(ctx, id, "/suspend", api.SuspendUserRequest{Reason: reason})
+}
+
+func (c *Client) UnsuspendUser(ctx context.Context, id string) error {
+	return c.userAction(ctx, id, "/unsuspend", nil)
+}
+
+func (c *Client) ForcePasswordReset(ctx context.Context, id string) error {
+	return c.userAction(ctx, id, "/force-password-reset", nil)
+}
+
+func (c *Client) RevokeSessions(ctx context.Context, id string) error {
+	return c.userAction(ctx, id, "/revoke-sessions", nil)
+}
+
+func (c *Client) VerifyEmail(ctx context.Context, id string) error {
+	return c.userAction(ctx, id, "/verify-email", nil)
+}
+
+func (c *Client) ListAuditEvents(ctx context.Context, filter api.AuditFilter) (api.AuditEventPage, error) {
+	query := url.Values{}
+	setQuery(query, "actor_id", filter.ActorID)
+	setQuery(query, "action", filter.Action)
+	setQuery(query, "target_type", filter.TargetType)
+	setQuery(query, "target_id", filter.TargetID)
+	setQueryTime(query, "from", filter.From)
+	setQueryTime(query, "to", filter.To)
+	setPagination(query, filter.Limit, filter.Offset)
+
+	var resp api.AuditEventPage
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/admin/audit-events", query: query, auth: true}, &resp)
+	return resp, err
+}
+
+func (c *Client) ListFeatureFlags(ctx context.Context) ([]api.FeatureFlag, error) {
+	var resp api.FeatureFlagList
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/admin/feature-flags", auth: true}, &resp)
+	return resp.Flags, err
+}
+
+func (c *Client) UpdateFeatureFlag(ctx context.Context, key string, req api.UpdateFeatureFlagRequest) (api.FeatureFlag, error) {
+	var resp api.FeatureFlagResponse
+	err := c.do(ctx, call{method: http.MethodPatch, path: "/api/v1/admin/feature-flags/" + url.PathEscape(key), body: req, auth: true}, &resp)
+	return resp.Flag, err
+}
+
+func (c *Client) userAction(ctx context.Context, id, action string, body any) error {
+	return c.do(ctx, call{method: http.MethodPost, path: userPath(id, action), body: body, auth: true}, nil)
+}
+
+func userPath(id, suffix string) string {
+	return "/api/v1/admin/users/" + url.PathEscape(id) + suffix
+}
+
+func setQuery(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+func setQueryTime(query url.Values, key string, value time.Time) {
+	if !value.IsZero() {
+		query.Set(key, value.Format(time.RFC3339))
+	}
+}
+
+func setPagination(query url.Values, limit, offset int) {
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"resellution/backend/api"
+)
+
+// Register creates an account and keeps the returned token for later calls.
+func (c *Client) Register(ctx context.Context, req api.RegisterRequest) (api.AuthResponse, error) {
+	var resp api.AuthResponse
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/auth/register", body: req}, &resp); err != nil {
+		return api.AuthResponse{}, err
+	}
+	c.SetToken(resp.Token)
+	return resp, nil
+}
+
+// Login signs in and keeps the returned token for later calls.
+func (c *Client) Login(ctx context.Context, req api.LoginRequest) (api.AuthResponse, error) {
+	var resp api.AuthResponse
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/auth/login", body: req}, &resp); err != nil {
+		return api.AuthResponse{}, err
+	}
+	c.SetToken(resp.Token)
+	return resp, nil
+}
+
+// Logout revokes the current session and forgets the token.
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/auth/logout", auth: true}, nil); err != nil {
+		return err
+	}
+	c.SetToken("")
+	return nil
+}
+
+func (c *Client) Me(ctx context.Context) (api.PublicUser, error) {
+	var resp api.UserResponse
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/auth/me", auth: true}, &resp)
+	return resp.User, err
+}
+
+// FeatureFlags returns the feature flags evaluated for the signed-in user, by key.
+func (c *Client) FeatureFlags(ctx context.Context) (map[string]bool, error) {
+	var resp api.UserResponse
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/auth/me", auth: true}, &resp)
+	return resp.Flags, err
+}
+
+// Profile is Me with the ETag of the returned version, which UpdateProfile and
+// DeactivateAccount require.
+func (c *Client) Profile(ctx context.Context) (api.PublicUser, string, error) {
+	var resp api.UserResponse
+	var etag string
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/auth/me", auth: true, etag: &etag}, &resp)
+	return resp.User, etag, err
+}
+
+// UpdateProfile changes the profile version etag and returns the new version and its
+// ETag. It fails with ErrPreconditionFailed when the profile changed since etag was
+// read; read it again with Profile and reapply the change.
+func (c *Client) UpdateProfile(ctx context.Context, etag string, req api.UpdateProfileRequest) (api.PublicUser, string, error) {
+	var resp api.UserResponse
+	var newETag string
+	err := c.do(ctx, call{method: http.MethodPatch, path: "/api/v1/users/me", body: req, auth: true, ifMatch: etag, etag: &newETag}, &resp)
+	return resp.User, newETag, err
+}
+
+// DeactivateAccount deletes the signed-in user's account, if it is still at version
+// etag, and forgets the token.
+func (c *Client) DeactivateAccount(ctx context.Context, etag string) error {
+	if err := c.do(ctx, call{method: http.MethodDelete, path: "/api/v1/users/me", auth: true, ifMatch: etag}, nil); err != nil {
+		return err
+	}
+	c.SetToken("")
+	return nil
+}
+
+// RequestPasswordReset emails a one-time code if the account exists. The API answers
+// the same either way.
+func (c *Client) RequestPasswordReset(ctx context.Context, email string) error {
+	return c.do(ctx, call{method: http.MethodPost, path: "/api/v1/auth/password/reset/request", body: api.PasswordResetRequest{Email: email}}, nil)
+}
+
+func (c *Client) ConfirmPasswordReset(ctx context.Context, req api.PasswordResetConfirmRequest) error {
+	return c.do(ctx, call{method: http.MethodPost, path: "/api/v1/auth/password/reset/confirm", body: req}, nil)
+}
+
+func (c *Client) ListCategories(ctx context.Context) ([]api.Category, error) {
+	var resp api.CategoryList
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/categories"}, &resp)
+	return resp.Categories, err
+}
@@ -0,0 +1,312 @@
+// Package client is a typed Go client for the ReSellution API, for tooling such as
+// importers, bots and load scripts. Request and response bodies are the api package
+// types the handlers use.
+//
+// The client retries rate-limited requests and, for idempotent methods, server errors
+// with exponential backoff, honouring Retry-After. With Credentials set it signs in on
+// demand and signs in again when its token expires or its session is revoked. Errors
+// from the API are *Error values that match the Err* sentinels with errors.Is.
+//
+// Listings, chat and notifications have no API routes yet; their methods will be added
+// with the routes.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"resellution/backend/api"
+)
+
+const (
+	defaultMaxRetries    = 3
+	defaultBaseBackoff   = 200 * time.Millisecond
+	defaultMaxBackoff    = 10 * time.Second
+	defaultRefreshBefore = time.Minute
+)
+
+type Client struct {
+	// BaseURL is the server root, such as http://localhost:8080.
+	BaseURL    string
+	HTTPClient *http.Client
+	// Credentials, when set, are used to sign in before the first authenticated call
+	// and again when the token expires or is rejected.
+	Credentials *api.LoginRequest
+	// MaxRetries is the number of retries after the first attempt. Negative disables
+	// retries; zero uses the default of 3.
+	MaxRetries int
+	// BaseBackoff and MaxBackoff bound the exponential backoff between retries when
+	// the server sends no Retry-After.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	UserAgent   string
+
+	mu    sync.Mutex
+	token string
+	// sleep waits between retries; tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New returns a client for the server at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// SetToken sets the bearer token used for authenticated calls.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+// Token returns the current bearer token.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// call describes one API request.
+type call struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	// auth sends the bearer token and allows signing in again on a 401.
+	auth bool
+	// ifMatch, when set, is sent as If-Match.
+	ifMatch string
+	// etag, when non-nil, receives the ETag of a successful response.
+	etag *string
+}
+
+// do sends c and decodes a successful JSON response into out, when out is non-nil.
+func (c *Client) do(ctx context.Context, req call, out any) error {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return err
+		}
+	}
+
+	signedIn := false
+	if req.auth && c.Credentials != nil && c.tokenExpiring() {
+		if err := c.signIn(ctx); err != nil {
+			return err
+		}
+		signedIn = true
+	}
+
+	for {
+		resp, err := c.send(ctx, req, payload)
+		if err != nil {
+			return err
+		}
+		err = decodeResponse(resp, out)
+		if err == nil && req.etag != nil {
+			*req.etag = resp.Header.Get("ETag")
+		}
+		var apiErr *Error
+		if req.auth && !signedIn && c.Credentials != nil && errors.As(err, &apiErr) && apiErr.tokenRejected() {
+			if err := c.signIn(ctx); err != nil {
+				return err
+			}
+			signedIn = true
+			continue
+		}
+		return err
+	}
+}
+
+// send performs the request with retries and returns the final response.
+func (c *Client) send(ctx context.Context, req call, payload []byte) (*http.Response, error) {
+	target := c.BaseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, target, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		if payload != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		httpReq.Header.Set("Accept", "application/json, application/problem+json")
+		if c.UserAgent != "" {
+			httpReq.Header.Set("User-Agent", c.UserAgent)
+		}
+		if req.ifMatch != "" {
+			httpReq.Header.Set("If-Match", req.ifMatch)
+		}
+		if token := c.Token(); req.auth && token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := c.httpClient().Do(httpReq)
+		retry, wait := c.shouldRetry(req.method, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := c.wait(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry retries 429 for every method, since the server did not act on the
+// request. Server errors and transport failures are retried only for idempotent
+// methods, so a POST is never applied twice.
+func (c *Client) shouldRetry(method string, resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	if attempt >= c.maxRetries() {
+		return false, 0
+	}
+	if err != nil {
+		return idempotent(method) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded), c.backoff(attempt)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode >= 500 && idempotent(method):
+	default:
+		return false, 0
+	}
+	if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		return true, wait
+	}
+	return true, c.backoff(attempt)
+}
+
+// backoff is exponential with full jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	base, max := c.BaseBackoff, c.MaxBackoff
+	if base <= 0 {
+		base = defaultBaseBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	d := base << attempt
+	if d <= 0 || d > max {
+		d = max
+	}
+	return time.Duration(rand.Int64N(int64(d) + 1))
+}
+
+func (c *Client) wait(ctx context.Context, d time.Duration) error {
+	if c.sleep != nil {
+		return c.sleep(ctx, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *Client) signIn(ctx context.Context) error {
+	_, err := c.Login(ctx, *c.Credentials)
+	return err
+}
+
+// tokenExpiring reports whether there is no token or it expires within a minute. The
+// expiry is read from the token without verifying it; the server still does.
+func (c *Client) tokenExpiring() bool {
+	token := c.Token()
+	if token == "" {
+		return true
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(raw, &claims); err != nil || claims.Exp == 0 {
+		return false
+	}
+	return time.Until(time.Unix(claims.Exp, 0)) < defaultRefreshBefore
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) maxRetries() int {
+	switch {
+	case c.MaxRetries < 0:
+		return 0
+	case c.MaxRetries == 0:
+		return defaultMaxRetries
+	}
+	return c.MaxRetries
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return newError(resp, body)
+	}
+	if out == nil || len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode %s response: %w", resp.Request.URL.Path, err)
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"resellution/backend/api"
+	"resellution/backend/internal/handlers"
+	"resellution/backend/internal/memstore"
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/problem"
+	"resellution/backend/internal/utils"
+)
+
+func newTestClient(t *testing.T, handler http.Handler) (*Client, *[]time.Duration) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	var waits []time.Duration
+	c := New(server.URL)
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return c, &waits
+}
+
+func writeProblem(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", problem.ContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(api.Problem{Status: status, Code: code, Detail: "detail", RequestID: "req-1"})
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		responses []int
+		retryTo   string
+		wantCalls int32
+		wantErr   error
+		wantWaits []time.Duration
+	}{
+		{name: "429 honours Retry-After", method: http.MethodPost, responses: []int{429, 429, 200}, retryTo: "2", wantCalls: 3, wantWaits: []time.Duration{2 * time.Second, 2 * time.Second}},
+		{name: "5xx retried for GET", method: http.MethodGet, responses: []int{503, 200}, wantCalls: 2},
+		{name: "5xx not retried for POST", method: http.MethodPost, responses: []int{500}, wantCalls: 1, wantErr: ErrInternal},
+		{name: "4xx not retried", method: http.MethodGet, responses: []int{404}, wantCalls: 1, wantErr: ErrUserNotFound},
+		{name: "gives up after MaxRetries", method: http.MethodGet, responses: []int{429, 429, 429, 429, 429}, wantCalls: 4, wantErr: ErrRateLimited},
+	}
+
+	codes := map[int]string{404: problem.CodeUserNotFound, 429: problem.CodeRateLimited, 500: problem.CodeInternal, 503: problem.CodeInternal}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			c, waits := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Method == http.MethodPost && string(body) != `{"role":"admin"}` {
+					t.Errorf("attempt %d body = %q", calls.Load()+1, body)
+				}
+				status := tt.responses[calls.Add(1)-1]
+				if status == http.StatusOK {
+					_, _ = w.Write([]byte(`{"message":"ok"}`))
+					return
+				}
+				if tt.retryTo != "" {
+					w.Header().Set("Retry-After", tt.retryTo)
+				}
+				writeProblem(w, status, codes[status])
+			}))
+
+			req := call{method: tt.method, path: "/x"}
+			if tt.method == http.MethodPost {
+				req.body = map[string]string{"role": "admin"}
+			}
+			err := c.do(context.Background(), req, nil)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			if tt.wantWaits != nil && len(*waits) != len(tt.wantWaits) {
+				t.Fatalf("waits = %v, want %v", *waits, tt.wantWaits)
+			}
+			for i, want := range tt.wantWaits {
+				if (*waits)[i] != want {
+					t.Fatalf("waits = %v, want %v", *waits, tt.wantWaits)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if got, ok := retryAfter("7"); !ok || got != 7*time.Second {
+		t.Fatalf("retryAfter(7) = %v, %v", got, ok)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got, ok := retryAfter(date); !ok || got <= 0 || got > time.Minute {
+		t.Fatalf("retryAfter(%q) = %v, %v", date, got, ok)
+	}
+	if _, ok := retryAfter("soon"); ok {
+		t.Fatal("retryAfter(soon) parsed")
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", problem.ContentType)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(problem.Validation(problem.Field("email", problem.FieldInvalid, "email is invalid")))
+	}))
+	c.MaxRetries = -1
+
+	_, err := c.Register(context.Background(), api.RegisterRequest{Email: "nope"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrValidation) || errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("err = %#v", err)
+	}
+	if field, ok := apiErr.Field("email"); !ok || field.Code != problem.FieldInvalid {
+		t.Fatalf("Field(email) = %+v, %v", field, ok)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.RetryAfter != time.Minute {
+		t.Fatalf("err = %+v", apiErr)
+	}
+}
+
+func TestPlainTextError(t *testing.T) {
+	c, _ := newTestClient(t, http.NotFoundHandler())
+	err := c.DeleteCategory(context.Background(), "x")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Code != "" || apiErr.Detail != "404 page not found" {
+		t.Fatalf("err = %#v", err)
+	}
+}
+
+func newAPIServer(t *testing.T) (*memstore.Store, http.Handler) {
+	t.Helper()
+	store := memstore.New()
+	tokens := utils.NewTokenManager("test-secret")
+	h := handlers.AuthHandler{
+		Users:            store.Users(),
+		PasswordResets:   store.Users(),
+		Sessions:         store.Sessions(),
+		Tx:               store,
+		Audit:            store.Audit(),
+		TokenManager:     tokens,
+		TokenExpiryHours: 1,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/register", h.Register)
+	mux.HandleFunc("POST /api/v1/auth/login", h.Login)
+	mux.HandleFunc("GET /api/v1/auth/me", middleware.Auth(tokens, store.Sessions(), h.Me))
+	mux.HandleFunc("POST /api/v1/auth/logout", middleware.Auth(tokens, store.Sessions(), h.Logout))
+	mux.HandleFunc("PATCH /api/v1/users/me", middleware.Auth(tokens, store.Sessions(), h.UpdateProfile))
+	return store, mux
+}
+
+func TestSignsInAgainWhenSessionRevoked(t *testing.T) {
+	store, handler := newAPIServer(t)
+	ctx := context.Background()
+
+	registrar, _ := newTestClient(t, handler)
+	registered, err := registrar.Register(ctx, api.RegisterRequest{Email: "ana@example.com", Password: "correct-horse-1", FullName: "Ana Lima"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := newTestClient(t, handler)
+	c.Credentials = &api.LoginRequest{Email: "ana@example.com", Password: "correct-horse-1"}
+	// The first authenticated call signs in on demand.
+	if me, err := c.Me(ctx); err != nil || me.ID != registered.User.ID {
+		t.Fatalf("Me() = %+v, %v", me, err)
+	}
+	first := c.Token()
+
+	if _, err := store.Sessions().RevokeAllByUserID(ctx, registered.User.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registrar.Me(ctx); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("Me() without credentials err = %v, want ErrSessionRevoked", err)
+	}
+	if me, err := c.Me(ctx); err != nil || me.Email != "ana@example.com" {
+		t.Fatalf("Me() after revocation = %+v, %v", me, err)
+	}
+	if c.Token() == first {
+		t.Fatal("token was not replaced")
+	}
+
+	c.Credentials.Password = "wrong-password"
+	c.SetToken("garbage")
+	if _, err := c.Me(ctx); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Me() with bad credentials err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestUpdateProfileChecksVersion(t *testing.T) {
+	_, handler := newAPIServer(t)
+	ctx := context.Background()
+
+	c, _ := newTestClient(t, handler)
+	if _, err := c.Register(ctx, api.RegisterRequest{Email: "ravi@example.com", Password: "correct-horse-1", FullName: "Ravi Rao"}); err != nil {
+		t.Fatal(err)
+	}
+	_, etag, err := c.Profile(ctx)
+	if err != nil || etag == "" {
+		t.Fatalf("Profile() etag %q, err %v", etag, err)
+	}
+
+	city := "Pune"
+	updated, newETag, err := c.UpdateProfile(ctx, etag, api.UpdateProfileRequest{City: &city})
+	if err != nil || updated.City != city || newETag == "" || newETag == etag {
+		t.Fatalf("UpdateProfile() = %+v, %q, %v", updated, newETag, err)
+	}
+	if _, _, err := c.UpdateProfile(ctx, etag, api.UpdateProfileRequest{City: &city}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("UpdateProfile() with a stale ETag err = %v, want ErrPreconditionFailed", err)
+	}
+	if _, _, err := c.UpdateProfile(ctx, "", api.UpdateProfileRequest{City: &city}); !errors.Is(err, ErrPreconditionRequired) {
+		t.Fatalf("UpdateProfile() without an ETag err = %v, want ErrPreconditionRequired", err)
+	}
+}
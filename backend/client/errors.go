@@ -0,0 +1,113 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"resellution/backend/api"
+	"resellution/backend/internal/problem"
+)
+
+// Error is an error response from the API. Code is the stable problem code; it is empty
+// when the response was not a problem document, such as a 404 for an unknown route.
+type Error struct {
+	StatusCode int
+	Code       string
+	Detail     string
+	RequestID  string
+	Fields     []api.FieldError
+	// RetryAfter is the server's Retry-After, when it sent one.
+	RetryAfter time.Duration
+}
+
+// Sentinels for errors.Is. They match any *Error with the same code.
+var (
+	ErrInvalidJSON           = &Error{Code: problem.CodeInvalidJSON}
+	ErrBodyTooLarge          = &Error{Code: problem.CodeBodyTooLarge}
+	ErrPreconditionRequired  = &Error{Code: problem.CodePreconditionRequired}
+	ErrPreconditionFailed    = &Error{Code: problem.CodePreconditionFailed}
+	ErrValidation            = &Error{Code: problem.CodeValidationFailed}
+	ErrUnauthenticated       = &Error{Code: problem.CodeUnauthenticated}
+	ErrInvalidToken          = &Error{Code: problem.CodeInvalidToken}
+	ErrSessionRevoked        = &Error{Code: problem.CodeSessionRevoked}
+	ErrInvalidCredentials    = &Error{Code: problem.CodeInvalidCredentials}
+	ErrAccountSuspended      = &Error{Code: problem.CodeAccountSuspended}
+	ErrPasswordResetRequired = &Error{Code: problem.CodePasswordResetRequired}
+	ErrForbidden             = &Error{Code: problem.CodeForbidden}
+	ErrEmailTaken            = &Error{Code: problem.CodeEmailTaken}
+	ErrUserNotFound          = &Error{Code: problem.CodeUserNotFound}
+	ErrSelfAction            = &Error{Code: problem.CodeSelfAction}
+	ErrCategoryNotFound      = &Error{Code: problem.CodeCategoryNotFound}
+	ErrCategoryConflict      = &Error{Code: problem.CodeCategoryConflict}
+	ErrInvalidOTP            = &Error{Code: problem.CodeInvalidOTP}
+	ErrResetCooldown         = &Error{Code: problem.CodeResetCooldown}
+	ErrRateLimited           = &Error{Code: problem.CodeRateLimited}
+	ErrIdempotencyInProgress = &Error{Code: problem.CodeIdempotencyInProgress}
+	ErrIdempotencyKeyReused  = &Error{Code: problem.CodeIdempotencyKeyReused}
+	ErrFeatureDisabled       = &Error{Code: problem.CodeFeatureDisabled}
+	ErrFeatureFlagNotFound   = &Error{Code: problem.CodeFeatureFlagNotFound}
+	ErrInternal              = &Error{Code: problem.CodeInternal}
+)
+
+func (e *Error) Error() string {
+	switch {
+	case e.Code != "" && e.Detail != "":
+		return fmt.Sprintf("api: %d %s: %s", e.StatusCode, e.Code, e.Detail)
+	case e.Code != "":
+		return fmt.Sprintf("api: %d %s", e.StatusCode, e.Code)
+	case e.Detail != "":
+		return fmt.Sprintf("api: %d: %s", e.StatusCode, e.Detail)
+	}
+	return fmt.Sprintf("api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// Field returns the error for the named request field, if the API reported one.
+func (e *Error) Field(name string) (api.FieldError, bool) {
+	for _, field := range e.Fields {
+		if field.Field == name {
+			return field, true
+		}
+	}
+	return api.FieldError{}, false
+}
+
+// tokenRejected reports whether signing in again may fix the request.
+func (e *Error) tokenRejected() bool {
+	if e.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	switch e.Code {
+	case problem.CodeUnauthenticated, problem.CodeInvalidToken, problem.CodeSessionRevoked:
+		return true
+	}
+	return false
+}
+
+func newError(resp *http.Response, body []byte) *Error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		apiErr.RetryAfter = wait
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	var p api.Problem
+	if (mediaType == problem.ContentType || mediaType == "application/json") && json.Unmarshal(body, &p) == nil && p.Code != "" {
+		apiErr.Code = p.Code
+		apiErr.Detail = p.Detail
+		apiErr.RequestID = p.RequestID
+		apiErr.Fields = p.Errors
+		return apiErr
+	}
+
+	apiErr.Detail = strings.TrimSpace(string(body))
+	return apiErr
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"resellution/backend/api"
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/config"
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/utils"
+)
+
+// database holds the stores used by commands that work on the database directly.
+type database struct {
+	db           *sql.DB
+	userStore    models.UserStore
+	sessionStore models.SessionStore
+	tx           db.TxManager
+	audit        audit.Recorder
+	tokens       utils.TokenManager
+	// rateLimitWindow is how long a rate limit counter stays in use after its window
+	// starts.
+	rateLimitWindow time.Duration
+}
+
+func newDatabase(conn *sql.DB, cfg config.Config) *database {
+	return &database{
+		db:           conn,
+		userStore:    models.UserStore{DB: conn},
+		sessionStore: models.SessionStore{DB: conn},
+		tx:           db.TxManager{DB: conn, MaxRetries: cfg.DBTxMaxRetries},
+		audit:        audit.Recorder{DB: conn},
+		tokens:       utils.NewTokenManager(cfg.TokenSecret),
+
+		rateLimitWindow: time.Duration(cfg.PasswordResetRateLimitWindowMinutes) * time.Minute,
+	}
+}
+
+// mintToken issues a token backed by a new session, like a login, so the API accepts it
+// and revoke-sessions invalidates it.
+func (d *database) mintToken(ctx context.Context, user models.User, ttl time.Duration) (string, time.Time, error) {
+	permissions, err := d.userStore.PermissionsForRole(ctx, user.Role)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	sessionID := uuid.NewString()
+	expiresAt := time.Now().Add(ttl)
+	token, err := d.tokens.CreateWithClaims(utils.Claims{
+		UserID:      user.ID,
+		Role:        user.Role,
+		Permissions: permissions,
+		SessionID:   sessionID,
+	}, expiresAt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	err = d.sessionStore.Create(ctx, models.Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		TokenFingerprint: utils.Fingerprint(token),
+		UserAgent:        "resellctl",
+		ExpiresAt:        expiresAt,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+func (d *database) users() userBackend {
+	return dbUsers{d: d}
+}
+
+// dbUsers changes users in the database. Changes have no acting user; the audit log
+// records them with source resellctl.
+type dbUsers struct {
+	d *database
+}
+
+func (u dbUsers) findUser(ctx context.Context, ref string) (api.User, error) {
+	if _, err := uuid.Parse(ref); err == nil {
+		return u.d.userStore.FindByIDIncludingDeleted(ctx, ref)
+	}
+	users, _, err := u.d.userStore.SearchUsers(ctx, models.UserFilter{Email: ref, IncludeDeleted: true, Limit: maxEmailMatches})
+	if err != nil {
+		return api.User{}, err
+	}
+	return exactEmailMatch(users, ref)
+}
+
+func (u dbUsers) createAdmin(ctx context.Context, email, fullName, password string) (api.User, error) {
+	hash, err := utils.HashPassword(ctx, password)
+	if err != nil {
+		return api.User{}, err
+	}
+
+	var created models.User
+	err = u.d.tx.WithTx(ctx, func(ctx context.Context) error {
+		user, err := u.d.userStore.Create(ctx, models.User{ID: uuid.NewString(), Email: email, PasswordHash: hash, FullName: fullName})
+		if err != nil {
+			return err
+		}
+		if err := u.d.userStore.SetRole(ctx, user.ID, models.RoleAdmin, ""); err != nil {
+			return err
+		}
+		if err := u.d.userStore.MarkEmailVerified(ctx, user.ID, ""); err != nil {
+			return err
+		}
+		if created, err = u.d.userStore.FindByID(ctx, user.ID); err != nil {
+			return err
+		}
+		return u.d.audit.Record(ctx, audit.Event{
+			Action:     audit.ActionUserRegistered,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			After:      created,
+			Metadata:   map[string]any{"source": "resellctl", "role": models.RoleAdmin},
+		})
+	})
+	return created, err
+}
+
+func (u dbUsers) setsPasswords() bool {
+	return true
+}
+
+func (u dbUsers) resetPassword(ctx context.Context, user api.User, password string) error {
+	hash, err := utils.HashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+	return u.change(ctx, user.ID, audit.ActionPasswordResetCompleted, func(ctx context.Context) error {
+		if err := u.d.userStore.UpdatePasswordHashByID(ctx, user.ID, hash, ""); err != nil {
+			return err
+		}
+		if err := u.d.userStore.InvalidateActivePasswordResetOTPsByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+		_, err := u.d.sessionStore.RevokeAllByUserID(ctx, user.ID)
+		return err
+	})
+}
+
+func (u dbUsers) deactivate(ctx context.Context, userID string) error {
+	return u.change(ctx, userID, audit.ActionAdminUserDeactivated, func(ctx context.Context) error {
+		if err := u.d.userStore.DeactivateByID(ctx, userID, ""); err != nil {
+			return err
+		}
+		_, err := u.d.sessionStore.RevokeAllByUserID(ctx, userID)
+		return err
+	})
+}
+
+func (u dbUsers) restore(ctx context.Context, userID string) error {
+	return u.change(ctx, userID, audit.ActionAdminUserRestored, func(ctx context.Context) error {
+		return u.d.userStore.RestoreByID(ctx, userID, "")
+	})
+}
+
+func (u dbUsers) revokeSessions(ctx context.Context, userID string) error {
+	return u.change(ctx, userID, audit.ActionAdminUserSessionRevoked, func(ctx context.Context) error {
+		_, err := u.d.sessionStore.RevokeAllByUserID(ctx, userID)
+		return err
+	})
+}
+
+// change runs fn in a transaction and audits it with before/after snapshots of the
+// user, like the admin API does.
+func (u dbUsers) change(ctx context.Context, userID, action string, fn func(ctx context.Context) error) error {
+	return u.d.tx.WithTx(ctx, func(ctx context.Context) error {
+		before, err := u.d.userStore.FindByIDIncludingDeleted(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		after, err := u.d.userStore.FindByIDIncludingDeleted(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return u.d.audit.Record(ctx, audit.Event{
+			Action:     action,
+			TargetType: audit.TargetUser,
+			TargetID:   userID,
+			Before:     before,
+			After:      after,
+			Metadata:   map[string]any{"source": "resellctl"},
+		})
+	})
+}
@@ -0,0 +1,206 @@
+// Command resellctl is the operator tool for ReSellution. User commands work against
+// the database directly or, with -api, through the HTTP API as an admin; the other
+// commands need the database.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"text/tabwriter"
+	"time"
+
+	"resellution/backend/internal/config"
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/dbcmd"
+	"resellution/backend/internal/idempotency"
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/ratelimit"
+)
+
+const usage = `usage: resellctl [-api URL] <command> [flags]
+
+user commands (database, or the API with -api):
+  create-admin     -email E -name N [-password P]   create a verified admin account
+  reset-password   -user ID|EMAIL [-password P]     set a new password and sign the user out
+  deactivate       -user ID|EMAIL                   deactivate the account and sign the user out
+  restore          -user ID|EMAIL                   restore a deactivated account
+  revoke-sessions  -user ID|EMAIL                   sign the user out everywhere
+
+database commands:
+  migrate          up | down [n] | status | check
+  seed             [-profile prod|staging|dev] [-seed n]
+  purge            [-older-than DURATION]           delete expired OTPs, reset tokens, sessions,
+                                                    idempotency keys and rate limit counters
+  token            -user ID [-ttl DURATION]         mint a short-lived token for testing
+
+  config           print the effective configuration with secrets redacted
+
+With -api (or RESELLCTL_API_URL), user commands sign in with RESELLCTL_EMAIL and
+RESELLCTL_PASSWORD, or use RESELLCTL_TOKEN. Otherwise the database is configured like
+the server, from the environment and .env.`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "resellctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	global := flag.NewFlagSet("resellctl", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprintln(global.Output(), usage) }
+	apiURL := global.String("api", os.Getenv("RESELLCTL_API_URL"), "API base URL; user commands go through the API instead of the database")
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	if global.NArg() == 0 {
+		return errors.New("missing command\n" + usage)
+	}
+	command, args := global.Arg(0), global.Args()[1:]
+
+	if command == "config" {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		return printConfig(out, cfg.Redacted())
+	}
+
+	if newUserCommand, ok := userCommands[command]; ok {
+		if *apiURL != "" {
+			users, err := newAPIUsers(*apiURL)
+			if err != nil {
+				return err
+			}
+			return newUserCommand(ctx, users, args, out)
+		}
+		return withDatabase(ctx, func(d *database) error {
+			return newUserCommand(ctx, d.users(), args, out)
+		})
+	}
+
+	if *apiURL != "" {
+		return fmt.Errorf("%s needs database access and cannot run with -api", command)
+	}
+	switch command {
+	case "migrate":
+		return withDatabase(ctx, func(d *database) error {
+			return dbcmd.Migrate(ctx, d.db, out, args)
+		})
+	case "seed":
+		return withDatabase(ctx, func(d *database) error {
+			return dbcmd.Seed(ctx, d.db, out, args)
+		})
+	case "purge":
+		return withDatabase(ctx, func(d *database) error {
+			return runPurge(ctx, d, args, out)
+		})
+	case "token":
+		return withDatabase(ctx, func(d *database) error {
+			return runToken(ctx, d, args, out)
+		})
+	default:
+		return fmt.Errorf("unknown command %q\n%s", command, usage)
+	}
+}
+
+func withDatabase(ctx context.Context, fn func(d *database) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	conn, _, err := db.Open(ctx, cfg.DatabaseURL, db.PoolConfig{
+		MaxConns:         2,
+		StatementTimeout: time.Duration(cfg.DBStatementTimeoutMs) * time.Millisecond,
+	})
+	if err != nil {
+		return fmt.Errorf("database connection: %w", err)
+	}
+	defer conn.Close()
+	return fn(newDatabase(conn, cfg))
+}
+
+func runPurge(ctx context.Context, d *database, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	olderThan := flags.Duration("older-than", 0, "keep rows that expired or were used more recently than this")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *olderThan < 0 {
+		return errors.New("-older-than must not be negative")
+	}
+
+	cutoff := time.Now().Add(-*olderThan)
+	tokens, otps, err := d.userStore.PurgePasswordResets(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	sessions, err := d.sessionStore.PurgeExpired(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	keys, err := idempotency.PostgresStore{DB: d.db}.Purge(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	counters, err := ratelimit.PostgresStore{DB: d.db}.Purge(ctx, cutoff.Add(-d.rateLimitWindow))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "purged %d password reset OTPs, %d password reset tokens, %d sessions, %d idempotency keys, %d rate limit counters\n", otps, tokens, sessions, keys, counters)
+	return nil
+}
+
+// maxTokenTTL keeps minted tokens short-lived; they bypass login, so they should not
+// outlive a debugging session.
+const maxTokenTTL = 24 * time.Hour
+
+func runToken(ctx context.Context, d *database, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("token", flag.ContinueOnError)
+	userID := flags.String("user", "", "user ID")
+	ttl := flags.Duration("ttl", 15*time.Minute, "token lifetime, at most 24h")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *userID == "" {
+		return errors.New("token: -user is required")
+	}
+	if *ttl <= 0 || *ttl > maxTokenTTL {
+		return fmt.Errorf("token: -ttl must be between 0 and %s", maxTokenTTL)
+	}
+
+	user, err := d.userStore.FindByID(ctx, *userID)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return fmt.Errorf("no active user with ID %q", *userID)
+	}
+	if err != nil {
+		return err
+	}
+	token, expiresAt, err := d.mintToken(ctx, user, *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, token)
+	fmt.Fprintf(os.Stderr, "token for %s (%s) expires at %s; revoke it with `resellctl revoke-sessions -user %s`\n",
+		user.Email, user.Role, expiresAt.Format(time.RFC3339), user.ID)
+	return nil
+}
+
+// printConfig lists every configuration field with its value.
+func printConfig(out io.Writer, cfg config.Config) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	value := reflect.ValueOf(cfg)
+	for i := range value.NumField() {
+		fmt.Fprintf(w, "%s\t%v\n", value.Type().Field(i).Name, value.Field(i).Interface())
+	}
+	return w.Flush()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"resellution/backend/internal/handlers"
+	"resellution/backend/internal/memstore"
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/utils"
+)
+
+// newAPI serves the routes resellctl uses, backed by memstore, with an admin who signs
+// in as admin@example.com.
+func newAPI(t *testing.T) (*memstore.Store, string) {
+	t.Helper()
+	ctx := context.Background()
+	store := memstore.New()
+	hash, err := utils.HashPassword(ctx, "admin-pass-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := store.Users().Create(ctx, models.User{ID: uuid.NewString(), Email: "admin@example.com", PasswordHash: hash, FullName: "Admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users().SetRole(ctx, admin.ID, models.RoleAdmin, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := utils.NewTokenManager("test-secret")
+	auth := handlers.AuthHandler{
+		Users: store.Users(), PasswordResets: store.Users(), Sessions: store.Sessions(), Tx: store,
+		Audit: store.Audit(), TokenManager: tokens, TokenExpiryHours: 1,
+	}
+	admins := handlers.AdminHandler{Users: store.Users(), Sessions: store.Sessions(), Tx: store, Audit: store.Audit()}
+	permitted := func(permission string, next http.HandlerFunc) http.HandlerFunc {
+		return middleware.Auth(tokens, store.Sessions(), middleware.Require(permission, next))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/register", auth.Register)
+	mux.HandleFunc("POST /api/v1/auth/login", auth.Login)
+	mux.HandleFunc("GET /api/v1/admin/users", permitted(models.PermissionUsersRead, admins.SearchUsers))
+	mux.HandleFunc("GET /api/v1/admin/users/{id}", permitted(models.PermissionUsersRead, admins.GetUser))
+	mux.HandleFunc("PUT /api/v1/admin/users/{id}/role", permitted(models.PermissionRolesManage, admins.SetUserRole))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/verify-email", permitted(models.PermissionUsersModerate, admins.VerifyEmail))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/deactivate", permitted(models.PermissionUsersModerate, admins.DeactivateUser))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/restore", permitted(models.PermissionUsersModerate, admins.RestoreUser))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/force-password-reset", permitted(models.PermissionUsersModerate, admins.ForcePasswordReset))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return store, server.URL
+}
+
+func TestUserCommandsThroughAPI(t *testing.T) {
+	store, url := newAPI(t)
+	t.Setenv("RESELLCTL_EMAIL", "admin@example.com")
+	t.Setenv("RESELLCTL_PASSWORD", "admin-pass-1")
+	ctx := context.Background()
+
+	resellctl := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := run(ctx, append([]string{"-api", url}, args...), &out)
+		return out.String(), err
+	}
+
+	out, err := resellctl("create-admin", "-email", "ops@example.com", "-name", "Ops")
+	if err != nil {
+		t.Fatalf("create-admin: %v", err)
+	}
+	if !strings.Contains(out, "created admin ops@example.com") || !strings.Contains(out, "password: ") {
+		t.Fatalf("create-admin output = %q", out)
+	}
+	created, err := store.Users().FindByEmail(ctx, "ops@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Role != models.RoleAdmin || !created.IsVerified {
+		t.Fatalf("created user = %+v, want a verified admin", created)
+	}
+
+	if _, err := resellctl("create-admin", "-email", "ops@example.com", "-name", "Ops"); err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Fatalf("duplicate create-admin err = %v", err)
+	}
+
+	if _, err := resellctl("deactivate", "-user", "OPS@example.com"); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if _, err := store.Users().FindByID(ctx, created.ID); err == nil {
+		t.Fatal("user is still active after deactivate")
+	}
+	if _, err := resellctl("restore", "-user", created.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if _, err := store.Users().FindByID(ctx, created.ID); err != nil {
+		t.Fatalf("user not restored: %v", err)
+	}
+
+	if _, err := resellctl("reset-password", "-user", created.ID, "-password", "new-pass-1"); err == nil {
+		t.Fatal("reset-password -password through the API succeeded")
+	}
+	if _, err := resellctl("reset-password", "-user", created.ID); err != nil {
+		t.Fatalf("reset-password: %v", err)
+	}
+	if user, _ := store.Users().FindByID(ctx, created.ID); !user.PasswordResetRequired {
+		t.Fatal("reset-password did not require a password reset")
+	}
+
+	if _, err := resellctl("deactivate", "-user", "nobody@example.com"); err == nil || !strings.Contains(err.Error(), "no user") {
+		t.Fatalf("deactivate unknown user err = %v", err)
+	}
+}
+
+func TestDatabaseCommandsRejectAPI(t *testing.T) {
+	for _, command := range []string{"migrate", "seed", "purge", "token"} {
+		err := run(context.Background(), []string{"-api", "http://localhost:1", command}, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), "needs database access") {
+			t.Errorf("%s with -api err = %v", command, err)
+		}
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	for range 100 {
+		password := generatePassword()
+		if len(password) != 16 || !strings.ContainsAny(password, "23456789") || strings.IndexFunc(password, func(r rune) bool { return r > '9' }) < 0 {
+			t.Fatalf("generatePassword() = %q", password)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"resellution/backend/api"
+	"resellution/backend/client"
+	"resellution/backend/internal/models"
+)
+
+// userBackend performs the user commands, either on the database or through the API.
+type userBackend interface {
+	// findUser looks a user up by ID or email, including deactivated users.
+	findUser(ctx context.Context, ref string) (api.User, error)
+	createAdmin(ctx context.Context, email, fullName, password string) (api.User, error)
+	// setsPasswords reports whether resetPassword sets password. Otherwise it requires
+	// the user to choose a new password through the emailed code.
+	setsPasswords() bool
+	resetPassword(ctx context.Context, user api.User, password string) error
+	deactivate(ctx context.Context, userID string) error
+	restore(ctx context.Context, userID string) error
+	revokeSessions(ctx context.Context, userID string) error
+}
+
+var userCommands = map[string]func(ctx context.Context, users userBackend, args []string, out io.Writer) error{
+	"create-admin":    runCreateAdmin,
+	"reset-password":  runResetPassword,
+	"deactivate":      userAction("deactivate", "deactivated", userBackend.deactivate),
+	"restore":         userAction("restore", "restored", userBackend.restore),
+	"revoke-sessions": userAction("revoke-sessions", "revoked the sessions of", userBackend.revokeSessions),
+}
+
+func runCreateAdmin(ctx context.Context, users userBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := flags.String("email", "", "email address")
+	fullName := flags.String("name", "", "full name")
+	password := flags.String("password", "", "password; generated and printed when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || *fullName == "" {
+		return errors.New("create-admin: -email and -name are required")
+	}
+
+	generated := *password == ""
+	if generated {
+		*password = generatePassword()
+	}
+	user, err := users.createAdmin(ctx, strings.TrimSpace(*email), strings.TrimSpace(*fullName), *password)
+	if errors.Is(err, models.ErrEmailTaken) || errors.Is(err, client.ErrEmailTaken) {
+		return fmt.Errorf("%s is already registered", *email)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "created admin %s (%s)\n", user.Email, user.ID)
+	if generated {
+		fmt.Fprintf(out, "password: %s\n", *password)
+	}
+	return nil
+}
+
+func runResetPassword(ctx context.Context, users userBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	ref := flags.String("user", "", "user ID or email")
+	password := flags.String("password", "", "new password; generated and printed when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *ref == "" {
+		return errors.New("reset-password: -user is required")
+	}
+	if *password != "" && !users.setsPasswords() {
+		return errors.New("reset-password: the API cannot set a password; omit -password to require a reset, or run without -api")
+	}
+
+	user, err := findUser(ctx, users, *ref)
+	if err != nil {
+		return err
+	}
+	if !users.setsPasswords() {
+		if err := users.resetPassword(ctx, user, ""); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s must reset their password with an emailed code before signing in\n", user.Email)
+		return nil
+	}
+
+	generated := *password == ""
+	if generated {
+		*password = generatePassword()
+	}
+	if err := users.resetPassword(ctx, user, *password); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "reset the password of %s and signed them out\n", user.Email)
+	if generated {
+		fmt.Fprintf(out, "password: %s\n", *password)
+	}
+	return nil
+}
+
+// userAction builds a command that applies action to the user named by -user.
+func userAction(name, done string, action func(users userBackend, ctx context.Context, userID string) error) func(context.Context, userBackend, []string, io.Writer) error {
+	return func(ctx context.Context, users userBackend, args []string, out io.Writer) error {
+		flags := flag.NewFlagSet(name, flag.ContinueOnError)
+		ref := flags.String("user", "", "user ID or email")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if *ref == "" {
+			return fmt.Errorf("%s: -user is required", name)
+		}
+
+		user, err := findUser(ctx, users, *ref)
+		if err != nil {
+			return err
+		}
+		if err := action(users, ctx, user.ID); err != nil {
+			if errors.Is(err, models.ErrUserNotFound) || errors.Is(err, client.ErrUserNotFound) {
+				return fmt.Errorf("%s: %s is not in a state that allows it", name, user.Email)
+			}
+			return err
+		}
+		fmt.Fprintf(out, "%s %s (%s)\n", done, user.Email, user.ID)
+		return nil
+	}
+}
+
+func findUser(ctx context.Context, users userBackend, ref string) (api.User, error) {
+	user, err := users.findUser(ctx, ref)
+	if errors.Is(err, models.ErrUserNotFound) || errors.Is(err, client.ErrUserNotFound) {
+		return api.User{}, fmt.Errorf("no user %q", ref)
+	}
+	return user, err
+}
+
+// generatePassword returns a random password that satisfies the API's password rules.
+func generatePassword() string {
+	const (
+		letters = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+		digits  = "23456789"
+	)
+	pick := func(alphabet string) byte {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			panic(err) // crypto/rand does not fail on supported platforms
+		}
+		return alphabet[n.Int64()]
+	}
+
+	password := make([]byte, 16)
+	for i := range password {
+		password[i] = pick(letters + digits)
+	}
+	// The rules need at least one letter and one digit.
+	password[0] = pick(letters)
+	password[len(password)-1] = pick(digits)
+	return string(password)
+}
+
+// apiUsers changes users through the admin API, as the admin it signs in as.
+type apiUsers struct {
+	c *client.Client
+}
+
+func newAPIUsers(baseURL string) (apiUsers, error) {
+	c := client.New(baseURL)
+	c.UserAgent = "resellctl"
+	switch {
+	case os.Getenv("RESELLCTL_TOKEN") != "":
+		c.SetToken(os.Getenv("RESELLCTL_TOKEN"))
+	case os.Getenv("RESELLCTL_EMAIL") != "" && os.Getenv("RESELLCTL_PASSWORD") != "":
+		c.Credentials = &api.LoginRequest{Email: os.Getenv("RESELLCTL_EMAIL"), Password: os.Getenv("RESELLCTL_PASSWORD")}
+	default:
+		return apiUsers{}, errors.New("set RESELLCTL_TOKEN, or RESELLCTL_EMAIL and RESELLCTL_PASSWORD, to use the API")
+	}
+	return apiUsers{c: c}, nil
+}
+
+func (u apiUsers) findUser(ctx context.Context, ref string) (api.User, error) {
+	if _, err := uuid.Parse(ref); err == nil {
+		return u.c.GetUser(ctx, ref)
+	}
+	page, err := u.c.SearchUsers(ctx, api.UserFilter{Email: ref, IncludeDeleted: true, Limit: maxEmailMatches})
+	if err != nil {
+		return api.User{}, err
+	}
+	return exactEmailMatch(page.Users, ref)
+}
+
+// createAdmin registers the account as a new user would, then promotes and verifies it.
+func (u apiUsers) createAdmin(ctx context.Context, email, fullName, password string) (api.User, error) {
+	registrar := client.New(u.c.BaseURL)
+	registrar.UserAgent = u.c.UserAgent
+	registered, err := registrar.Register(ctx, api.RegisterRequest{Email: email, Password: password, FullName: fullName})
+	if err != nil {
+		return api.User{}, err
+	}
+	if err := u.c.SetUserRole(ctx, registered.User.ID, models.RoleAdmin); err != nil {
+		return api.User{}, err
+	}
+	if err := u.c.VerifyEmail(ctx, registered.User.ID); err != nil {
+		return api.User{}, err
+	}
+	return u.c.GetUser(ctx, registered.User.ID)
+}
+
+func (u apiUsers) setsPasswords() bool {
+	return false
+}
+
+func (u apiUsers) resetPassword(ctx context.Context, user api.User, _ string) error {
+	return u.c.ForcePasswordReset(ctx, user.ID)
+}
+
+func (u apiUsers) deactivate(ctx context.Context, userID string) error {
+	return u.c.DeactivateUser(ctx, userID)
+}
+
+func (u apiUsers) restore(ctx context.Context, userID string) error {
+	return u.c.RestoreUser(ctx, userID)
+}
+
+func (u apiUsers) revokeSessions(ctx context.Context, userID string) error {
+	return u.c.RevokeSessions(ctx, userID)
+}
+
+// maxEmailMatches bounds the substring search used to find a user by email.
+const maxEmailMatches = 200
+
+func exactEmailMatch(users []api.User, email string) (api.User, error) {
+	for _, user := range users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return api.User{}, models.ErrUserNotFound
+}
@@ -1,38 +1,140 @@
 package main
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/clientip"
 	"resellution/backend/internal/config"
+	"resellution/backend/internal/cors"
 	"resellution/backend/internal/db"
+	"resellution/backend/internal/dbcmd"
+	"resellution/backend/internal/featureflags"
 	"resellution/backend/internal/handlers"
+	"resellution/backend/internal/health"
+	"resellution/backend/internal/idempotency"
 	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/migrate"
 	"resellution/backend/internal/models"
 	"resellution/backend/internal/observability"
+	"resellution/backend/internal/openapi"
+	"resellution/backend/internal/problem"
 	"resellution/backend/internal/ratelimit"
+	"resellution/backend/internal/servertls"
 	"resellution/backend/internal/utils"
+	"resellution/backend/migrations"
+)
+
+const (
+	// migrationsRecheckInterval bounds schema checks while migrations are pending.
+	migrationsRecheckInterval = time.Minute
+	// smtpCheckInterval bounds how often readiness probes open an SMTP connection.
+	smtpCheckInterval = time.Minute
 )
 
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("config error: %v", err)
+		fatal("config error", err)
 	}
 
-	database, err := db.Connect(cfg.DatabaseURL)
+	logger, err := observability.NewLogger(observability.LogConfig{Level: cfg.LogLevel, RedactKeys: cfg.LogRedactKeys})
 	if err != nil {
-		log.Fatalf("database connection error: %v", err)
+		fatal("config error", err)
+	}
+	// Also routes the standard log package, used by dependencies, through the redactor.
+	slog.SetDefault(logger)
+
+	poolConfig := db.PoolConfig{
+		MaxConns:         int32(cfg.DBMaxConns),
+		MinConns:         int32(cfg.DBMinConns),
+		MaxConnLifetime:  time.Duration(cfg.DBMaxConnLifetimeMinutes) * time.Minute,
+		MaxConnIdleTime:  time.Duration(cfg.DBMaxConnIdleMinutes) * time.Minute,
+		StatementTimeout: time.Duration(cfg.DBStatementTimeoutMs) * time.Millisecond,
+	}
+	database, pool, err := db.Open(context.Background(), cfg.DatabaseURL, poolConfig)
+	if err != nil {
+		fatal("database connection error", err)
 	}
 	defer database.Close()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := dbcmd.Migrate(context.Background(), database, os.Stdout, os.Args[2:]); err != nil {
+				fatal("migrate error", err)
+			}
+			return
+		case "seed":
+			if err := dbcmd.Seed(context.Background(), database, os.Stdout, os.Args[2:]); err != nil {
+				fatal("seed error", err)
+			}
+			return
+		case "serve":
+		default:
+			fatal("invalid command", fmt.Errorf("unknown command %q (expected serve, migrate or seed)", os.Args[1]))
+		}
+	}
+
+	shutdownTracing, err := observability.SetupTracing(context.Background(), cfg.TracingExporter, cfg.TracingSampleRatio)
+	if err != nil {
+		fatal("tracing setup error", err)
+	}
+
+	// Background workers run until shutdown has drained the HTTP server.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	startWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	// Migrations are checked at startup and, while the schema is behind, at most once a
+	// minute until it is current, rather than on every readiness probe.
+	migrator := migrate.Migrator{DB: database, FS: migrations.FS}
+	migrationsCheck := health.UntilOK(health.Cached(migrator.Check, migrationsRecheckInterval))
+	if err := migrationsCheck(context.Background()); err != nil {
+		if cfg.MigrationsRequireCurrent {
+			fatal("refusing to start; run `server migrate up`", err)
+		}
+		slog.Warn("database schema is not current; readiness fails until migrations are applied", "error", err)
+	}
+
+	replicaMaxLag := time.Duration(cfg.DBReplicaMaxLagSeconds) * time.Second
+	replicaRouter := openReplicas(database, cfg.DatabaseReplicaURLs, poolConfig, replicaMaxLag)
+	defer replicaRouter.Close()
+	if replicaRouter != nil {
+		startWorker(func(ctx context.Context) {
+			replicaRouter.Run(ctx, time.Duration(cfg.DBReplicaCheckSeconds)*time.Second)
+		})
+	}
+
 	userStore := models.UserStore{DB: database}
+	categoryStore := models.CategoryStore{DB: database, Replicas: replicaRouter}
+	sessionStore := models.SessionStore{DB: database}
+	txManager := db.TxManager{DB: database, MaxRetries: cfg.DBTxMaxRetries}
+	auditRecorder := audit.Recorder{DB: database}
 	tokenManager := utils.NewTokenManager(cfg.TokenSecret)
 	var emailSender utils.EmailSender
+	// SMTP is optional: without it password reset emails are not sent, so an outage
+	// degrades readiness instead of failing it.
+	smtpCheck := health.Check{Name: "smtp"}
 	if strings.TrimSpace(cfg.SMTPHost) != "" {
-		emailSender = utils.SMTPEmailSender{
+		smtpSender := utils.SMTPEmailSender{
 			Host:      cfg.SMTPHost,
 			Port:      cfg.SMTPPort,
 			Username:  cfg.SMTPUsername,
@@ -40,95 +142,347 @@ func main() {
 			FromEmail: cfg.SMTPFromEmail,
 			FromName:  cfg.SMTPFromName,
 		}
+		emailSender = smtpSender
+		smtpCheck.Probe = health.Cached(smtpSender.Ping, smtpCheckInterval)
+	}
+
+	featureFlagStore := featureflags.PostgresStore{DB: database}
+	featureFlags := featureflags.NewService(featureFlagStore, func(ctx context.Context, userID string) (featureflags.User, error) {
+		user, err := userStore.FindByID(ctx, userID)
+		return featureflags.User{ID: user.ID, City: user.City}, err
+	})
+	if err := featureFlags.Refresh(context.Background()); err != nil {
+		// Every flag stays off until a periodic refresh succeeds.
+		slog.Error("feature flag load failed", "error", err)
 	}
+	startWorker(func(ctx context.Context) {
+		featureFlags.Run(ctx, time.Duration(cfg.FeatureFlagRefreshSeconds)*time.Second)
+	})
 
+	metrics := observability.NewMetrics()
 	authHandler := handlers.AuthHandler{
 		Users:                        userStore,
+		Sessions:                     sessionStore,
+		PasswordResets:               userStore,
+		Tx:                           txManager,
+		Audit:                        auditRecorder,
 		TokenManager:                 tokenManager,
 		EmailSender:                  emailSender,
+		Metrics:                      metrics,
+		Flags:                        featureFlags,
 		TokenExpiryHours:             cfg.TokenExpiryHours,
 		PasswordResetExpiryMinutes:   cfg.PasswordResetExpiryMinutes,
 		PasswordResetCooldownMinutes: cfg.PasswordResetCooldownMinutes,
 		PasswordResetOTPDigits:       cfg.PasswordResetOTPDigits,
 		PasswordResetMaxAttempts:     cfg.PasswordResetMaxAttempts,
+		LogResetOTP:                  cfg.LogResetOTP,
+	}
+
+	categoryHandler := handlers.CategoryHandler{Categories: categoryStore, Tx: txManager, Audit: auditRecorder}
+	adminHandler := handlers.AdminHandler{Users: userStore, Sessions: sessionStore, Tx: txManager, Audit: auditRecorder}
+	featureFlagHandler := handlers.FeatureFlagHandler{Flags: featureFlagStore, Cache: featureFlags, Tx: txManager, Audit: auditRecorder}
+
+	if cfg.AdminBootstrapEmail != "" {
+		bootstrapAdmin(userStore, cfg.AdminBootstrapEmail)
+	}
+
+	idempotencyStore := idempotency.PostgresStore{DB: database}
+	startWorker(func(ctx context.Context) {
+		runIdempotencyPurge(ctx, idempotencyStore)
+	})
+
+	if cfg.RateLimitStore == "postgres" {
+		rateLimitStore := ratelimit.PostgresStore{DB: database}
+		rateLimitWindow := time.Duration(cfg.PasswordResetRateLimitWindowMinutes) * time.Minute
+		startWorker(func(ctx context.Context) {
+			runRateLimitPurge(ctx, rateLimitStore, rateLimitWindow)
+		})
+	}
+
+	if cfg.AuditRetentionDays > 0 {
+		startWorker(func(ctx context.Context) {
+			runAuditRetention(ctx, auditRecorder, time.Duration(cfg.AuditRetentionDays)*24*time.Hour)
+		})
 	}
 
 	mux := http.NewServeMux()
 
-	metrics := observability.NewMetrics()
-	logger := observability.NewLogger()
+	metrics.SetDBPoolStats(func() observability.DBPoolStats {
+		stat := pool.Stat()
+		return observability.DBPoolStats{
+			MaxConns:             stat.MaxConns(),
+			TotalConns:           stat.TotalConns(),
+			IdleConns:            stat.IdleConns(),
+			AcquiredConns:        stat.AcquiredConns(),
+			AcquireCount:         stat.AcquireCount(),
+			EmptyAcquireCount:    stat.EmptyAcquireCount(),
+			CanceledAcquireCount: stat.CanceledAcquireCount(),
+			AcquireDuration:      stat.AcquireDuration(),
+		}
+	})
 
-	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
+	healthChecker := &health.Checker{
+		Timeout: time.Duration(cfg.HealthCheckTimeoutMs) * time.Millisecond,
+		Checks: []health.Check{
+			{Name: "database", Critical: true, Probe: database.PingContext},
+			{Name: "migrations", Critical: true, Probe: migrationsCheck},
+			smtpCheck,
+		},
+	}
+	passwordResetRateLimiter := ratelimit.NewIPRateLimiterWithStore(newRateLimitStore(cfg, database, logger), cfg.PasswordResetRateLimitPerIP, cfg.PasswordResetRateLimitWindowMinutes)
+	spec, err := openapi.Load()
+	if err != nil {
+		fatal("openapi document error", err)
+	}
+	registerRoutes(mux, routeDeps{
+		health:       healthChecker,
+		metrics:      metrics,
+		spec:         spec,
+		auth:         authHandler,
+		categories:   categoryHandler,
+		admin:        adminHandler,
+		featureFlags: featureFlagHandler,
+		tokens:       tokenManager,
+		sessions:     sessionStore,
+		idempotency:  idempotencyStore,
+		resetLimiter: passwordResetRateLimiter,
 	})
-	mux.HandleFunc("GET /metrics", observability.MetricsHandler(metrics))
-	mux.HandleFunc("POST /api/v1/auth/register", authHandler.Register)
-	mux.HandleFunc("POST /api/v1/auth/login", authHandler.Login)
-	passwordResetRateLimiter := ratelimit.NewIPRateLimiter(cfg.PasswordResetRateLimitPerIP, cfg.PasswordResetRateLimitWindowMinutes)
-	mux.HandleFunc("POST /api/v1/auth/password/reset/request", ratelimit.IPRateLimit(passwordResetRateLimiter, authHandler.RequestPasswordReset))
-	mux.HandleFunc("POST /api/v1/auth/password/reset/confirm", authHandler.ConfirmPasswordReset)
-	mux.HandleFunc("GET /api/v1/auth/me", middleware.Auth(tokenManager, authHandler.Me))
-	mux.HandleFunc("PATCH /api/v1/users/me", middleware.Auth(tokenManager, authHandler.UpdateProfile))
-	mux.HandleFunc("PUT /api/v1/users/me", middleware.Auth(tokenManager, authHandler.UpdateProfile))
-	mux.HandleFunc("DELETE /api/v1/users/me", middleware.Auth(tokenManager, authHandler.DeactivateAccount))
-	mux.HandleFunc("POST /api/v1/auth/logout", middleware.Auth(tokenManager, authHandler.Logout))
-
-	handler := observability.RequestMetrics(metrics, logger, withCORS(cfg.CorsOrigin, mux))
 
+	clientIPResolver, err := clientip.NewResolver(cfg.TrustedProxyCIDRs, cfg.TrustedProxyHeader)
+	if err != nil {
+		fatal("config error", err)
+	}
+
+	corsPolicy, err := cors.New(cors.Config{
+		AllowedOrigins:   cfg.CorsOrigins,
+		AllowedMethods:   cfg.CorsAllowedMethods,
+		AllowedHeaders:   cfg.CorsAllowedHeaders,
+		ExposedHeaders:   cfg.CorsExposedHeaders,
+		AllowCredentials: cfg.CorsAllowCredentials,
+		MaxAge:           time.Duration(cfg.CorsMaxAgeSeconds) * time.Second,
+	})
+	if err != nil {
+		fatal("config error", err)
+	}
+
+	var routes http.Handler = observability.CaptureRoute(mux, problem.Unmatched)
+	if cfg.OpenAPIValidateRequests {
+		routes = openapi.ValidateRequests(spec, routes)
+	}
+	if replicaRouter != nil {
+		routes = middleware.ReadYourWrites(middleware.NewWriteTracker(replicaMaxLag), tokenManager, routes)
+	}
+	// The global body limit wraps request validation, which reads the body first.
+	routes = middleware.MaxBodySize(cfg.MaxRequestBodyBytes, routes.ServeHTTP)
+	routes = middleware.SecurityHeaders(cfg.HSTSMaxAgeSeconds, corsPolicy.Handler(routes))
+	// Recovery sits inside RequestMetrics so a recovered panic is logged and counted as a
+	// 500 with its request ID.
+	routes = middleware.Recover(routes)
+	// Compression wraps recovery so a recovered 500 is encoded like any other response.
+	if cfg.CompressionEnabled {
+		routes = middleware.Compress(cfg.CompressionMinBytes, routes)
+	}
+	handler := clientip.Middleware(clientIPResolver, observability.Tracing(observability.RequestMetrics(metrics, logger, routes)))
+
+	tlsConfig, err := servertls.Config(cfg.TLSMode, cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		fatal("TLS setup error", err)
+	}
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
 		Handler:      handler,
+		TLSConfig:    tlsConfig,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	logger.Info("backend started", map[string]any{"port": cfg.Port, "url": "http://localhost:" + cfg.Port})
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("server error: %v", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	scheme := "http"
+	go func() {
+		if tlsConfig != nil {
+			// The certificate is already in TLSConfig; HTTP/2 is negotiated through ALPN.
+			serveErr <- server.ListenAndServeTLS("", "")
+			return
+		}
+		serveErr <- server.ListenAndServe()
+	}()
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	logger.Info("backend started", "port", cfg.Port, "url", scheme+"://localhost:"+cfg.Port, "tls", cfg.TLSMode)
+
+	select {
+	case err := <-serveErr:
+		fatal("server error", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	shutdown(server, healthChecker, logger,
+		time.Duration(cfg.ShutdownDelaySeconds)*time.Second,
+		time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+
+	stopWorkers()
+	workers.Wait()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Warn("flushing spans failed", "error", err)
+	}
+	logger.Info("backend stopped")
+}
+
+// fatal logs err and exits. Like log.Fatal, it skips deferred calls.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// shutdown fails readiness, waits delay so load balancers stop sending new requests,
+// then stops accepting connections and waits up to timeout for in-flight requests.
+// A second SIGINT/SIGTERM during the wait restores the default behaviour and kills the
+// process.
+func shutdown(server *http.Server, checker *health.Checker, logger *slog.Logger, delay, timeout time.Duration) {
+	logger.Info("shutting down", "delay", delay.String(), "timeout", timeout.String())
+	checker.SetShuttingDown()
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("shutdown did not drain in time", "error", err)
+		server.Close()
 	}
 }
 
-func withCORS(allowedOriginsCSV string, next http.Handler) http.Handler {
-	allowedOrigins := parseAllowedOrigins(allowedOriginsCSV)
+// bootstrapAdmin promotes an existing account to admin so the first admin can be created
+// without direct database access. The account must already be registered.
+func bootstrapAdmin(users models.UserStore, email string) {
+	if err := users.SetRoleByEmail(context.Background(), email, models.RoleAdmin); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			slog.Warn("admin bootstrap skipped: no active account", "email", email)
+			return
+		}
+		fatal("admin bootstrap error", err)
+	}
+	slog.Info("admin bootstrap: account has the admin role", "email", email)
+}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if origin != "" && isOriginAllowed(origin, allowedOrigins) {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Vary", "Origin")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+// runAuditRetention deletes audit events older than retention once at startup and then
+// daily until ctx is done.
+func runAuditRetention(ctx context.Context, recorder audit.Recorder, retention time.Duration) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := recorder.Purge(ctx, time.Now().Add(-retention))
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "audit retention purge failed", "error", err)
+		} else if deleted > 0 {
+			slog.InfoContext(ctx, "audit retention purged events", "deleted", deleted)
 		}
 
-		if r.Method == http.MethodOptions {
-			if origin == "" || !isOriginAllowed(origin, allowedOrigins) {
-				w.WriteHeader(http.StatusForbidden)
-				return
-			}
-			w.WriteHeader(http.StatusNoContent)
+		select {
+		case <-ctx.Done():
 			return
+		case <-ticker.C:
 		}
+	}
+}
 
-		next.ServeHTTP(w, r)
-	})
+// runIdempotencyPurge deletes expired idempotency keys hourly until ctx is done. Expired
+// keys are already ignored; this only keeps the table small.
+func runIdempotencyPurge(ctx context.Context, store idempotency.PostgresStore) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := store.Purge(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "idempotency key purge failed", "error", err)
+		} else if deleted > 0 {
+			slog.InfoContext(ctx, "idempotency key purge deleted keys", "deleted", deleted)
+		}
+	}
 }
 
-func parseAllowedOrigins(raw string) map[string]struct{} {
-	origins := make(map[string]struct{})
-	for _, origin := range strings.Split(raw, ",") {
-		trimmed := strings.TrimSpace(origin)
-		if trimmed == "" {
-			continue
+// runRateLimitPurge deletes rate limit counters whose window ended more than window ago,
+// hourly until ctx is done. Ended windows are already ignored; this only keeps the table
+// from growing by a row per client IP ever seen.
+func runRateLimitPurge(ctx context.Context, store ratelimit.PostgresStore, window time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := store.Purge(ctx, time.Now().Add(-window))
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "rate limit counter purge failed", "error", err)
+		} else if deleted > 0 {
+			slog.InfoContext(ctx, "rate limit counter purge deleted counters", "deleted", deleted)
 		}
-		origins[trimmed] = struct{}{}
 	}
-	return origins
 }
 
-func isOriginAllowed(origin string, allowed map[string]struct{}) bool {
-	_, ok := allowed[origin]
-	return ok
+// openReplicas connects to each read replica with the primary's pool settings. It
+// returns nil when no replicas are configured so every read stays on the primary.
+func openReplicas(primary *sql.DB, urls []string, poolConfig db.PoolConfig, maxLag time.Duration) *db.Router {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	replicas := make([]*db.Replica, 0, len(urls))
+	for i, url := range urls {
+		replicaDB, _, err := db.Open(context.Background(), url, poolConfig)
+		if err != nil {
+			fatal(fmt.Sprintf("replica-%d connection error", i+1), err)
+		}
+		replicas = append(replicas, &db.Replica{Name: fmt.Sprintf("replica-%d", i+1), DB: replicaDB})
+	}
+
+	return db.NewRouter(primary, replicas, maxLag, func(replica *db.Replica, healthy bool, lag time.Duration, err error) {
+		switch {
+		case healthy:
+			slog.Info("db replica in rotation", "replica", replica.Name, "lag", lag.String())
+		case err != nil:
+			slog.Warn("db replica out of rotation", "replica", replica.Name, "error", err)
+		default:
+			slog.Warn("db replica out of rotation: lag exceeds limit", "replica", replica.Name, "lag", lag.String(), "max_lag", maxLag.String())
+		}
+	})
+}
+
+func newRateLimitStore(cfg config.Config, database *sql.DB, logger *slog.Logger) ratelimit.Store {
+	if cfg.RateLimitStore != "postgres" {
+		return ratelimit.NewMemoryStore()
+	}
+
+	store := ratelimit.PostgresStore{DB: database}
+	if !cfg.RateLimitFallback {
+		return store
+	}
+	return ratelimit.FallbackStore{
+		Primary:  store,
+		Fallback: ratelimit.NewMemoryStore(),
+		Timeout:  time.Duration(cfg.RateLimitStoreTimeoutMs) * time.Millisecond,
+		OnError: func(err error) {
+			logger.Warn("rate limit store unavailable, using local limits", "error", err)
+		},
+	}
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+
+	"resellution/backend/internal/handlers"
+	"resellution/backend/internal/health"
+	"resellution/backend/internal/idempotency"
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/observability"
+	"resellution/backend/internal/openapi"
+	"resellution/backend/internal/ratelimit"
+	"resellution/backend/internal/utils"
+)
+
+// routeMux is the part of http.ServeMux registerRoutes uses, so tests can record the
+// registered patterns.
+type routeMux interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+type routeDeps struct {
+	health       *health.Checker
+	metrics      *observability.Metrics
+	spec         *openapi.Spec
+	auth         handlers.AuthHandler
+	categories   handlers.CategoryHandler
+	admin        handlers.AdminHandler
+	featureFlags handlers.FeatureFlagHandler
+	tokens       utils.TokenManager
+	sessions     middleware.SessionChecker
+	resetLimiter *ratelimit.IPRateLimiter
+	idempotency  idempotency.Store
+}
+
+// Per-route request body limits. Every request is also capped by MAX_REQUEST_BODY_BYTES.
+const (
+	// credentialsBodyLimit covers sign-up, sign-in and password reset bodies.
+	credentialsBodyLimit = 4 << 10
+	// jsonBodyLimit covers the other JSON bodies: profiles, categories and admin actions.
+	jsonBodyLimit = 64 << 10
+)
+
+// registerRoutes registers every route of the server. Each one must be described in
+// internal/openapi/openapi.json; TestRoutesAreDocumented enforces it.
+func registerRoutes(mux routeMux, d routeDeps) {
+	authed := func(next http.HandlerFunc) http.HandlerFunc {
+		return middleware.Auth(d.tokens, d.sessions, next)
+	}
+	permitted := func(permission string, next http.HandlerFunc) http.HandlerFunc {
+		return authed(middleware.Require(permission, next))
+	}
+	limit := middleware.MaxBodySize
+	// idempotent goes inside authed, so keys are scoped to the user, and outside rate
+	// limits, so replays are not counted.
+	idempotent := func(next http.HandlerFunc) http.HandlerFunc {
+		return idempotency.Middleware(d.idempotency, next)
+	}
+
+	mux.HandleFunc("GET /livez", d.health.Livez)
+	mux.HandleFunc("GET /readyz", d.health.Readyz)
+	// /health predates the split and stays as an alias of /livez for existing probes.
+	mux.HandleFunc("GET /health", d.health.Livez)
+	mux.HandleFunc("GET /metrics", observability.MetricsHandler(d.metrics))
+	mux.HandleFunc("GET /openapi.json", d.spec.ServeJSON)
+	mux.HandleFunc("GET /docs", openapi.Docs)
+
+	mux.HandleFunc("POST /api/v1/auth/register", limit(credentialsBodyLimit, idempotent(d.auth.Register)))
+	mux.HandleFunc("POST /api/v1/auth/login", limit(credentialsBodyLimit, d.auth.Login))
+	mux.HandleFunc("POST /api/v1/auth/password/reset/request", limit(credentialsBodyLimit, idempotent(ratelimit.IPRateLimit(d.resetLimiter, d.auth.RequestPasswordReset))))
+	mux.HandleFunc("POST /api/v1/auth/password/reset/confirm", limit(credentialsBodyLimit, d.auth.ConfirmPasswordReset))
+	mux.HandleFunc("GET /api/v1/auth/me", authed(d.auth.Me))
+	mux.HandleFunc("PATCH /api/v1/users/me", limit(jsonBodyLimit, authed(d.auth.UpdateProfile)))
+	mux.HandleFunc("PUT /api/v1/users/me", limit(jsonBodyLimit, authed(d.auth.UpdateProfile)))
+	mux.HandleFunc("DELETE /api/v1/users/me", authed(d.auth.DeactivateAccount))
+	mux.HandleFunc("POST /api/v1/auth/logout", authed(d.auth.Logout))
+	mux.HandleFunc("GET /api/v1/categories", d.categories.List)
+
+	mux.HandleFunc("POST /api/v1/admin/categories", limit(jsonBodyLimit, permitted(models.PermissionCategoriesManage, idempotent(d.categories.Create))))
+	mux.HandleFunc("PATCH /api/v1/admin/categories/{id}", limit(jsonBodyLimit, permitted(models.PermissionCategoriesManage, d.categories.Update)))
+	mux.HandleFunc("DELETE /api/v1/admin/categories/{id}", permitted(models.PermissionCategoriesManage, d.categories.Delete))
+	mux.HandleFunc("GET /api/v1/admin/users", permitted(models.PermissionUsersRead, d.admin.SearchUsers))
+	mux.HandleFunc("GET /api/v1/admin/users/{id}", permitted(models.PermissionUsersRead, d.admin.GetUser))
+	mux.HandleFunc("PUT /api/v1/admin/users/{id}/role", limit(jsonBodyLimit, permitted(models.PermissionRolesManage, d.admin.SetUserRole)))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/deactivate", permitted(models.PermissionUsersModerate, d.admin.DeactivateUser))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/restore", permitted(models.PermissionUsersModerate, d.admin.RestoreUser))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/suspend", limit(jsonBodyLimit, permitted(models.PermissionUsersModerate, d.admin.SuspendUser)))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/unsuspend", permitted(models.PermissionUsersModerate, d.admin.UnsuspendUser))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/force-password-reset", permitted(models.PermissionUsersModerate, d.admin.ForcePasswordReset))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/revoke-sessions", permitted(models.PermissionUsersModerate, d.admin.RevokeSessions))
+	mux.HandleFunc("GET /api/v1/admin/audit-events", permitted(models.PermissionAuditRead, d.admin.ListAuditEvents))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/verify-email", permitted(models.PermissionUsersModerate, d.admin.VerifyEmail))
+	mux.HandleFunc("GET /api/v1/admin/feature-flags", permitted(models.PermissionFeatureFlagsManage, d.featureFlags.List))
+	mux.HandleFunc("PATCH /api/v1/admin/feature-flags/{key}", limit(jsonBodyLimit, permitted(models.PermissionFeatureFlagsManage, d.featureFlags.Update)))
+}
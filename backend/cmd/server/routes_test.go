@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"resellution/backend/internal/health"
+	"resellution/backend/internal/openapi"
+	"resellution/backend/internal/ratelimit"
+)
+
+type recordingMux struct {
+	patterns []string
+}
+
+func (m *recordingMux) HandleFunc(pattern string, _ func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := &recordingMux{}
+	registerRoutes(mux, routeDeps{
+		health:       &health.Checker{},
+		spec:         spec,
+		resetLimiter: ratelimit.NewIPRateLimiter(1, 1),
+	})
+
+	registered := map[string]bool{}
+	for _, pattern := range mux.patterns {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			t.Errorf("route %q has no method; register routes as \"METHOD /path\"", pattern)
+			continue
+		}
+		registered[method+" "+path] = true
+		if _, ok := spec.Find(method, path); !ok {
+			t.Errorf("route %s %s is missing from internal/openapi/openapi.json", method, path)
+		}
+	}
+	for _, op := range spec.Operations() {
+		if !registered[op.Method+" "+op.Path] {
+			t.Errorf("openapi.json documents %s %s, which is not registered", op.Method, op.Path)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"resellution/backend/internal/clientip"
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/observability"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionUserRegistered          = "user.registered"
+	ActionUserLogin               = "user.login"
+	ActionUserLoginFailed         = "user.login_failed"
+	ActionUserLogout              = "user.logout"
+	ActionUserProfileUpdated      = "user.profile_updated"
+	ActionUserDeactivated         = "user.deactivated"
+	ActionPasswordResetRequested  = "user.password_reset_requested"
+	ActionPasswordResetCompleted  = "user.password_reset_completed"
+	ActionAdminUserRoleChanged    = "admin.user.role_changed"
+	ActionAdminUserDeactivated    = "admin.user.deactivated"
+	ActionAdminUserRestored       = "admin.user.restored"
+	ActionAdminUserSuspended      = "admin.user.suspended"
+	ActionAdminUserUnsuspended    = "admin.user.unsuspended"
+	ActionAdminUserPasswordReset  = "admin.user.password_reset_forced"
+	ActionAdminUserSessionRevoked = "admin.user.sessions_revoked"
+	ActionAdminUserEmailVerified  = "admin.user.email_verified"
+	ActionAdminCategoryCreated    = "admin.category.created"
+	ActionAdminCategoryUpdated    = "admin.category.updated"
+	ActionAdminCategoryDeleted    = "admin.category.deleted"
+	ActionAdminFeatureFlagUpdated = "admin.feature_flag.updated"
+)
+
+const (
+	TargetUser        = "user"
+	TargetCategory    = "category"
+	TargetListing     = "listing"
+	TargetFeatureFlag = "feature_flag"
+)
+
+// Event describes a change to record. Before and After are snapshots of the target
+// (any JSON-marshalable value, nil for creations and deletions); only the fields that
+// differ are stored.
+type Event struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     any
+	After      any
+	Metadata   map[string]any
+}
+
+// Change is the before and after value of a single field.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// StoredEvent is an audit event as returned to admins.
+type StoredEvent struct {
+	ID         int64             `json:"id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	ActorID    string            `json:"actor_id,omitempty"`
+	Action     string            `json:"action"`
+	TargetType string            `json:"target_type"`
+	TargetID   string            `json:"target_id,omitempty"`
+	IPAddress  string            `json:"ip_address,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	Changes    map[string]Change `json:"changes,omitempty"`
+	Metadata   map[string]any    `json:"metadata,omitempty"`
+}
+
+// Recorder writes audit events. Record joins the transaction carried by ctx (see
+// db.TxManager) so an event is only persisted when the change it describes commits.
+type Recorder struct {
+	DB *sql.DB
+}
+
+// ignoredFields change on every write and would only add noise to diffs.
+var ignoredFields = map[string]struct{}{
+	"updated_at": {},
+	"updated_by": {},
+}
+
+func (r Recorder) Record(ctx context.Context, event Event) error {
+	if event.ActorID == "" {
+		event.ActorID, _ = middleware.UserIDFromContext(ctx)
+	}
+	requestID, _ := observability.RequestIDFromContext(ctx)
+	ipAddress, _ := clientip.FromContext(ctx)
+
+	changes, err := Diff(event.Before, event.After)
+	if err != nil {
+		return err
+	}
+	changesJSON, err := marshalNullable(changes)
+	if err != nil {
+		return err
+	}
+	metadataJSON, err := marshalNullable(event.Metadata)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_events (actor_id, action, target_type, target_id, ip_address, request_id, changes, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err = db.Conn(ctx, r.DB).ExecContext(ctx, query,
+		nullIfEmpty(event.ActorID),
+		event.Action,
+		event.TargetType,
+		nullIfEmpty(event.TargetID),
+		nullIfEmpty(ipAddress),
+		nullIfEmpty(requestID),
+		changesJSON,
+		metadataJSON,
+	)
+	return err
+}
+
+// Diff returns the top-level JSON fields that differ between before and after.
+func Diff(before, after any) (map[string]Change, error) {
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for key, afterValue := range afterFields {
+		if _, ignored := ignoredFields[key]; ignored {
+			continue
+		}
+		beforeValue := beforeFields[key]
+		if !reflect.DeepEqual(beforeValue, afterValue) {
+			changes[key] = Change{Before: beforeValue, After: afterValue}
+		}
+	}
+	for key, beforeValue := range beforeFields {
+		if _, ignored := ignoredFields[key]; ignored {
+			continue
+		}
+		if _, ok := afterFields[key]; !ok {
+			changes[key] = Change{Before: beforeValue, After: nil}
+		}
+	}
+	return changes, nil
+}
+
+func toFields(v any) (map[string]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("audit snapshot: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("audit snapshot must be a JSON object: %w", err)
+	}
+	return fields, nil
+}
+
+// Filter narrows an audit log query. Empty fields are ignored.
+type Filter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// Query returns matching events, newest first, and the total number of matches.
+func (r Recorder) Query(ctx context.Context, filter Filter) ([]StoredEvent, int, error) {
+	var conditions []string
+	var args []any
+	addCondition := func(format string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.ActorID != "" {
+		addCondition("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		addCondition("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		addCondition("target_id = $%d", filter.TargetID)
+	}
+	if !filter.From.IsZero() {
+		addCondition("occurred_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("occurred_at < $%d", filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT id, occurred_at, actor_id, action, target_type, target_id, ip_address, request_id, changes, metadata, COUNT(*) OVER ()
+		FROM audit_events
+		%s
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)-1, len(args))
+
+	rows, err := db.Conn(ctx, r.DB).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []StoredEvent{}
+	total := 0
+	for rows.Next() {
+		var event StoredEvent
+		var actorID, targetID, ipAddress, requestID sql.NullString
+		var changes, metadata []byte
+		if err := rows.Scan(
+			&event.ID,
+			&event.OccurredAt,
+			&actorID,
+			&event.Action,
+			&event.TargetType,
+			&targetID,
+			&ipAddress,
+			&requestID,
+			&changes,
+			&metadata,
+			&total,
+		); err != nil {
+			return nil, 0, err
+		}
+		event.ActorID = actorID.String
+		event.TargetID = targetID.String
+		event.IPAddress = ipAddress.String
+		event.RequestID = requestID.String
+		if len(changes) > 0 {
+			if err := json.Unmarshal(changes, &event.Changes); err != nil {
+				return nil, 0, err
+			}
+		}
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &event.Metadata); err != nil {
+				return nil, 0, err
+			}
+		}
+		events = append(events, event)
+	}
+	return events, total, rows.Err()
+}
+
+// Purge deletes events that occurred before cutoff and returns how many were removed.
+// It is the only path allowed past the append-only trigger.
+func (r Recorder) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	var deleted int64
+	err := db.TxManager{DB: r.DB}.WithTx(ctx, func(ctx context.Context) error {
+		conn := db.Conn(ctx, r.DB)
+		if _, err := conn.ExecContext(ctx, `SET LOCAL audit.allow_purge = 'on'`); err != nil {
+			return err
+		}
+		result, err := conn.ExecContext(ctx, `DELETE FROM audit_events WHERE occurred_at < $1`, cutoff)
+		if err != nil {
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
+
+func marshalNullable[T any](v map[string]T) (any, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
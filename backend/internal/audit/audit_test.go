@@ -0,0 +1,41 @@
+package audit
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	type profile struct {
+		Name      string `json:"name"`
+		City      string `json:"city,omitempty"`
+		UpdatedAt string `json:"updated_at"`
+	}
+
+	changes, err := Diff(
+		profile{Name: "Asha", City: "Pune", UpdatedAt: "t1"},
+		profile{Name: "Asha K", UpdatedAt: "t2"},
+	)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d: %v", len(changes), changes)
+	}
+	if c := changes["name"]; c.Before != "Asha" || c.After != "Asha K" {
+		t.Errorf("name change = %+v", c)
+	}
+	if c := changes["city"]; c.Before != "Pune" || c.After != nil {
+		t.Errorf("city change = %+v", c)
+	}
+	if _, ok := changes["updated_at"]; ok {
+		t.Error("updated_at should be ignored")
+	}
+}
+
+func TestDiff_Creation(t *testing.T) {
+	changes, err := Diff(nil, map[string]any{"slug": "books"})
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if c := changes["slug"]; c.Before != nil || c.After != "books" {
+		t.Errorf("slug change = %+v", c)
+	}
+}
@@ -0,0 +1,178 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type contextKey string
+
+const clientIPContextKey contextKey = "client_ip"
+
+// Forwarding headers a Resolver can read.
+const (
+	HeaderXForwardedFor = "x-forwarded-for"
+	HeaderForwarded     = "forwarded"
+)
+
+// Resolver determines the client IP of a request. The forwarding header is only honoured
+// when the connecting peer is a trusted proxy, and the chain is walked from the right so
+// a client cannot spoof its address by prepending hops.
+type Resolver struct {
+	trusted []*net.IPNet
+	header  string
+}
+
+// NewResolver builds a resolver from trusted proxy CIDRs and the forwarding header those
+// proxies set, HeaderXForwardedFor or HeaderForwarded. Only that header is read: a proxy
+// that appends to one passes the other through from the client unchanged. Bare IPs are
+// accepted as single-host ranges.
+func NewResolver(trustedCIDRs []string, header string) (*Resolver, error) {
+	header = strings.ToLower(strings.TrimSpace(header))
+	if header != HeaderXForwardedFor && header != HeaderForwarded {
+		return nil, fmt.Errorf("invalid trusted proxy header %q", header)
+	}
+	r := &Resolver{header: header}
+	for _, raw := range trustedCIDRs {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "/") {
+			ip := net.ParseIP(raw)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", raw)
+			}
+			if ip.To4() != nil {
+				raw += "/32"
+			} else {
+				raw += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return r, nil
+}
+
+// Resolve returns the client IP for req from the resolver's forwarding header.
+func (r *Resolver) Resolve(req *http.Request) string {
+	remote := remoteIP(req.RemoteAddr)
+	if !r.isTrusted(remote) {
+		return remote
+	}
+
+	var hops []string
+	if r.header == HeaderForwarded {
+		hops = parseForwarded(req.Header.Values("Forwarded"))
+	} else {
+		hops = parseXForwardedFor(req.Header.Values("X-Forwarded-For"))
+	}
+
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// Unknown or obfuscated hop: nothing to its left can be trusted.
+			break
+		}
+		client = ip.String()
+		if !r.isTrusted(client) {
+			break
+		}
+	}
+	return client
+}
+
+func (r *Resolver) isTrusted(rawIP string) bool {
+	ip := net.ParseIP(rawIP)
+	if ip == nil {
+		return false
+	}
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware resolves the client IP once and stores it in the request context.
+func Middleware(resolver *Resolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPContextKey, resolver.Resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPContextKey).(string)
+	return ip, ok
+}
+
+// FromRequest returns the resolved client IP, falling back to the peer address when the
+// middleware has not run.
+func FromRequest(r *http.Request) string {
+	if ip, ok := FromContext(r.Context()); ok {
+		return ip
+	}
+	return remoteIP(r.RemoteAddr)
+}
+
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func parseXForwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+func parseForwarded(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(key, "for") {
+					continue
+				}
+				hop = forwardedNodeIP(val)
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// forwardedNodeIP strips quotes, IPv6 brackets and ports from a Forwarded node, e.g.
+// `"[2001:db8:cafe::17]:4711"` or `192.0.2.60:8080`.
+func forwardedNodeIP(node string) string {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
@@ -0,0 +1,60 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	xff, err := NewResolver([]string{"10.0.0.0/8", "127.0.0.1"}, HeaderXForwardedFor)
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+	forwarded, err := NewResolver([]string{"10.0.0.0/8", "127.0.0.1"}, HeaderForwarded)
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		resolver   *Resolver
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"untrusted peer ignores headers", xff, "203.0.113.9:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "203.0.113.9"},
+		{"no headers", xff, "10.0.0.5:1234", nil, "10.0.0.5"},
+		{"single hop", xff, "10.0.0.5:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"spoofed leftmost hop", xff, "10.0.0.5:1234", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7, 10.1.1.1"}, "198.51.100.7"},
+		{"all hops trusted", xff, "127.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.2.2.2, 10.1.1.1"}, "10.2.2.2"},
+		{"garbage hop", xff, "10.0.0.5:1234", map[string]string{"X-Forwarded-For": "nonsense, 10.1.1.1"}, "10.1.1.1"},
+		{"forwarded header", forwarded, "10.0.0.5:1234", map[string]string{"Forwarded": `for=192.0.2.60;proto=http;by=203.0.113.43`}, "192.0.2.60"},
+		{"forwarded ipv6 with port", forwarded, "10.0.0.5:1234", map[string]string{"Forwarded": `for="[2001:db8:cafe::17]:4711", for=10.1.1.1`}, "2001:db8:cafe::17"},
+		{"forwarded ignores xff", forwarded, "10.0.0.5:1234", map[string]string{"Forwarded": "for=192.0.2.60", "X-Forwarded-For": "198.51.100.7"}, "192.0.2.60"},
+		{"xff ignores client forwarded", xff, "10.0.0.5:1234", map[string]string{"Forwarded": "for=1.2.3.4", "X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"forwarded ignores client xff", forwarded, "10.0.0.5:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "10.0.0.5"},
+		{"forwarded obfuscated", forwarded, "10.0.0.5:1234", map[string]string{"Forwarded": "for=_hidden, for=10.1.1.1"}, "10.1.1.1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			if got := tc.resolver.Resolve(req); got != tc.want {
+				t.Errorf("Resolve() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewResolver_InvalidCIDR(t *testing.T) {
+	if _, err := NewResolver([]string{"not-an-ip"}, HeaderXForwardedFor); err == nil {
+		t.Error("expected error for invalid trusted proxy")
+	}
+	if _, err := NewResolver(nil, "x-real-ip"); err == nil {
+		t.Error("expected error for unsupported header")
+	}
+}
@@ -1,157 +1,169 @@
 package config
 
 import (
-	"bufio"
 	"errors"
+	"net/url"
 	"os"
-	"strconv"
 	"strings"
 )
 
+// Config is the server configuration. Each field is read from the environment
+// variable named by its env tag, falling back to the config file named by CONFIG_FILE
+// and then to the default tag. The tags also declare validation:
+//
+//	env:"NAME,required,file,lower"  required fields have no default; file fields may be
+//	                                read from the file named by NAME_FILE; lower values
+//	                                are lower-cased
+//	default:"value"                 used when no source sets the variable
+//	min:"n" max:"n"                 bounds for numbers, minimum length for strings
+//	oneof:"a b c"                   allowed values
+//
+// Lists are comma-separated.
 type Config struct {
-	Port                       string
-	DatabaseURL                string
-	TokenSecret                string
-	TokenExpiryHours           int
-	PasswordResetExpiryMinutes int
-	PasswordResetCooldownMinutes int
-	PasswordResetOTPDigits     int
-	PasswordResetMaxAttempts   int
-	PasswordResetRateLimitPerIP int
-	PasswordResetRateLimitWindowMinutes int
-	SMTPHost                   string
-	SMTPPort                   string
-	SMTPUsername               string
-	SMTPPassword               string
-	SMTPFromEmail              string
-	SMTPFromName               string
-	CorsOrigin                 string
+	AppEnv                              string   `env:"APP_ENV,lower" default:"production" oneof:"development staging production"`
+	Port                                string   `env:"PORT" default:"8080"`
+	DatabaseURL                         string   `env:"DATABASE_URL,required,file"`
+	DatabaseReplicaURLs                 []string `env:"DATABASE_REPLICA_URLS,file"`
+	DBReplicaMaxLagSeconds              int      `env:"DB_REPLICA_MAX_LAG_SECONDS" default:"10" min:"1"`
+	DBReplicaCheckSeconds               int      `env:"DB_REPLICA_CHECK_SECONDS" default:"5" min:"1"`
+	DBMaxConns                          int      `env:"DB_MAX_CONNS" default:"10" min:"1"`
+	DBMinConns                          int      `env:"DB_MIN_CONNS" default:"0" min:"0"`
+	DBMaxConnLifetimeMinutes            int      `env:"DB_MAX_CONN_LIFETIME_MINUTES" default:"60" min:"0"`
+	DBMaxConnIdleMinutes                int      `env:"DB_MAX_CONN_IDLE_MINUTES" default:"15" min:"0"`
+	DBStatementTimeoutMs                int      `env:"DB_STATEMENT_TIMEOUT_MS" default:"5000" min:"0"`
+	DBTxMaxRetries                      int      `env:"DB_TX_MAX_RETRIES" default:"3" min:"0" max:"10"`
+	TokenSecret                         string   `env:"TOKEN_SECRET,required,file" min:"32"`
+	TokenExpiryHours                    int      `env:"TOKEN_EXPIRY_HOURS" default:"24" min:"1" max:"8760"`
+	PasswordResetExpiryMinutes          int      `env:"PASSWORD_RESET_EXPIRY_MINUTES" default:"15" min:"1" max:"1440"`
+	PasswordResetCooldownMinutes        int      `env:"PASSWORD_RESET_COOLDOWN_MINUTES" default:"5" min:"0"`
+	PasswordResetOTPDigits              int      `env:"PASSWORD_RESET_OTP_DIGITS" default:"6" min:"4" max:"10"`
+	PasswordResetMaxAttempts            int      `env:"PASSWORD_RESET_MAX_ATTEMPTS" default:"5" min:"1"`
+	PasswordResetRateLimitPerIP         int      `env:"PASSWORD_RESET_RATE_LIMIT_PER_IP" default:"5" min:"1"`
+	PasswordResetRateLimitWindowMinutes int      `env:"PASSWORD_RESET_RATE_LIMIT_WINDOW_MINUTES" default:"60" min:"1"`
+	RateLimitStore                      string   `env:"RATE_LIMIT_STORE,lower" default:"memory" oneof:"memory postgres"`
+	RateLimitFallback                   bool     `env:"RATE_LIMIT_FALLBACK" default:"true"`
+	RateLimitStoreTimeoutMs             int      `env:"RATE_LIMIT_STORE_TIMEOUT_MS" default:"200" min:"1"`
+	SMTPHost                            string   `env:"SMTP_HOST"`
+	SMTPPort                            string   `env:"SMTP_PORT" default:"587"`
+	SMTPUsername                        string   `env:"SMTP_USERNAME"`
+	SMTPPassword                        string   `env:"SMTP_PASSWORD,file"`
+	SMTPFromEmail                       string   `env:"SMTP_FROM_EMAIL" default:"no-reply@resellution.local"`
+	SMTPFromName                        string   `env:"SMTP_FROM_NAME" default:"ReSellution"`
+	CorsOrigins                         []string `env:"CORS_ORIGIN" default:"http://localhost:5173,http://127.0.0.1:5173"`
+	CorsAllowedMethods                  []string `env:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,PATCH,DELETE,OPTIONS"`
+	CorsAllowedHeaders                  []string `env:"CORS_ALLOWED_HEADERS" default:"Authorization,Content-Type,X-Request-ID,Idempotency-Key,If-Match,If-None-Match"`
+	CorsExposedHeaders                  []string `env:"CORS_EXPOSED_HEADERS" default:"X-Request-ID,Retry-After,Idempotent-Replayed,ETag"`
+	CorsAllowCredentials                bool     `env:"CORS_ALLOW_CREDENTIALS" default:"false"`
+	CorsMaxAgeSeconds                   int      `env:"CORS_MAX_AGE_SECONDS" default:"600" min:"0"`
+	TrustedProxyCIDRs                   []string `env:"TRUSTED_PROXY_CIDRS"`
+	TrustedProxyHeader                  string   `env:"TRUSTED_PROXY_HEADER,lower" default:"x-forwarded-for" oneof:"x-forwarded-for forwarded"`
+	AdminBootstrapEmail                 string   `env:"ADMIN_BOOTSTRAP_EMAIL,lower"`
+	AuditRetentionDays                  int      `env:"AUDIT_RETENTION_DAYS" default:"365" min:"1"`
+	MigrationsRequireCurrent            bool     `env:"MIGRATIONS_REQUIRE_CURRENT" default:"false"`
+	ShutdownTimeoutSeconds              int      `env:"SHUTDOWN_TIMEOUT_SECONDS" default:"20" min:"1"`
+	ShutdownDelaySeconds                int      `env:"SHUTDOWN_DELAY_SECONDS" default:"0" min:"0"`
+	HealthCheckTimeoutMs                int      `env:"HEALTH_CHECK_TIMEOUT_MS" default:"2000" min:"1"`
+	TracingExporter                     string   `env:"TRACING_EXPORTER,lower" default:"none" oneof:"none stdout otlphttp"`
+	TracingSampleRatio                  float64  `env:"TRACING_SAMPLE_RATIO" default:"1" min:"0" max:"1"`
+	LogLevel                            string   `env:"LOG_LEVEL,lower" default:"info" oneof:"debug info warn error"`
+	LogRedactKeys                       []string `env:"LOG_REDACT_KEYS" default:"email,phone,token,otp,password,authorization"`
+	LogResetOTP                         bool     `env:"LOG_RESET_OTP" default:"false"`
+	OpenAPIValidateRequests             bool     `env:"OPENAPI_VALIDATE_REQUESTS" default:"false"`
+	MaxRequestBodyBytes                 int64    `env:"MAX_REQUEST_BODY_BYTES" default:"1048576" min:"1"`
+	HSTSMaxAgeSeconds                   int      `env:"HSTS_MAX_AGE_SECONDS" default:"63072000" min:"0"`
+	CompressionEnabled                  bool     `env:"COMPRESSION_ENABLED" default:"true"`
+	CompressionMinBytes                 int      `env:"COMPRESSION_MIN_BYTES" default:"1024" min:"0"`
+	TLSMode                             string   `env:"TLS_MODE,lower" default:"off" oneof:"off files self-signed"`
+	TLSCertFile                         string   `env:"TLS_CERT_FILE"`
+	TLSKeyFile                          string   `env:"TLS_KEY_FILE"`
+	FeatureFlagRefreshSeconds           int      `env:"FEATURE_FLAG_REFRESH_SECONDS" default:"30" min:"1"`
 }
 
+// Load reads the configuration. Variables in ./.env fill in what the environment does
+// not set, and CONFIG_FILE names an optional YAML or TOML file layered under both.
+// Every problem is reported at once, joined into the returned error.
 func Load() (Config, error) {
-	loadDotEnv(".env")
-
-	expiryHours := 24
-	if raw := os.Getenv("TOKEN_EXPIRY_HOURS"); raw != "" {
-		parsed, err := strconv.Atoi(raw)
-		if err != nil {
-			return Config{}, err
-		}
-		expiryHours = parsed
+	var errs []error
+	if err := loadDotEnv(".env"); err != nil {
+		errs = append(errs, err)
 	}
-	passwordResetExpiryMinutes := 15
-	if raw := os.Getenv("PASSWORD_RESET_EXPIRY_MINUTES"); raw != "" {
-		parsed, err := strconv.Atoi(raw)
+	var fileValues map[string]string
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := readConfigFile(path)
 		if err != nil {
-			return Config{}, err
+			errs = append(errs, err)
 		}
-		passwordResetExpiryMinutes = parsed
+		fileValues = values
 	}
-	passwordResetCooldownMinutes := 5
-	if raw := os.Getenv("PASSWORD_RESET_COOLDOWN_MINUTES"); raw != "" {
-		parsed, err := strconv.Atoi(raw)
-		if err != nil {
-			return Config{}, err
-		}
-		passwordResetCooldownMinutes = parsed
+
+	cfg, loadErrs := load(os.LookupEnv, fileValues)
+	if err := errors.Join(append(errs, loadErrs...)...); err != nil {
+		return Config{}, err
 	}
-	passwordResetOTPDigits := 6
-	if raw := os.Getenv("PASSWORD_RESET_OTP_DIGITS"); raw != "" {
-		parsed, err := strconv.Atoi(raw)
-		if err != nil {
-			return Config{}, err
-		}
-		passwordResetOTPDigits = parsed
+	return cfg, nil
+}
+
+// load decodes a Config from the environment and the config file values, then checks
+// the rules that span fields.
+func load(lookupEnv func(string) (string, bool), fileValues map[string]string) (Config, []error) {
+	var cfg Config
+	errs := decode(&cfg, lookupEnv, fileValues)
+
+	if len(cfg.LogRedactKeys) == 1 && cfg.LogRedactKeys[0] == "none" {
+		cfg.LogRedactKeys = nil
 	}
-	passwordResetMaxAttempts := 5
-	if raw := os.Getenv("PASSWORD_RESET_MAX_ATTEMPTS"); raw != "" {
-		parsed, err := strconv.Atoi(raw)
-		if err != nil {
-			return Config{}, err
-		}
-		passwordResetMaxAttempts = parsed
+	if cfg.DBMinConns > cfg.DBMaxConns {
+		errs = append(errs, errors.New("DB_MIN_CONNS must not exceed DB_MAX_CONNS"))
 	}
-	passwordResetRateLimitPerIP := 5
-	if raw := os.Getenv("PASSWORD_RESET_RATE_LIMIT_PER_IP"); raw != "" {
-		parsed, err := strconv.Atoi(raw)
-		if err != nil {
-			return Config{}, err
-		}
-		passwordResetRateLimitPerIP = parsed
+	if cfg.LogResetOTP && cfg.AppEnv == "production" {
+		errs = append(errs, errors.New("LOG_RESET_OTP is for local development and is refused when APP_ENV=production"))
 	}
-	passwordResetRateLimitWindowMinutes := 60
-	if raw := os.Getenv("PASSWORD_RESET_RATE_LIMIT_WINDOW_MINUTES"); raw != "" {
-		parsed, err := strconv.Atoi(raw)
-		if err != nil {
-			return Config{}, err
-		}
-		passwordResetRateLimitWindowMinutes = parsed
+	if cfg.TLSMode == "files" && (cfg.TLSCertFile == "" || cfg.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_MODE=files requires TLS_CERT_FILE and TLS_KEY_FILE"))
 	}
+	return cfg, errs
+}
 
-	cfg := Config{
-		Port:                       envOrDefault("PORT", "8080"),
-		DatabaseURL:                os.Getenv("DATABASE_URL"),
-		TokenSecret:                os.Getenv("TOKEN_SECRET"),
-		TokenExpiryHours:           expiryHours,
-		PasswordResetExpiryMinutes: passwordResetExpiryMinutes,
-		PasswordResetCooldownMinutes: passwordResetCooldownMinutes,
-		PasswordResetOTPDigits:     passwordResetOTPDigits,
-		PasswordResetMaxAttempts:   passwordResetMaxAttempts,
-		PasswordResetRateLimitPerIP: passwordResetRateLimitPerIP,
-		PasswordResetRateLimitWindowMinutes: passwordResetRateLimitWindowMinutes,
-		SMTPHost:                   os.Getenv("SMTP_HOST"),
-		SMTPPort:                   envOrDefault("SMTP_PORT", "587"),
-		SMTPUsername:               os.Getenv("SMTP_USERNAME"),
-		SMTPPassword:               os.Getenv("SMTP_PASSWORD"),
-		SMTPFromEmail:              envOrDefault("SMTP_FROM_EMAIL", "no-reply@resellution.local"),
-		SMTPFromName:               envOrDefault("SMTP_FROM_NAME", "ReSellution"),
-		CorsOrigin:                 envOrDefault("CORS_ORIGIN", "http://localhost:5173,http://127.0.0.1:5173"),
-	}
+// redactedValue replaces secrets in Redacted.
+const redactedValue = "[redacted]"
 
-	if cfg.DatabaseURL == "" {
-		return Config{}, errors.New("DATABASE_URL is required")
+// Redacted returns a copy of c that is safe to print: secrets are replaced and
+// passwords are removed from database URLs.
+func (c Config) Redacted() Config {
+	if c.TokenSecret != "" {
+		c.TokenSecret = redactedValue
 	}
-	if cfg.TokenSecret == "" {
-		return Config{}, errors.New("TOKEN_SECRET is required")
+	if c.SMTPPassword != "" {
+		c.SMTPPassword = redactedValue
 	}
-
-	return cfg, nil
-}
-
-func envOrDefault(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	c.DatabaseURL = redactURL(c.DatabaseURL)
+	replicas := make([]string, len(c.DatabaseReplicaURLs))
+	for i, replica := range c.DatabaseReplicaURLs {
+		replicas[i] = redactURL(replica)
 	}
-	return fallback
+	c.DatabaseReplicaURLs = replicas
+	return c
 }
 
-func loadDotEnv(path string) {
-	file, err := os.Open(path)
-	if err != nil {
-		return
+// redactURL masks the password of a URL, or the whole value when it is not a URL, since
+// a key/value DSN may also carry one.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
-		if key == "" {
-			continue
-		}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == "" {
+		return redactedValue
+	}
+	return parsed.Redacted()
+}
 
-		if _, exists := os.LookupEnv(key); !exists {
-			_ = os.Setenv(key, value)
+func splitCSV(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			values = append(values, trimmed)
 		}
 	}
+	return values
 }
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fieldSpec is the parsed form of a Config field's tags.
+type fieldSpec struct {
+	name     string
+	required bool
+	file     bool
+	lower    bool
+	def      string
+	hasDef   bool
+	min, max string
+	oneof    []string
+}
+
+func parseSpec(field reflect.StructField) (fieldSpec, bool) {
+	tag, ok := field.Tag.Lookup("env")
+	if !ok {
+		return fieldSpec{}, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	spec := fieldSpec{name: name, min: field.Tag.Get("min"), max: field.Tag.Get("max")}
+	for _, option := range strings.Split(options, ",") {
+		switch option {
+		case "required":
+			spec.required = true
+		case "file":
+			spec.file = true
+		case "lower":
+			spec.lower = true
+		}
+	}
+	spec.def, spec.hasDef = field.Tag.Lookup("default")
+	if oneof := field.Tag.Get("oneof"); oneof != "" {
+		spec.oneof = strings.Fields(oneof)
+	}
+	return spec, true
+}
+
+// decode fills cfg field by field and returns every problem it finds rather than
+// stopping at the first. Config file keys that name no variable are problems too, so a
+// typo does not silently fall back to a default.
+func decode(cfg *Config, lookupEnv func(string) (string, bool), fileValues map[string]string) []error {
+	var errs []error
+	known := make(map[string]bool)
+	value := reflect.ValueOf(cfg).Elem()
+	for i := range value.NumField() {
+		spec, ok := parseSpec(value.Type().Field(i))
+		if !ok {
+			continue
+		}
+		known[spec.name] = true
+		if spec.file {
+			known[spec.name+"_FILE"] = true
+		}
+
+		raw, set, err := spec.resolve(lookupEnv, fileValues)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !set {
+			if spec.required {
+				errs = append(errs, fmt.Errorf("%s is required", spec.name))
+				continue
+			}
+			if !spec.hasDef {
+				continue
+			}
+			raw = spec.def
+		}
+		if spec.lower {
+			raw = strings.ToLower(raw)
+		}
+		if err := spec.set(value.Field(i), raw); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var unknown []string
+	for key := range fileValues {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("config file sets unknown variable %s", key))
+	}
+	return errs
+}
+
+// resolve returns the variable's raw value and whether any source sets it. The
+// environment wins over the config file. A file field may instead name a file holding
+// the value in NAME_FILE, as Docker and Kubernetes secrets are mounted; setting both
+// in the same source is an error.
+func (s fieldSpec) resolve(lookupEnv func(string) (string, bool), fileValues map[string]string) (string, bool, error) {
+	sources := []func(string) (string, bool){
+		func(key string) (string, bool) {
+			value, ok := lookupEnv(key)
+			value = strings.TrimSpace(value)
+			return value, ok && value != ""
+		},
+		func(key string) (string, bool) {
+			value, ok := fileValues[key]
+			return value, ok && value != ""
+		},
+	}
+	for _, lookup := range sources {
+		value, ok := lookup(s.name)
+		path, fromFile := "", false
+		if s.file {
+			path, fromFile = lookup(s.name + "_FILE")
+		}
+		switch {
+		case ok && fromFile:
+			return "", false, fmt.Errorf("%s and %s_FILE are both set; use one", s.name, s.name)
+		case fromFile:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return "", false, fmt.Errorf("%s_FILE: %w", s.name, err)
+			}
+			return strings.TrimSpace(string(content)), true, nil
+		case ok:
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// set parses raw into field and checks it against the spec's bounds. Values of file
+// fields are secrets and are left out of error messages.
+func (s fieldSpec) set(field reflect.Value, raw string) error {
+	shown := fmt.Sprintf(", got %q", raw)
+	if s.file {
+		shown = ""
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		if s.min != "" {
+			minLen, _ := strconv.Atoi(s.min)
+			if len(raw) < minLen {
+				return fmt.Errorf("%s must be at least %d characters", s.name, minLen)
+			}
+		}
+		if s.oneof != nil && !slices.Contains(s.oneof, raw) {
+			return fmt.Errorf("%s must be one of %s%s", s.name, strings.Join(s.oneof, ", "), shown)
+		}
+		field.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer%s", s.name, shown)
+		}
+		if s.min != "" {
+			if minValue, _ := strconv.ParseInt(s.min, 10, 64); n < minValue {
+				return fmt.Errorf("%s must be at least %d, got %d", s.name, minValue, n)
+			}
+		}
+		if s.max != "" {
+			if maxValue, _ := strconv.ParseInt(s.max, 10, 64); n > maxValue {
+				return fmt.Errorf("%s must be at most %d, got %d", s.name, maxValue, n)
+			}
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("%s is out of range, got %d", s.name, n)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number%s", s.name, shown)
+		}
+		if s.min != "" {
+			if minValue, _ := strconv.ParseFloat(s.min, 64); f < minValue {
+				return fmt.Errorf("%s must be at least %g, got %g", s.name, minValue, f)
+			}
+		}
+		if s.max != "" {
+			if maxValue, _ := strconv.ParseFloat(s.max, 64); f > maxValue {
+				return fmt.Errorf("%s must be at most %g, got %g", s.name, maxValue, f)
+			}
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false%s", s.name, shown)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(splitCSV(raw)))
+	default:
+		return fmt.Errorf("%s has unsupported type %s", s.name, field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// loadDotEnv sets the variables in the .env file at path that the environment does not
+// already set. A missing file is not an error.
+func loadDotEnv(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	vars, err := parseDotEnv(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for key, value := range vars {
+		if _, exists := os.LookupEnv(key); !exists {
+			_ = os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// parseDotEnv parses the .env format shells and Docker Compose accept:
+//
+//	KEY=value            surrounding spaces are trimmed; " #" starts a comment
+//	export KEY=value     the export prefix is ignored
+//	KEY='value'          single quotes keep the value literally
+//	KEY="a\nb"           double quotes process \n, \r, \t, \" and \\ and may span lines
+//
+// Malformed lines are reported together, by line number.
+func parseDotEnv(src string) (map[string]string, error) {
+	vars := make(map[string]string)
+	var errs []error
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validEnvKey(key) {
+			errs = append(errs, fmt.Errorf("line %d: expected KEY=value", lineNo))
+			continue
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		var rest string
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				errs = append(errs, fmt.Errorf("line %d: unterminated single quote", lineNo))
+				continue
+			}
+			value, rest = value[1:end+1], value[end+2:]
+		case strings.HasPrefix(value, `"`):
+			body := value[1:]
+			end := closingQuote(body)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				body += "\n" + lines[i]
+				end = closingQuote(body)
+			}
+			if end < 0 {
+				errs = append(errs, fmt.Errorf("line %d: unterminated double quote", lineNo))
+				continue
+			}
+			value, rest = unescapeDoubleQuoted(body[:end]), body[end+1:]
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = value[:comment]
+			} else if comment := strings.Index(value, "\t#"); comment >= 0 {
+				value = value[:comment]
+			}
+			value = strings.TrimSpace(value)
+		}
+		if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+			errs = append(errs, fmt.Errorf("line %d: unexpected text after closing quote", lineNo))
+			continue
+		}
+		vars[key] = value
+	}
+	return vars, errors.Join(errs...)
+}
+
+func validEnvKey(key string) bool {
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+	for _, c := range key {
+		if !(c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// closingQuote returns the index of the first unescaped double quote in s, or -1.
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+var doubleQuoteEscapes = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+
+func unescapeDoubleQuoted(s string) string {
+	return doubleQuoteEscapes.Replace(s)
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	src := strings.Join([]string{
+		"# comment",
+		"PLAIN=value",
+		"  SPACED = padded value  ",
+		"export EXPORTED=yes",
+		"COMMENTED=value # trailing comment",
+		"HASH=abc#def",
+		"SINGLE='keep # and \\n literally' # comment",
+		`DOUBLE="say \"hi\"\n\\done" # comment`,
+		`MULTI="-----BEGIN KEY-----`,
+		`abc`,
+		`-----END KEY-----"`,
+		"EMPTY=",
+		"EQUALS=a=b",
+		"WINDOWS=crlf\r",
+	}, "\n")
+
+	vars, err := parseDotEnv(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"PLAIN":     "value",
+		"SPACED":    "padded value",
+		"EXPORTED":  "yes",
+		"COMMENTED": "value",
+		"HASH":      "abc#def",
+		"SINGLE":    "keep # and \\n literally",
+		"DOUBLE":    "say \"hi\"\n\\done",
+		"MULTI":     "-----BEGIN KEY-----\nabc\n-----END KEY-----",
+		"EMPTY":     "",
+		"EQUALS":    "a=b",
+		"WINDOWS":   "crlf",
+	}
+	for key, value := range want {
+		if vars[key] != value {
+			t.Errorf("%s = %q, want %q", key, vars[key], value)
+		}
+	}
+}
+
+func TestParseDotEnvReportsEveryError(t *testing.T) {
+	_, err := parseDotEnv("GOOD=1\nno equals sign\n1BAD=x\nOPEN='abc\nJOINED='it''s'\nLAST=\"never closed")
+	if err == nil {
+		t.Fatal("want an error")
+	}
+	for _, want := range []string{"line 2:", "line 3:", "line 4: unterminated single quote", "line 5: unexpected text after closing quote", "line 6: unterminated double quote"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readConfigFile reads a YAML (.yaml, .yml) or TOML (.toml) config file into variable
+// names and raw values. Keys are the variable names in any case, and nested tables join
+// their keys with underscores, so both
+//
+//	database_url: postgres://...
+//	database:
+//	  url: postgres://...
+//
+// set DATABASE_URL. Lists become comma-separated values.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE: %w", err)
+	}
+
+	var doc map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("CONFIG_FILE %s: unsupported format; use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten(values, "", doc); err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flatten(values map[string]string, prefix string, table map[string]any) error {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := strings.ToUpper(prefix + key)
+		switch value := table[key].(type) {
+		case nil:
+		case map[string]any:
+			if err := flatten(values, name+"_", value); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				if !isScalar(item) {
+					return fmt.Errorf("%s: lists may only hold plain values", name)
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			if err := setOnce(values, name, strings.Join(items, ",")); err != nil {
+				return err
+			}
+		default:
+			if !isScalar(value) {
+				return fmt.Errorf("%s: unsupported value %v", name, value)
+			}
+			if err := setOnce(values, name, strings.TrimSpace(fmt.Sprint(value))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setOnce rejects a variable set both by a flat key and by a nested table.
+func setOnce(values map[string]string, name, value string) error {
+	if _, exists := values[name]; exists {
+		return fmt.Errorf("%s is set more than once", name)
+	}
+	values[name] = value
+	return nil
+}
+
+func isScalar(value any) bool {
+	switch value.(type) {
+	case string, bool, int, int64, uint64, float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+// Package cors implements Cross-Origin Resource Sharing for the API: origin allow-lists
+// with wildcard subdomains, preflight handling and credentialed requests.
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config describes which cross-origin requests are allowed.
+type Config struct {
+	// AllowedOrigins lists exact origins such as https://resellution.app, patterns with a
+	// leading wildcard label such as https://*.resellution.app, which match any subdomain
+	// but not the domain itself, or "*" for any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers clients may send; "*" allows any.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers scripts may read beyond the safelisted
+	// ones.
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and read credentialed responses. It
+	// cannot be combined with the "*" origin.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight result; zero leaves it to them.
+	MaxAge time.Duration
+}
+
+// Policy answers preflight requests and annotates responses to allowed origins.
+type Policy struct {
+	anyOrigin        bool
+	origins          map[string]struct{}
+	patterns         []pattern
+	methods          map[string]struct{}
+	allowedMethods   string
+	anyHeader        bool
+	headers          map[string]struct{}
+	allowedHeaders   string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// pattern is an origin with a wildcard first label: the scheme and port must match
+// and the host must end in suffix.
+type pattern struct {
+	scheme string
+	suffix string
+	port   string
+}
+
+// New validates cfg and returns its policy.
+func New(cfg Config) (*Policy, error) {
+	p := &Policy{
+		origins:          make(map[string]struct{}),
+		methods:          make(map[string]struct{}),
+		headers:          make(map[string]struct{}),
+		allowCredentials: cfg.AllowCredentials,
+	}
+
+	var errs []error
+	for _, origin := range cfg.AllowedOrigins {
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			pat, err := parsePattern(origin)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			p.patterns = append(p.patterns, pat)
+		default:
+			normalized, err := normalizeOrigin(origin)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("origin %q: %w", origin, err))
+				continue
+			}
+			p.origins[normalized] = struct{}{}
+		}
+	}
+	if p.anyOrigin && cfg.AllowCredentials {
+		errs = append(errs, errors.New(`the "*" origin cannot be combined with credentials; list the origins instead`))
+	}
+
+	methods := make([]string, 0, len(cfg.AllowedMethods))
+	for _, method := range cfg.AllowedMethods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "" {
+			continue
+		}
+		p.methods[method] = struct{}{}
+		methods = append(methods, method)
+	}
+	p.allowedMethods = strings.Join(methods, ", ")
+
+	headers := make([]string, 0, len(cfg.AllowedHeaders))
+	for _, header := range cfg.AllowedHeaders {
+		header = strings.TrimSpace(header)
+		switch header {
+		case "":
+			continue
+		case "*":
+			p.anyHeader = true
+			continue
+		}
+		p.headers[strings.ToLower(header)] = struct{}{}
+		headers = append(headers, http.CanonicalHeaderKey(header))
+	}
+	p.allowedHeaders = strings.Join(headers, ", ")
+
+	exposed := make([]string, 0, len(cfg.ExposedHeaders))
+	for _, header := range cfg.ExposedHeaders {
+		if header = strings.TrimSpace(header); header != "" {
+			exposed = append(exposed, http.CanonicalHeaderKey(header))
+		}
+	}
+	p.exposedHeaders = strings.Join(exposed, ", ")
+
+	if cfg.MaxAge < 0 {
+		errs = append(errs, errors.New("max age must not be negative"))
+	} else if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(cfg.MaxAge / time.Second))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("cors: %w", err)
+	}
+	return p, nil
+}
+
+// Handler applies the policy in front of next. Preflight requests are answered here:
+// 204 when allowed and 403 otherwise. Other requests always reach next; responses to
+// allowed origins carry the CORS headers, and those to other origins carry none, so the
+// browser withholds them from the page.
+func (p *Policy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		header := w.Header()
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Add("Vary", "Origin")
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			if !p.preflightAllowed(r) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			p.setOriginHeaders(header, origin)
+			header.Set("Access-Control-Allow-Methods", p.allowedMethods)
+			if requested := r.Header.Get("Access-Control-Request-Headers"); p.anyHeader && requested != "" {
+				// Echoing the request keeps the response valid with credentials, where a
+				// literal "*" is not a wildcard.
+				header.Set("Access-Control-Allow-Headers", requested)
+			} else if p.allowedHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", p.allowedHeaders)
+			}
+			if p.maxAge != "" {
+				header.Set("Access-Control-Max-Age", p.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if origin != "" {
+			header.Add("Vary", "Origin")
+			if p.AllowsOrigin(origin) {
+				p.setOriginHeaders(header, origin)
+				if p.exposedHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", p.exposedHeaders)
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AllowsOrigin reports whether origin may make cross-origin requests.
+func (p *Policy) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+	normalized, err := normalizeOrigin(origin)
+	if err != nil {
+		return false
+	}
+	if _, ok := p.origins[normalized]; ok {
+		return true
+	}
+
+	u, _ := url.Parse(normalized)
+	for _, pat := range p.patterns {
+		if u.Scheme == pat.scheme && u.Port() == pat.port && strings.HasSuffix(u.Hostname(), pat.suffix) && len(u.Hostname()) > len(pat.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) preflightAllowed(r *http.Request) bool {
+	if !p.AllowsOrigin(r.Header.Get("Origin")) {
+		return false
+	}
+	if _, ok := p.methods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))]; !ok {
+		return false
+	}
+	if p.anyHeader {
+		return true
+	}
+	for _, requested := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		requested = strings.ToLower(strings.TrimSpace(requested))
+		if requested == "" {
+			continue
+		}
+		if _, ok := p.headers[requested]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Policy) setOriginHeaders(header http.Header, origin string) {
+	if p.anyOrigin && !p.allowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// normalizeOrigin lowercases the scheme and host and drops a default port, so
+// configured and received origins compare equal.
+func normalizeOrigin(origin string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return "", errors.New("must be scheme://host[:port]")
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return scheme + "://" + host, nil
+}
+
+func parsePattern(origin string) (pattern, error) {
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(origin), "://")
+	if !ok || !strings.HasPrefix(rest, "*.") || strings.Count(rest, "*") != 1 {
+		return pattern{}, fmt.Errorf("origin pattern %q: the wildcard must be the whole first label, as in https://*.example.com", origin)
+	}
+	normalized, err := normalizeOrigin(scheme + "://wildcard" + rest[1:])
+	if err != nil {
+		return pattern{}, fmt.Errorf("origin pattern %q: %w", origin, err)
+	}
+	u, _ := url.Parse(normalized)
+	return pattern{
+		scheme: u.Scheme,
+		suffix: strings.TrimPrefix(u.Hostname(), "wildcard"),
+		port:   u.Port(),
+	}, nil
+}
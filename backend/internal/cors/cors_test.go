@@ -0,0 +1,167 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPolicy(t *testing.T, cfg Config) *Policy {
+	t.Helper()
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+var testConfig = Config{
+	AllowedOrigins:   []string{"https://resellution.app", "https://*.resellution.app", "http://localhost:5173"},
+	AllowedMethods:   []string{"GET", "POST", "PATCH", "DELETE"},
+	AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-ID", "Idempotency-Key"},
+	ExposedHeaders:   []string{"X-Request-ID", "Retry-After"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func TestPreflight(t *testing.T) {
+	reached := false
+	handler := newPolicy(t, testConfig).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+
+	tests := []struct {
+		name       string
+		origin     string
+		method     string
+		headers    string
+		wantStatus int
+	}{
+		{"exact origin", "https://resellution.app", "POST", "Content-Type, Authorization", http.StatusNoContent},
+		{"preview subdomain", "https://pr-123.resellution.app", "PATCH", "Idempotency-Key", http.StatusNoContent},
+		{"nested subdomain", "https://a.b.resellution.app", "GET", "", http.StatusNoContent},
+		{"default port", "https://resellution.app:443", "GET", "", http.StatusNoContent},
+		{"origin case", "HTTPS://PR-1.Resellution.App", "GET", "", http.StatusNoContent},
+		{"header case", "http://localhost:5173", "POST", "x-request-id,idempotency-key", http.StatusNoContent},
+		{"unknown origin", "https://evil.example", "GET", "", http.StatusForbidden},
+		{"suffix without dot", "https://evilresellution.app", "GET", "", http.StatusForbidden},
+		{"wrong scheme", "http://pr-123.resellution.app", "GET", "", http.StatusForbidden},
+		{"wrong port", "http://localhost:3000", "GET", "", http.StatusForbidden},
+		{"null origin", "null", "GET", "", http.StatusForbidden},
+		{"method not allowed", "https://resellution.app", "PUT", "", http.StatusForbidden},
+		{"header not allowed", "https://resellution.app", "POST", "Content-Type, X-Debug", http.StatusForbidden},
+		{"missing origin", "", "GET", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			req := httptest.NewRequest(http.MethodOptions, "/api/v1/auth/me", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if reached {
+				t.Error("preflight reached the next handler")
+			}
+			if vary := strings.Join(rec.Header().Values("Vary"), ", "); vary != "Origin, Access-Control-Request-Method, Access-Control-Request-Headers" {
+				t.Errorf("Vary = %q", vary)
+			}
+
+			want := map[string]string{
+				"Access-Control-Allow-Origin":      tt.origin,
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST, PATCH, DELETE",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type, X-Request-Id, Idempotency-Key",
+				"Access-Control-Max-Age":           "600",
+			}
+			for header, value := range want {
+				if tt.wantStatus != http.StatusNoContent {
+					value = ""
+				}
+				if got := rec.Header().Get(header); got != value {
+					t.Errorf("%s = %q, want %q", header, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestActualRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		method     string
+		origin     string
+		wantOrigin string
+		wantCreds  string
+	}{
+		{"allowed origin", testConfig, http.MethodGet, "https://pr-9.resellution.app", "https://pr-9.resellution.app", "true"},
+		{"disallowed origin", testConfig, http.MethodPost, "https://evil.example", "", ""},
+		{"same origin", testConfig, http.MethodGet, "", "", ""},
+		{"plain OPTIONS", testConfig, http.MethodOptions, "https://resellution.app", "https://resellution.app", "true"},
+		{"any origin", Config{AllowedOrigins: []string{"*"}}, http.MethodGet, "https://anywhere.example", "*", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := false
+			handler := newPolicy(t, tt.cfg).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+			}))
+			req := httptest.NewRequest(tt.method, "/api/v1/categories", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if !reached {
+				t.Fatal("request did not reach the next handler")
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCreds)
+			}
+			wantExposed := ""
+			if tt.wantOrigin != "" && len(tt.cfg.ExposedHeaders) > 0 {
+				wantExposed = "X-Request-Id, Retry-After"
+			}
+			if got := rec.Header().Get("Access-Control-Expose-Headers"); got != wantExposed {
+				t.Errorf("Access-Control-Expose-Headers = %q, want %q", got, wantExposed)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"wildcard with credentials", Config{AllowedOrigins: []string{"*"}, AllowCredentials: true}},
+		{"wildcard inside label", Config{AllowedOrigins: []string{"https://pr-*.resellution.app"}}},
+		{"wildcard without scheme", Config{AllowedOrigins: []string{"*.resellution.app"}}},
+		{"origin with path", Config{AllowedOrigins: []string{"https://resellution.app/app"}}},
+		{"origin without scheme", Config{AllowedOrigins: []string{"resellution.app"}}},
+		{"negative max age", Config{MaxAge: -time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Fatal("New succeeded, want an error")
+			}
+		})
+	}
+}
@@ -1,20 +1,80 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"strconv"
+	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
+// PoolConfig tunes the connection pool. Zero values keep the pgxpool defaults, except
+// StatementTimeout where zero means no timeout.
+type PoolConfig struct {
+	MaxConns         int32
+	MinConns         int32
+	MaxConnLifetime  time.Duration
+	MaxConnIdleTime  time.Duration
+	StatementTimeout time.Duration
+}
+
+// Connect opens a database with the default pool settings.
 func Connect(databaseURL string) (*sql.DB, error) {
-	db, err := sql.Open("pgx", databaseURL)
+	database, _, err := Open(context.Background(), databaseURL, PoolConfig{})
+	return database, err
+}
+
+// Open creates a pgxpool.Pool and returns a *sql.DB backed by it, so stores keep using
+// database/sql while connections are managed (and reported, see Pool.Stat) by pgxpool.
+// StatementTimeout is set as the statement_timeout of every connection, so the server
+// cancels any single query that runs longer. Closing the *sql.DB closes the pool.
+func Open(ctx context.Context, databaseURL string, cfg PoolConfig) (*sql.DB, *pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
 	}
 
-	if err = db.Ping(); err != nil {
-		return nil, err
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, nil, err
 	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+
+	database := sql.OpenDB(poolConnector{Connector: stdlib.GetPoolConnector(pool), pool: pool})
+	// Idle connections are kept by pgxpool; database/sql holding them too would starve
+	// the pool.
+	database.SetMaxIdleConns(0)
+	return database, pool, nil
+}
+
+// poolConnector closes the pool when the *sql.DB built on it is closed.
+type poolConnector struct {
+	driver.Connector
+	pool *pgxpool.Pool
+}
 
-	return db, nil
+func (c poolConnector) Close() error {
+	c.pool.Close()
+	return nil
 }
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Router sends read-only queries to replicas that are keeping up with the primary and
+// everything else to the primary. A nil *Router routes everything to the primary.
+type Router struct {
+	primary  *sql.DB
+	replicas []*Replica
+	maxLag   time.Duration
+	next     atomic.Uint64
+	onChange func(replica *Replica, healthy bool, lag time.Duration, err error)
+}
+
+// Replica is one read replica and its last observed health.
+type Replica struct {
+	Name    string
+	DB      *sql.DB
+	healthy atomic.Bool
+	lag     atomic.Int64
+}
+
+// Healthy reports whether the replica answered the last lag check within the limit.
+func (r *Replica) Healthy() bool { return r.healthy.Load() }
+
+// Lag is the replication lag seen by the last successful check.
+func (r *Replica) Lag() time.Duration { return time.Duration(r.lag.Load()) }
+
+// NewRouter returns a router over primary and replicas. Replicas start out of rotation
+// until the first CheckReplicas call finds them within maxLag. onChange, if not nil,
+// is called whenever a replica enters or leaves rotation.
+func NewRouter(primary *sql.DB, replicas []*Replica, maxLag time.Duration, onChange func(replica *Replica, healthy bool, lag time.Duration, err error)) *Router {
+	return &Router{primary: primary, replicas: replicas, maxLag: maxLag, onChange: onChange}
+}
+
+func (r *Router) Primary() *sql.DB { return r.primary }
+
+// Close closes the replica pools. The primary belongs to the caller and stays open.
+func (r *Router) Close() error {
+	if r == nil {
+		return nil
+	}
+	var errs []error
+	for _, replica := range r.replicas {
+		errs = append(errs, replica.DB.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Router) Replicas() []*Replica { return r.replicas }
+
+type primaryContextKey struct{}
+
+// WithPrimary marks ctx so reads go to the primary, for paths that must see their own
+// (or the user's recent) writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// ReadConn returns the Querier for a read-only query: the transaction carried by ctx if
+// any, the primary when ctx was marked WithPrimary or no replica is healthy, and
+// otherwise the next healthy replica in round-robin order.
+func ReadConn(ctx context.Context, primary *sql.DB, router *Router) Querier {
+	if tx, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	if router == nil || ctx.Value(primaryContextKey{}) != nil {
+		return primary
+	}
+	if replica := router.pick(); replica != nil {
+		return replica.DB
+	}
+	return primary
+}
+
+func (r *Router) pick() *Replica {
+	n := uint64(len(r.replicas))
+	if n == 0 {
+		return nil
+	}
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		replica := r.replicas[(start+i)%n]
+		if replica.Healthy() {
+			return replica
+		}
+	}
+	return nil
+}
+
+// lagQuery returns the replay lag in seconds. A replica that is streaming from the
+// primary and has replayed everything it received reports zero, so an idle primary does
+// not make replicas look stale. Without a streaming WAL receiver nothing new arrives, so
+// the lag is the age of the last replayed transaction, or infinite when there is none.
+// Seeing the receiver status needs pg_read_all_stats; without it replicas of an idle
+// primary are treated as lagging.
+const lagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() THEN 0
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn()
+			AND EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming') THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 'Infinity')
+	END::float8
+`
+
+// CheckReplicas measures every replica's lag and takes replicas out of rotation when
+// the check fails or the lag exceeds the limit.
+func (r *Router) CheckReplicas(ctx context.Context) {
+	for _, replica := range r.replicas {
+		var seconds float64
+		err := replica.DB.QueryRowContext(ctx, lagQuery).Scan(&seconds)
+		lag := time.Duration(math.MaxInt64)
+		if seconds < lag.Seconds() {
+			lag = time.Duration(seconds * float64(time.Second))
+		}
+		healthy := err == nil && lag <= r.maxLag
+		if err == nil {
+			replica.lag.Store(int64(lag))
+		}
+
+		if replica.healthy.Swap(healthy) != healthy && r.onChange != nil {
+			r.onChange(replica, healthy, lag, err)
+		}
+	}
+}
+
+// Run checks replicas every interval until ctx is done.
+func (r *Router) Run(ctx context.Context, interval time.Duration) {
+	r.CheckReplicas(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.CheckReplicas(ctx)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+)
+
+// openUnconnected returns a handle that never dials; ReadConn only compares handles.
+func openUnconnected(t *testing.T) *sql.DB {
+	t.Helper()
+	handle, err := sql.Open("pgx", "postgres://unused@127.0.0.1:1/unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { handle.Close() })
+	return handle
+}
+
+func TestReadConnRouting(t *testing.T) {
+	primary := openUnconnected(t)
+	first := &Replica{Name: "replica-1", DB: openUnconnected(t)}
+	second := &Replica{Name: "replica-2", DB: openUnconnected(t)}
+	router := NewRouter(primary, []*Replica{first, second}, time.Second, nil)
+	ctx := context.Background()
+
+	if got := ReadConn(ctx, primary, nil); got != primary {
+		t.Fatal("nil router should read from the primary")
+	}
+	if got := ReadConn(ctx, primary, router); got != primary {
+		t.Fatal("replicas start out of rotation; expected the primary")
+	}
+
+	first.healthy.Store(true)
+	second.healthy.Store(true)
+	seen := map[Querier]int{}
+	for i := 0; i < 4; i++ {
+		seen[ReadConn(ctx, primary, router)]++
+	}
+	if seen[first.DB] != 2 || seen[second.DB] != 2 {
+		t.Fatalf("expected round robin across replicas, got %v", seen)
+	}
+
+	if got := ReadConn(WithPrimary(ctx), primary, router); got != primary {
+		t.Fatal("WithPrimary should read from the primary")
+	}
+
+	second.healthy.Store(false)
+	for i := 0; i < 3; i++ {
+		if got := ReadConn(ctx, primary, router); got != first.DB {
+			t.Fatal("unhealthy replica should be skipped")
+		}
+	}
+
+	first.healthy.Store(false)
+	if got := ReadConn(ctx, primary, router); got != primary {
+		t.Fatal("expected the primary when no replica is healthy")
+	}
+}
+
+func TestCheckReplicasRemovesUnreachableReplica(t *testing.T) {
+	replica := &Replica{Name: "replica-1", DB: openUnconnected(t)}
+	replica.healthy.Store(true)
+
+	var changes []bool
+	router := NewRouter(openUnconnected(t), []*Replica{replica}, time.Second, func(_ *Replica, healthy bool, _ time.Duration, err error) {
+		if !healthy && err == nil {
+			t.Error("expected the connection error to be reported")
+		}
+		changes = append(changes, healthy)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	router.CheckReplicas(ctx)
+
+	if replica.Healthy() {
+		t.Fatal("unreachable replica should leave rotation")
+	}
+	if len(changes) != 1 || changes[0] {
+		t.Fatalf("expected one change to unhealthy, got %v", changes)
+	}
+}
+
+// TestCheckReplicasLag needs a primary and a streaming replica of it, e.g. two local
+// Postgres instances as described in the README.
+func TestCheckReplicasLag(t *testing.T) {
+	primaryURL := os.Getenv("TEST_DATABASE_URL")
+	replicaURL := os.Getenv("TEST_REPLICA_DATABASE_URL")
+	if primaryURL == "" || replicaURL == "" {
+		t.Skip("TEST_DATABASE_URL and TEST_REPLICA_DATABASE_URL are not set")
+	}
+
+	ctx := context.Background()
+	primary, err := Connect(primaryURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+	replicaDB, _, err := Open(ctx, replicaURL, PoolConfig{MaxConns: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicaDB.Close()
+
+	replica := &Replica{Name: "replica-1", DB: replicaDB}
+	router := NewRouter(primary, []*Replica{replica}, 10*time.Second, nil)
+	router.CheckReplicas(ctx)
+
+	if !replica.Healthy() {
+		t.Fatalf("replica lag %s should be within 10s", replica.Lag())
+	}
+	if got := ReadConn(ctx, primary, router); got != replicaDB {
+		t.Fatal("expected reads to go to the healthy replica")
+	}
+}
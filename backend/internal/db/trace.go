@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "resellution/backend/db"
+
+// Traced wraps q so each statement runs in a client span named after the operation and
+// collection, e.g. "SELECT users". Query arguments are not recorded because they hold
+// emails and password hashes. Spans end when the statement returns, so time spent
+// iterating rows or scanning a *sql.Row is not included.
+func Traced(q Querier, collection string) Querier {
+	return tracedQuerier{q: q, collection: collection}
+}
+
+type tracedQuerier struct {
+	q          Querier
+	collection string
+}
+
+func (t tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	result, err := t.q.ExecContext(ctx, query, args...)
+	recordError(span, err)
+	return result, err
+}
+
+func (t tracedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	recordError(span, err)
+	return rows, err
+}
+
+func (t tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	row := t.q.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); !errors.Is(err, sql.ErrNoRows) {
+		recordError(span, err)
+	}
+	return row
+}
+
+func (t tracedQuerier) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	statement := strings.Join(strings.Fields(query), " ")
+	operation, _, _ := strings.Cut(statement, " ")
+	operation = strings.ToUpper(operation)
+
+	return otel.Tracer(tracerName).Start(ctx, operation+" "+t.collection,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(t.collection),
+			semconv.DBQueryText(statement),
+		),
+	)
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type execQuerier struct {
+	Querier
+	err error
+}
+
+func (q execQuerier) ExecContext(context.Context, string, ...any) (sql.Result, error) {
+	return nil, q.err
+}
+
+func TestTracedRecordsStatementWithoutArguments(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	q := Traced(execQuerier{err: errors.New("boom")}, "users")
+	_, _ = q.ExecContext(context.Background(), `
+		UPDATE users
+		SET password_hash = $2
+		WHERE id = $1
+	`, "user-1", "secret-hash")
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "UPDATE users" {
+		t.Errorf("span name = %q", span.Name())
+	}
+	for _, attr := range span.Attributes() {
+		if attr.Key == "db.query.text" && attr.Value.AsString() != "UPDATE users SET password_hash = $2 WHERE id = $1" {
+			t.Errorf("db.query.text = %q", attr.Value.AsString())
+		}
+		if attr.Value.AsString() == "secret-hash" {
+			t.Errorf("argument leaked into %s", attr.Key)
+		}
+	}
+	if span.Status().Description != "boom" {
+		t.Errorf("status = %+v, want the error", span.Status())
+	}
+}
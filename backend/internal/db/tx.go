@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier is the subset of *sql.DB and *sql.Tx used by the stores.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txContextKey struct{}
+
+// Conn returns the transaction carried by ctx, or database when there is none, so store
+// methods join a transaction started by TxManager.WithTx without changing signatures.
+func Conn(ctx context.Context, database *sql.DB) Querier {
+	if tx, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return database
+}
+
+// TxManager runs functions inside a database transaction.
+type TxManager struct {
+	DB *sql.DB
+	// Isolation is the isolation level of new transactions. The zero value uses the
+	// server default (READ COMMITTED).
+	Isolation sql.IsolationLevel
+	// MaxRetries is how many more times a transaction is run after it fails with a
+	// serialization failure or deadlock.
+	MaxRetries int
+}
+
+// WithTx runs fn in a transaction stored in the context passed to fn. The transaction
+// is committed when fn returns nil and rolled back otherwise. Nested calls join the
+// outer transaction. Transactions that fail with a serialization failure or deadlock
+// are retried up to MaxRetries times with backoff, so fn must be safe to run again:
+// it should only change state through the transaction.
+func (m TxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := m.run(ctx, fn)
+		if err == nil || attempt >= m.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		backoff := time.Duration(10<<attempt)*time.Millisecond + rand.N(10*time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (m TxManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: m.Isolation})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock, after which
+// the whole transaction can be run again.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// Transactor is implemented by TxManager and by in-memory stores used in tests.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var _ Transactor = TxManager{}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"serialization failure": {&pgconn.PgError{Code: "40001"}, true},
+		"deadlock":              {&pgconn.PgError{Code: "40P01"}, true},
+		"wrapped":               {fmt.Errorf("update user: %w", &pgconn.PgError{Code: "40001"}), true},
+		"unique violation":      {&pgconn.PgError{Code: "23505"}, false},
+		"other error":           {errors.New("connection reset"), false},
+		"nil":                   {nil, false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); got != tc.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
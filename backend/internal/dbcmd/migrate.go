@@ -0,0 +1,84 @@
+// Package dbcmd implements the migrate and seed subcommands shared by the server and
+// resellctl binaries.
+package dbcmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"resellution/backend/internal/migrate"
+	"resellution/backend/migrations"
+)
+
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    roll back the last n migrations (default 1)
+  status      list migrations and whether they are applied
+  check       exit non-zero unless every migration is applied and unmodified`
+
+// Migrate runs the migrate subcommand named by args[0] and reports progress to out.
+func Migrate(ctx context.Context, database *sql.DB, out io.Writer, args []string) error {
+	migrator := migrate.Migrator{DB: database, FS: migrations.FS}
+
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("down expects a positive number of steps, got %q", args[1])
+			}
+			steps = parsed
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, migration := range rolledBack {
+			fmt.Fprintf(out, "rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "-"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05Z07:00")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, status.State, appliedAt)
+		}
+		return w.Flush()
+	case "check":
+		if err := migrator.Check(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "schema is up to date")
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+}
@@ -0,0 +1,48 @@
+package dbcmd
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+
+	"resellution/backend/internal/migrate"
+	"resellution/backend/internal/seed"
+	"resellution/backend/migrations"
+	"resellution/backend/seeds"
+)
+
+// Seed runs the seed subcommand with the flags in args and reports what it inserted
+// to out.
+func Seed(ctx context.Context, database *sql.DB, out io.Writer, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	profile := flags.String("profile", seed.ProfileProd, "seed profile: prod (reference data only), staging or dev")
+	randSeed := flags.Int64("seed", 1, "random seed for generated demo data")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	migrator := migrate.Migrator{DB: database, FS: migrations.FS}
+	if err := migrator.Check(ctx); err != nil {
+		return fmt.Errorf("%w (run `migrate up` first)", err)
+	}
+
+	seeder := seed.Seeder{DB: database, FS: seeds.FS, Profile: *profile, RandSeed: *randSeed}
+	summary, err := seeder.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "applied %d reference seed file(s)\n", summary.ReferenceFiles)
+	if *profile != seed.ProfileProd {
+		fmt.Fprintf(out, "inserted %d users, %d listings, %d images, %d conversations, %d messages, %d favorites\n",
+			summary.Users, summary.Listings, summary.Images, summary.Conversations, summary.Messages, summary.Favorites)
+		if *profile == seed.ProfileDev {
+			fmt.Fprintf(out, "demo accounts use the password %q (admin: demo.admin@resellution.dev)\n", seed.DemoPassword)
+		} else {
+			fmt.Fprintf(out, "demo accounts use the password %q (no admin or moderator accounts)\n", seed.DemoPassword)
+		}
+	}
+	return nil
+}
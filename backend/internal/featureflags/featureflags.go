@@ -0,0 +1,179 @@
+// Package featureflags turns features on at runtime, for everyone, for listed users,
+// for a percentage of users or for users in given cities. Flags live in Postgres and
+// are cached in memory by a Service, which refreshes them periodically so a change made
+// on one instance reaches the others within the refresh interval.
+package featureflags
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/problem"
+)
+
+// Flags checked by the code. Each is created by a migration.
+const (
+	Chat          = "chat"
+	TwoFactorAuth = "two_factor_auth"
+	NewSearch     = "new_search"
+)
+
+// User is what flags are evaluated against. The zero User is an anonymous visitor.
+type User struct {
+	ID   string
+	City string
+}
+
+// Evaluate reports whether the flag is on for user:
+//
+//   - a disabled flag is off for everyone;
+//   - users in AllowedUserIDs always get it;
+//   - when Cities is set, users in other cities do not;
+//   - of the remaining users, RolloutPercentage percent get it, picked by a hash of the
+//     flag key and user ID, so a user keeps the feature as the percentage grows.
+//
+// Anonymous visitors get only flags rolled out to 100% of users in every city.
+func (f Flag) Evaluate(user User) bool {
+	if !f.Enabled {
+		return false
+	}
+	if user.ID != "" && slices.Contains(f.AllowedUserIDs, user.ID) {
+		return true
+	}
+	if len(f.Cities) > 0 && !slices.ContainsFunc(f.Cities, func(city string) bool {
+		return strings.EqualFold(city, strings.TrimSpace(user.City))
+	}) {
+		return false
+	}
+	if f.RolloutPercentage >= 100 {
+		return true
+	}
+	if user.ID == "" {
+		return false
+	}
+	return bucket(f.Key, user.ID) < f.RolloutPercentage
+}
+
+// bucket places userID in one of 100 buckets. The flag key is part of the hash so each
+// flag rolls out to a different set of users.
+func bucket(key, userID string) int {
+	sum := sha256.Sum256([]byte(key + "/" + userID))
+	return int(binary.BigEndian.Uint32(sum[:4]) % 100)
+}
+
+// UserLookup loads the user a request is made by, for flags that target cities.
+type UserLookup func(ctx context.Context, userID string) (User, error)
+
+// Service answers flag checks from an in-memory copy of the store. Until the first
+// Refresh every flag is off.
+type Service struct {
+	store Store
+	users UserLookup
+
+	mu    sync.RWMutex
+	flags map[string]Flag
+}
+
+func NewService(store Store, users UserLookup) *Service {
+	return &Service{store: store, users: users, flags: make(map[string]Flag)}
+}
+
+// Refresh reloads every flag from the store. On error the previous flags stay in use.
+func (s *Service) Refresh(ctx context.Context) error {
+	flags, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]Flag, len(flags))
+	for _, flag := range flags {
+		byKey[flag.Key] = flag
+	}
+
+	s.mu.Lock()
+	s.flags = byKey
+	s.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the flags every interval until ctx is done.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "feature flag refresh failed", "error", err)
+		}
+	}
+}
+
+// Enabled reports whether the flag with key is on for user. Unknown flags are off.
+func (s *Service) Enabled(key string, user User) bool {
+	s.mu.RLock()
+	flag, ok := s.flags[key]
+	s.mu.RUnlock()
+	return ok && flag.Evaluate(user)
+}
+
+// ForUser evaluates every flag for user.
+func (s *Service) ForUser(user User) map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]bool, len(s.flags))
+	for key, flag := range s.flags {
+		result[key] = flag.Evaluate(user)
+	}
+	return result
+}
+
+// EnabledForRequest reports whether the flag with key is on for the signed-in user of
+// r, or for an anonymous visitor when there is none. The user is only loaded when the
+// flag targets cities.
+func (s *Service) EnabledForRequest(r *http.Request, key string) bool {
+	s.mu.RLock()
+	flag, ok := s.flags[key]
+	s.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	user := User{}
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+		user.ID = userID
+		if len(flag.Cities) > 0 && s.users != nil {
+			loaded, err := s.users(r.Context(), userID)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "feature flag user lookup failed", "flag", key, "error", err)
+			} else {
+				user = loaded
+			}
+		}
+	}
+	return flag.Evaluate(user)
+}
+
+// Require answers 404 while the flag with key is off for the caller, so a feature
+// behind a flag looks absent until it reaches them. It goes inside middleware.Auth for
+// per-user flags.
+func (s *Service) Require(key string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.EnabledForRequest(r, key) {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeFeatureDisabled, "this feature is not available")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,208 @@
+package featureflags
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/utils"
+)
+
+const (
+	alice = "8d9a3f4e-1b2c-4d5e-9f60-7a8b9c0d1e2f"
+	bob   = "1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name string
+		flag Flag
+		user User
+		want bool
+	}{
+		{"disabled", Flag{RolloutPercentage: 100}, User{ID: alice}, false},
+		{"disabled ignores allow-list", Flag{AllowedUserIDs: []string{alice}}, User{ID: alice}, false},
+		{"on for everyone", Flag{Enabled: true, RolloutPercentage: 100}, User{ID: alice}, true},
+		{"on for anonymous", Flag{Enabled: true, RolloutPercentage: 100}, User{}, true},
+		{"enabled at zero percent", Flag{Enabled: true}, User{ID: alice}, false},
+		{"allow-listed", Flag{Enabled: true, AllowedUserIDs: []string{alice}}, User{ID: alice}, true},
+		{"not allow-listed", Flag{Enabled: true, AllowedUserIDs: []string{alice}}, User{ID: bob}, false},
+		{"allow-list beats cities", Flag{Enabled: true, AllowedUserIDs: []string{alice}, Cities: []string{"Pune"}}, User{ID: alice, City: "Goa"}, true},
+		{"city matches", Flag{Enabled: true, RolloutPercentage: 100, Cities: []string{"Pune"}}, User{ID: alice, City: " pune "}, true},
+		{"other city", Flag{Enabled: true, RolloutPercentage: 100, Cities: []string{"Pune"}}, User{ID: alice, City: "Goa"}, false},
+		{"anonymous with cities", Flag{Enabled: true, RolloutPercentage: 100, Cities: []string{"Pune"}}, User{}, false},
+		{"anonymous in partial rollout", Flag{Enabled: true, RolloutPercentage: 99}, User{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.flag.Key = "chat"
+			if got := tt.flag.Evaluate(tt.user); got != tt.want {
+				t.Fatalf("Evaluate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollout(t *testing.T) {
+	const users = 10000
+	enabledAt := func(key string, percentage int) map[string]bool {
+		flag := Flag{Key: key, Enabled: true, RolloutPercentage: percentage}
+		enabled := make(map[string]bool)
+		for i := range users {
+			id := fmt.Sprintf("user-%d", i)
+			if flag.Evaluate(User{ID: id}) {
+				enabled[id] = true
+			}
+		}
+		return enabled
+	}
+
+	ten, fifty := enabledAt("chat", 10), enabledAt("chat", 50)
+	if len(ten) < users*8/100 || len(ten) > users*12/100 {
+		t.Errorf("10%% rollout enabled %d of %d users", len(ten), users)
+	}
+	// Growing the rollout keeps everyone who already had the feature.
+	for id := range ten {
+		if !fifty[id] {
+			t.Fatalf("%s lost the flag when the rollout grew", id)
+		}
+	}
+	// Each flag picks its own users.
+	other := enabledAt("new_search", 10)
+	shared := 0
+	for id := range ten {
+		if other[id] {
+			shared++
+		}
+	}
+	if shared > len(ten)/2 {
+		t.Errorf("%d of %d users share both 10%% rollouts", shared, len(ten))
+	}
+}
+
+func TestServiceRefresh(t *testing.T) {
+	store := NewMemoryStore(Flag{Key: Chat})
+	service := NewService(store, nil)
+	if service.Enabled(Chat, User{ID: alice}) {
+		t.Fatal("flag on before the first refresh")
+	}
+
+	if _, err := store.Update(context.Background(), Flag{Key: Chat, Enabled: true, AllowedUserIDs: []string{alice}}); err != nil {
+		t.Fatal(err)
+	}
+	if service.Enabled(Chat, User{ID: alice}) {
+		t.Fatal("service saw the change before refreshing")
+	}
+	if err := service.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !service.Enabled(Chat, User{ID: alice}) || service.Enabled(Chat, User{ID: bob}) {
+		t.Fatal("refresh did not apply the allow-list")
+	}
+	if got := service.ForUser(User{ID: bob}); len(got) != 1 || got[Chat] {
+		t.Fatalf("ForUser = %v", got)
+	}
+	if service.Enabled("unknown", User{ID: alice}) {
+		t.Fatal("unknown flag is on")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	store := NewMemoryStore(Flag{Key: Chat, Enabled: true, RolloutPercentage: 100, Cities: []string{"Pune"}})
+	cities := map[string]string{alice: "Pune", bob: "Goa"}
+	service := NewService(store, func(_ context.Context, userID string) (User, error) {
+		return User{ID: userID, City: cities[userID]}, nil
+	})
+	if err := service.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := utils.NewTokenManager("test-secret")
+	handler := middleware.Auth(tokens, nil, service.Require(Chat, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	for userID, want := range map[string]int{alice: http.StatusNoContent, bob: http.StatusNotFound} {
+		token, err := tokens.Create(userID, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/conversations", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != want {
+			t.Errorf("user in %s: status %d, want %d", cities[userID], rec.Code, want)
+		}
+	}
+}
+
+func testStores(t *testing.T) map[string]Store {
+	stores := map[string]Store{"memory": NewMemoryStore(Flag{Key: Chat, Description: "Chat"})}
+
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		return stores
+	}
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	stores["postgres"] = PostgresStore{DB: db}
+	return stores
+}
+
+func TestStores(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			original, err := store.Get(ctx, Chat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _, _ = store.Update(ctx, original) })
+
+			updated, err := store.Update(ctx, Flag{
+				Key: Chat, Description: "ignored", Enabled: true, RolloutPercentage: 25,
+				AllowedUserIDs: []string{alice}, Cities: []string{"Pune", "Goa"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Description != original.Description || !updated.Enabled || updated.RolloutPercentage != 25 ||
+				len(updated.AllowedUserIDs) != 1 || len(updated.Cities) != 2 || updated.UpdatedAt.IsZero() {
+				t.Fatalf("updated = %+v", updated)
+			}
+
+			flags, err := store.List(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, flag := range flags {
+				if flag.Key == Chat {
+					found = flag.RolloutPercentage == 25 && flag.Cities[1] == "Goa"
+				}
+			}
+			if !found {
+				t.Fatalf("List = %+v", flags)
+			}
+
+			if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get(missing) = %v, want ErrNotFound", err)
+			}
+			if _, err := store.Update(ctx, Flag{Key: "missing"}); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Update(missing) = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package featureflags
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"resellution/backend/internal/db"
+)
+
+// ErrNotFound means no flag has the key.
+var ErrNotFound = errors.New("feature flag not found")
+
+// Flag is a feature flag and its targeting rules; see Flag.Evaluate.
+type Flag struct {
+	Key               string    `json:"key"`
+	Description       string    `json:"description"`
+	Enabled           bool      `json:"enabled"`
+	RolloutPercentage int       `json:"rollout_percentage"`
+	AllowedUserIDs    []string  `json:"allowed_user_ids"`
+	Cities            []string  `json:"cities"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	UpdatedBy         string    `json:"updated_by,omitempty"`
+}
+
+// Store persists flags. Flags are created by migrations, so stores only change the
+// targeting of existing ones.
+type Store interface {
+	// List returns every flag ordered by key.
+	List(ctx context.Context) ([]Flag, error)
+	// Get returns the flag with key, locked for update when ctx carries a transaction.
+	Get(ctx context.Context, key string) (Flag, error)
+	// Update replaces the targeting of the flag with flag.Key and returns the stored
+	// flag.
+	Update(ctx context.Context, flag Flag) (Flag, error)
+}
+
+// MemoryStore keeps flags in process memory, for tests and local runs without a
+// database.
+type MemoryStore struct {
+	mu    sync.Mutex
+	flags map[string]Flag
+}
+
+func NewMemoryStore(flags ...Flag) *MemoryStore {
+	s := &MemoryStore{flags: make(map[string]Flag)}
+	for _, flag := range flags {
+		s.flags[flag.Key] = clone(flag)
+	}
+	return s
+}
+
+func (s *MemoryStore) List(_ context.Context) ([]Flag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flags := make([]Flag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		flags = append(flags, clone(flag))
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags, nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (Flag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flag, ok := s.flags[key]
+	if !ok {
+		return Flag{}, ErrNotFound
+	}
+	return clone(flag), nil
+}
+
+func (s *MemoryStore) Update(_ context.Context, flag Flag) (Flag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.flags[flag.Key]
+	if !ok {
+		return Flag{}, ErrNotFound
+	}
+	flag.Description = stored.Description
+	flag.UpdatedAt = time.Now().UTC()
+	s.flags[flag.Key] = clone(flag)
+	return clone(flag), nil
+}
+
+func clone(flag Flag) Flag {
+	flag.AllowedUserIDs = slices.Clone(flag.AllowedUserIDs)
+	flag.Cities = slices.Clone(flag.Cities)
+	return flag
+}
+
+// PostgresStore keeps flags in the feature_flags table. It joins the transaction
+// carried by ctx, so a change commits together with its audit event.
+type PostgresStore struct {
+	DB *sql.DB
+}
+
+const flagColumns = `key, description, enabled, rollout_percentage, allowed_user_ids, cities, updated_at, COALESCE(updated_by::text, '')`
+
+func (s PostgresStore) List(ctx context.Context) ([]Flag, error) {
+	query := `SELECT ` + flagColumns + ` FROM feature_flags ORDER BY key`
+
+	rows, err := db.Conn(ctx, s.DB).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []Flag
+	for rows.Next() {
+		flag, err := scanFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+	}
+	return flags, rows.Err()
+}
+
+func (s PostgresStore) Get(ctx context.Context, key string) (Flag, error) {
+	query := `SELECT ` + flagColumns + ` FROM feature_flags WHERE key = $1`
+	if _, inTx := db.Conn(ctx, s.DB).(*sql.Tx); inTx {
+		query += ` FOR UPDATE`
+	}
+
+	flag, err := scanFlag(db.Conn(ctx, s.DB).QueryRowContext(ctx, query, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Flag{}, ErrNotFound
+	}
+	return flag, err
+}
+
+func (s PostgresStore) Update(ctx context.Context, flag Flag) (Flag, error) {
+	allowed, err := json.Marshal(nonNil(flag.AllowedUserIDs))
+	if err != nil {
+		return Flag{}, err
+	}
+	cities, err := json.Marshal(nonNil(flag.Cities))
+	if err != nil {
+		return Flag{}, err
+	}
+
+	query := `
+		UPDATE feature_flags
+		SET enabled = $2,
+			rollout_percentage = $3,
+			allowed_user_ids = $4,
+			cities = $5,
+			updated_at = NOW(),
+			updated_by = NULLIF($6, '')::uuid
+		WHERE key = $1
+		RETURNING ` + flagColumns
+
+	updated, err := scanFlag(db.Conn(ctx, s.DB).QueryRowContext(ctx, query,
+		flag.Key, flag.Enabled, flag.RolloutPercentage, allowed, cities, flag.UpdatedBy))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Flag{}, ErrNotFound
+	}
+	return updated, err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFlag(row rowScanner) (Flag, error) {
+	var flag Flag
+	var allowed, cities []byte
+	if err := row.Scan(&flag.Key, &flag.Description, &flag.Enabled, &flag.RolloutPercentage,
+		&allowed, &cities, &flag.UpdatedAt, &flag.UpdatedBy); err != nil {
+		return Flag{}, err
+	}
+	if err := json.Unmarshal(allowed, &flag.AllowedUserIDs); err != nil {
+		return Flag{}, err
+	}
+	if err := json.Unmarshal(cities, &flag.Cities); err != nil {
+		return Flag{}, err
+	}
+	return flag, nil
+}
+
+// nonNil keeps empty lists as [] rather than null in the JSONB columns.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
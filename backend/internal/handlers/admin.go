@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"resellution/backend/api"
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/problem"
+)
+
+// AuditLog records and queries audit events.
+type AuditLog interface {
+	AuditRecorder
+	Query(ctx context.Context, filter audit.Filter) ([]audit.StoredEvent, int, error)
+}
+
+type AdminHandler struct {
+	Users    models.UserRepository
+	Sessions models.SessionRepository
+	Tx       db.Transactor
+	Audit    AuditLog
+}
+
+const (
+	defaultAdminSearchLimit = 50
+	maxAdminSearchLimit     = 200
+	maxSuspendReasonLength  = 500
+)
+
+// SearchUsers lists users filtered by the email, phone, city, created_after,
+// created_before and include_deleted query parameters.
+func (h AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseUserFilter(r)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	users, total, err := h.Users.SearchUsers(r.Context(), filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "admin.search_users failed", "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to search users")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.UserPage{Users: users, Total: total, Limit: filter.Limit, Offset: filter.Offset})
+}
+
+// GetUser returns a user including deactivated accounts.
+func (h AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.Users.FindByIDIncludingDeleted(r.Context(), userID)
+	if err != nil {
+		writeAdminUserError(w, r, err, "failed to fetch user")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.AdminUserResponse{User: user})
+}
+
+func (h AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "unauthorized")
+		return
+	}
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req api.SetRoleRequest
+	if err := decodeJSON(r, &req); err != nil {
+		problem.BodyError(w, r, err)
+		return
+	}
+	req.Role = strings.TrimSpace(strings.ToLower(req.Role))
+	if req.Role == "" {
+		problem.Write(w, r, problem.Validation(problem.Field("role", problem.FieldRequired, "role is required")))
+		return
+	}
+	if userID == adminID && req.Role != models.RoleAdmin {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeSelfAction, "admins cannot remove their own admin role")
+		return
+	}
+
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		before, err := h.Users.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := h.Users.SetRole(ctx, userID, req.Role, adminID); err != nil {
+			return err
+		}
+		// Tokens carry the role and its permissions, so the user signs in again to get
+		// the new ones rather than keeping the old ones until their tokens expire.
+		if before.Role != req.Role {
+			if _, err := h.Sessions.RevokeAllByUserID(ctx, userID); err != nil {
+				return err
+			}
+		}
+		after, err := h.Users.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			ActorID:    adminID,
+			Action:     audit.ActionAdminUserRoleChanged,
+			TargetType: audit.TargetUser,
+			TargetID:   userID,
+			Before:     before,
+			After:      after,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrRoleNotFound) {
+			problem.Write(w, r, problem.Validation(problem.Field("role", problem.FieldNotFound, "role does not exist")))
+			return
+		}
+		if !errors.Is(err, models.ErrUserNotFound) {
+			slog.ErrorContext(r.Context(), audit.ActionAdminUserRoleChanged+" failed", "admin_id", adminID, "target_user_id", userID, "error", err)
+		}
+		writeAdminUserError(w, r, err, "failed to update role")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.MessageResponse{Message: "role updated"})
+	slog.InfoContext(r.Context(), audit.ActionAdminUserRoleChanged+" success", "admin_id", adminID, "target_user_id", userID, "role", req.Role)
+}
+
+func (h AdminHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, audit.ActionAdminUserDeactivated, "user deactivated", func(ctx context.Context, adminID, userID string) error {
+		if err := h.Users.DeactivateByID(ctx, userID, adminID); err != nil {
+			return err
+		}
+		_, err := h.Sessions.RevokeAllByUserID(ctx, userID)
+		return err
+	})
+}
+
+// RestoreUser reactivates an account deactivated by its owner or an admin.
+func (h AdminHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, audit.ActionAdminUserRestored, "user restored", func(ctx context.Context, adminID, userID string) error {
+		return h.Users.RestoreByID(ctx, userID, adminID)
+	})
+}
+
+// SuspendUser blocks login and revokes every session, keeping the account visible.
+func (h AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	// The body is optional; an empty body suspends without a reason.
+	var req api.SuspendUserRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		problem.BodyError(w, r, err)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) > maxSuspendReasonLength {
+		problem.Write(w, r, problem.Validation(problem.Field("reason", problem.FieldTooLong, "reason must not exceed %d characters", maxSuspendReasonLength)))
+		return
+	}
+
+	h.userAction(w, r, audit.ActionAdminUserSuspended, "user suspended", func(ctx context.Context, adminID, userID string) error {
+		if err := h.Users.Suspend(ctx, userID, req.Reason, adminID); err != nil {
+			return err
+		}
+		_, err := h.Sessions.RevokeAllByUserID(ctx, userID)
+		return err
+	})
+}
+
+func (h AdminHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, audit.ActionAdminUserUnsuspended, "user unsuspended", func(ctx context.Context, adminID, userID string) error {
+		return h.Users.Unsuspend(ctx, userID, adminID)
+	})
+}
+
+// ForcePasswordReset blocks login until the user resets their password through the
+// OTP flow, and signs them out everywhere.
+func (h AdminHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, audit.ActionAdminUserPasswordReset, "password reset required", func(ctx context.Context, adminID, userID string) error {
+		if err := h.Users.RequirePasswordReset(ctx, userID, adminID); err != nil {
+			return err
+		}
+		_, err := h.Sessions.RevokeAllByUserID(ctx, userID)
+		return err
+	})
+}
+
+func (h AdminHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, audit.ActionAdminUserSessionRevoked, "sessions revoked", func(ctx context.Context, adminID, userID string) error {
+		_, err := h.Sessions.RevokeAllByUserID(ctx, userID)
+		return err
+	})
+}
+
+func (h AdminHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, audit.ActionAdminUserEmailVerified, "email marked as verified", func(ctx context.Context, adminID, userID string) error {
+		return h.Users.MarkEmailVerified(ctx, userID, adminID)
+	})
+}
+
+// ListAuditEvents queries the audit log by the actor_id, action, target_type, target_id,
+// from and to query parameters.
+func (h AdminHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	events, total, err := h.Audit.Query(r.Context(), filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "admin.audit_events failed", "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to fetch audit events")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.AuditEventPage{Events: events, Total: total, Limit: filter.Limit, Offset: filter.Offset})
+}
+
+var (
+	errSelfAction = errors.New("admins cannot perform this action on their own account")
+	errOutranked  = errors.New("you cannot act on a user whose role outranks yours")
+)
+
+// lockoutActions would lock the acting admin out of their own account, so they may only
+// be applied to other users.
+var lockoutActions = map[string]bool{
+	audit.ActionAdminUserDeactivated:   true,
+	audit.ActionAdminUserSuspended:     true,
+	audit.ActionAdminUserPasswordReset: true,
+}
+
+// userAction runs an admin action against the user in the {id} path parameter inside a
+// transaction, records it in the audit log with before/after snapshots of the user and
+// writes the shared success and error responses. The acting user's current role, not
+// the one in their token, must be at least the target's.
+func (h AdminHandler) userAction(w http.ResponseWriter, r *http.Request, action, message string, fn func(ctx context.Context, adminID, userID string) error) {
+	adminID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "unauthorized")
+		return
+	}
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		if userID == adminID && lockoutActions[action] {
+			return errSelfAction
+		}
+		actor, err := h.Users.FindByID(ctx, adminID)
+		if err != nil {
+			return err
+		}
+		before, err := h.Users.FindByIDIncludingDeleted(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if models.RoleRank(before.Role) > models.RoleRank(actor.Role) {
+			return errOutranked
+		}
+		if err := fn(ctx, adminID, userID); err != nil {
+			return err
+		}
+		after, err := h.Users.FindByIDIncludingDeleted(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			ActorID:    adminID,
+			Action:     action,
+			TargetType: audit.TargetUser,
+			TargetID:   userID,
+			Before:     before,
+			After:      after,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errSelfAction) {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeSelfAction, err.Error())
+			return
+		}
+		if errors.Is(err, errOutranked) {
+			problem.Error(w, r, http.StatusForbidden, problem.CodeForbidden, err.Error())
+			return
+		}
+		if !errors.Is(err, models.ErrUserNotFound) {
+			slog.ErrorContext(r.Context(), action+" failed", "admin_id", adminID, "target_user_id", userID, "error", err)
+		}
+		writeAdminUserError(w, r, err, "failed to update user")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.MessageResponse{Message: message})
+	slog.InfoContext(r.Context(), action+" success", "admin_id", adminID, "target_user_id", userID)
+}
+
+func userIDFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID := r.PathValue("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeUserNotFound, "user not found")
+		return "", false
+	}
+	return userID, true
+}
+
+func writeAdminUserError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	if errors.Is(err, models.ErrUserNotFound) {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeUserNotFound, "user not found")
+		return
+	}
+	problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, fallback)
+}
+
+func parseUserFilter(r *http.Request) (models.UserFilter, error) {
+	query := r.URL.Query()
+	filter := models.UserFilter{
+		Email: strings.TrimSpace(query.Get("email")),
+		Phone: strings.TrimSpace(query.Get("phone")),
+		City:  strings.TrimSpace(query.Get("city")),
+		Limit: defaultAdminSearchLimit,
+	}
+
+	if raw := query.Get("created_after"); raw != "" {
+		parsed, err := parseFilterTime(raw)
+		if err != nil {
+			return models.UserFilter{}, problem.Field("created_after", problem.FieldInvalid, "created_after must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+		}
+		filter.CreatedAfter = parsed
+	}
+	if raw := query.Get("created_before"); raw != "" {
+		parsed, err := parseFilterTime(raw)
+		if err != nil {
+			return models.UserFilter{}, problem.Field("created_before", problem.FieldInvalid, "created_before must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+		}
+		filter.CreatedBefore = parsed
+	}
+	if raw := query.Get("include_deleted"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return models.UserFilter{}, problem.Field("include_deleted", problem.FieldInvalid, "include_deleted must be true or false")
+		}
+		filter.IncludeDeleted = parsed
+	}
+
+	limit, offset, err := parsePagination(query)
+	if err != nil {
+		return models.UserFilter{}, err
+	}
+	if limit > 0 {
+		filter.Limit = limit
+	}
+	filter.Offset = offset
+	return filter, nil
+}
+
+func parseFilterTime(raw string) (time.Time, error) {
+	if parsed, err := time.Parse(time.DateOnly, raw); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		ActorID:    strings.TrimSpace(query.Get("actor_id")),
+		Action:     strings.TrimSpace(query.Get("action")),
+		TargetType: strings.TrimSpace(query.Get("target_type")),
+		TargetID:   strings.TrimSpace(query.Get("target_id")),
+		Limit:      defaultAdminSearchLimit,
+	}
+	if filter.ActorID != "" {
+		if _, err := uuid.Parse(filter.ActorID); err != nil {
+			return audit.Filter{}, problem.Field("actor_id", problem.FieldInvalid, "actor_id must be a valid UUID")
+		}
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		parsed, err := parseFilterTime(raw)
+		if err != nil {
+			return audit.Filter{}, problem.Field("from", problem.FieldInvalid, "from must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+		}
+		filter.From = parsed
+	}
+	if raw := query.Get("to"); raw != "" {
+		parsed, err := parseFilterTime(raw)
+		if err != nil {
+			return audit.Filter{}, problem.Field("to", problem.FieldInvalid, "to must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+		}
+		filter.To = parsed
+	}
+
+	limit, offset, err := parsePagination(query)
+	if err != nil {
+		return audit.Filter{}, err
+	}
+	if limit > 0 {
+		filter.Limit = limit
+	}
+	filter.Offset = offset
+	return filter, nil
+}
+
+// parsePagination reads the limit and offset query parameters. A zero limit means the
+// parameter was not given.
+func parsePagination(query url.Values) (int, int, error) {
+	limit := 0
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxAdminSearchLimit {
+			return 0, 0, problem.Field("limit", problem.FieldInvalid, "limit must be between 1 and %d", maxAdminSearchLimit)
+		}
+		limit = parsed
+	}
+	offset := 0
+	if raw := query.Get("offset"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			return 0, 0, problem.Field("offset", problem.FieldInvalid, "offset must be a non-negative integer")
+		}
+		offset = parsed
+	}
+	return limit, offset, nil
+}
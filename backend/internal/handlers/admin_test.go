@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/problem"
+)
+
+// signUpAs registers a user, gives them role and returns their ID and a token carrying
+// the role.
+func (s authTestServer) signUpAs(t *testing.T, email, role string) (string, string) {
+	t.Helper()
+	s.register(t, email, "Password123")
+	user, err := s.store.Users().FindByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != models.RoleUser {
+		if err := s.store.Users().SetRole(context.Background(), user.ID, role, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status, body := s.do(t, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": email, "password": "Password123"})
+	if status != http.StatusOK {
+		t.Fatalf("login %s: status %d body %v", email, status, body)
+	}
+	return user.ID, body["token"].(string)
+}
+
+func TestRoleChangeRevokesSessions(t *testing.T) {
+	s := newAuthTestServer(t)
+	_, adminToken := s.signUpAs(t, "admin@example.com", models.RoleAdmin)
+	userID, userToken := s.signUpAs(t, "demoted@example.com", models.RoleAdmin)
+
+	path := "/api/v1/admin/users/" + userID + "/role"
+	if status, body := s.do(t, http.MethodPut, path, adminToken, map[string]string{"role": models.RoleUser}); status != http.StatusOK {
+		t.Fatalf("demote: status %d body %v", status, body)
+	}
+	// The old token still claims admin permissions, so it must stop working.
+	if status, _ := s.do(t, http.MethodPut, path, userToken, map[string]string{"role": models.RoleAdmin}); status != http.StatusUnauthorized {
+		t.Fatalf("demoted user's old token: status %d, want 401", status)
+	}
+	if status, _ := s.do(t, http.MethodGet, "/api/v1/auth/me", adminToken, nil); status != http.StatusOK {
+		t.Fatalf("acting admin's session: status %d, want 200", status)
+	}
+}
+
+func TestModerationRespectsRank(t *testing.T) {
+	s := newAuthTestServer(t)
+	adminID, adminToken := s.signUpAs(t, "admin@example.com", models.RoleAdmin)
+	_, modToken := s.signUpAs(t, "mod@example.com", models.RoleModerator)
+	userID, _ := s.signUpAs(t, "user@example.com", models.RoleUser)
+
+	for _, action := range []string{"suspend", "deactivate", "force-password-reset"} {
+		path := "/api/v1/admin/users/" + adminID + "/" + action
+		if status, body := s.do(t, http.MethodPost, path, modToken, nil); status != http.StatusForbidden || body["code"] != problem.CodeForbidden {
+			t.Errorf("moderator %s admin: status %d body %v, want 403", action, status, body)
+		}
+		if status, body := s.do(t, http.MethodPost, path, adminToken, nil); status != http.StatusBadRequest || body["code"] != problem.CodeSelfAction {
+			t.Errorf("admin %s self: status %d body %v, want 400", action, status, body)
+		}
+	}
+	if status, body := s.do(t, http.MethodPost, "/api/v1/admin/users/"+userID+"/suspend", modToken, nil); status != http.StatusOK {
+		t.Fatalf("moderator suspend user: status %d body %v", status, body)
+	}
+	if status, _ := s.do(t, http.MethodGet, "/api/v1/auth/me", adminToken, nil); status != http.StatusOK {
+		t.Fatalf("admin session after rejected actions: status %d, want 200", status)
+	}
+}
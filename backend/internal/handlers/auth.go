@@ -1,17 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/big"
-	"net/mail"
 	"net/http"
+	"net/mail"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -19,62 +22,42 @@ import (
 
 	"github.com/google/uuid"
 
+	"resellution/backend/api"
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/clientip"
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/featureflags"
+	"resellution/backend/internal/httpcache"
 	"resellution/backend/internal/middleware"
 	"resellution/backend/internal/models"
+	"resellution/backend/internal/observability"
+	"resellution/backend/internal/problem"
 	"resellution/backend/internal/utils"
 )
 
+// AuditRecorder records audit events. audit.Recorder writes them to Postgres.
+type AuditRecorder interface {
+	Record(ctx context.Context, event audit.Event) error
+}
+
 type AuthHandler struct {
-	Users                      models.UserStore
-	TokenManager               utils.TokenManager
-	EmailSender                utils.EmailSender
+	Users                        models.UserRepository
+	PasswordResets               models.PasswordResetRepository
+	Sessions                     models.SessionRepository
+	Tx                           db.Transactor
+	Audit                        AuditRecorder
+	TokenManager                 utils.TokenManager
+	EmailSender                  utils.EmailSender
+	Metrics                      *observability.Metrics
+	Flags                        FeatureFlags
 	TokenExpiryHours             int
 	PasswordResetExpiryMinutes   int
 	PasswordResetCooldownMinutes int
 	PasswordResetOTPDigits       int
 	PasswordResetMaxAttempts     int
-}
-
-type registerRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	FullName string `json:"full_name"`
-}
-
-type loginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-}
-
-type passwordResetRequest struct {
-	Email string `json:"email"`
-}
-
-type passwordResetConfirmRequest struct {
-	Email       string `json:"email"`
-	OTP         string `json:"otp"`
-	NewPassword string `json:"new_password"`
-}
-
-type authResponse struct {
-	Token string     `json:"token"`
-	User  publicUser `json:"user"`
-}
-
-type publicUser struct {
-	ID       string `json:"id"`
-	Email    string `json:"email"`
-	FullName string `json:"full_name"`
-	City     string `json:"city,omitempty"`
-	Bio      string `json:"bio,omitempty"`
-	PhotoURL string `json:"photo_url,omitempty"`
-}
-
-type updateProfileRequest struct {
-	FullName *string `json:"full_name"`
-	City     *string `json:"city"`
-	Bio      *string `json:"bio"`
-	PhotoURL *string `json:"photo_url"`
+	// LogResetOTP logs password reset codes when no EmailSender is set, so a local
+	// setup without SMTP can finish a reset.
+	LogResetOTP bool
 }
 
 const (
@@ -89,9 +72,9 @@ const (
 )
 
 func (h AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	var req registerRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+	var req api.RegisterRequest
+	if err := decodeJSON(r, &req); err != nil {
+		problem.BodyError(w, r, err)
 		return
 	}
 
@@ -99,26 +82,18 @@ func (h AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	req.Password = strings.TrimSpace(req.Password)
 	req.FullName = strings.TrimSpace(req.FullName)
 
-	if req.Email == "" || req.Password == "" || req.FullName == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email, password, and full_name are required"})
-		return
-	}
-	if err := validateEmail(req.Email); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-	if err := validateFullName(req.FullName); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-	if err := validatePassword(req.Password); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	var invalid fieldErrors
+	invalid.check("email", req.Email, validateEmail)
+	invalid.check("password", req.Password, validatePassword)
+	invalid.check("full_name", req.FullName, validateFullName)
+	if len(invalid) > 0 {
+		problem.Write(w, r, problem.Validation(invalid...))
 		return
 	}
 
-	hash, err := utils.HashPassword(req.Password)
+	hash, err := utils.HashPassword(r.Context(), req.Password)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to secure password"})
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to secure password")
 		return
 	}
 
@@ -129,127 +104,170 @@ func (h AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		FullName:     req.FullName,
 	}
 
-	createdUser, err := h.Users.Create(r.Context(), user)
+	var createdUser models.User
+	var token string
+	err = h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		var err error
+		createdUser, err = h.Users.Create(ctx, user)
+		if err != nil {
+			return err
+		}
+		if err := h.Audit.Record(ctx, audit.Event{
+			ActorID:    createdUser.ID,
+			Action:     audit.ActionUserRegistered,
+			TargetType: audit.TargetUser,
+			TargetID:   createdUser.ID,
+			After:      createdUser,
+		}); err != nil {
+			return err
+		}
+		token, err = h.createSession(ctx, r, createdUser)
+		return err
+	})
 	if err != nil {
-		log.Printf("register create user failed: %v", err)
-		lowerErr := strings.ToLower(err.Error())
-		if strings.Contains(lowerErr, "duplicate") || strings.Contains(lowerErr, "unique") {
-			writeJSON(w, http.StatusConflict, map[string]string{"error": "email already registered"})
+		slog.ErrorContext(r.Context(), "auth.register failed", "email", req.Email, "error", err)
+		if errors.Is(err, models.ErrEmailTaken) {
+			problem.Error(w, r, http.StatusConflict, problem.CodeEmailTaken, "email already registered")
 			return
 		}
+		lowerErr := strings.ToLower(err.Error())
 		if strings.Contains(lowerErr, "relation \"users\" does not exist") {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "users table not found; run backend migration first"})
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "users table not found; run `server migrate up` first")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create user"})
-		return
-	}
-	log.Printf("auth.register.success email=%s user_id=%s", createdUser.Email, createdUser.ID)
-
-	token, err := h.createToken(createdUser.ID)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create token"})
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to create user")
 		return
 	}
+	observability.SetUserID(r.Context(), createdUser.ID)
+	slog.InfoContext(r.Context(), "auth.register.success", "email", createdUser.Email)
+	h.Metrics.Inc(observability.EventRegistration)
 
-	writeJSON(w, http.StatusCreated, authResponse{
+	w.Header().Set("Cache-Control", httpcache.NoStore)
+	writeJSON(w, http.StatusCreated, api.AuthResponse{
 		Token: token,
 		User:  toPublicUser(createdUser),
 	})
 }
 
 func (h AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	var req loginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+	var req api.LoginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		problem.BodyError(w, r, err)
 		return
 	}
 
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 	req.Password = strings.TrimSpace(req.Password)
 
-	if req.Email == "" || req.Password == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email and password are required"})
-		return
-	}
-	if err := validateEmail(req.Email); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-	if len(req.Password) > maxPasswordLength {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("password must not exceed %d characters", maxPasswordLength)})
+	var invalid fieldErrors
+	invalid.check("email", req.Email, validateEmail)
+	invalid.check("password", req.Password, validatePasswordLength)
+	if len(invalid) > 0 {
+		problem.Write(w, r, problem.Validation(invalid...))
 		return
 	}
 
 	user, err := h.Users.FindByEmail(r.Context(), req.Email)
 	if err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
-			log.Printf("auth.login.failed email=%s reason=user_not_found", req.Email)
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid email or password"})
+			slog.InfoContext(r.Context(), "auth.login.failed", "email", req.Email, "reason", "user_not_found")
+			h.recordLoginFailure(r, "", "user_not_found")
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeInvalidCredentials, "invalid email or password")
 			return
 		}
-		log.Printf("auth.login.failed email=%s reason=fetch_error err=%v", req.Email, err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch user"})
+		slog.ErrorContext(r.Context(), "auth.login.failed", "email", req.Email, "reason", "fetch_error", "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to fetch user")
 		return
 	}
 
-	if !utils.VerifyPassword(req.Password, user.PasswordHash) {
-		log.Printf("auth.login.failed email=%s reason=invalid_password", req.Email)
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid email or password"})
+	if !utils.VerifyPassword(r.Context(), req.Password, user.PasswordHash) {
+		slog.InfoContext(r.Context(), "auth.login.failed", "email", req.Email, "reason", "invalid_password")
+		h.recordLoginFailure(r, user.ID, "invalid_password")
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeInvalidCredentials, "invalid email or password")
+		return
+	}
+	if user.SuspendedAt != nil {
+		slog.InfoContext(r.Context(), "auth.login.failed", "email", req.Email, "reason", "suspended")
+		h.recordLoginFailure(r, user.ID, "suspended")
+		problem.Error(w, r, http.StatusForbidden, problem.CodeAccountSuspended, "account suspended")
+		return
+	}
+	if user.PasswordResetRequired {
+		slog.InfoContext(r.Context(), "auth.login.failed", "email", req.Email, "reason", "password_reset_required")
+		h.recordLoginFailure(r, user.ID, "password_reset_required")
+		problem.Error(w, r, http.StatusForbidden, problem.CodePasswordResetRequired, "password reset required")
 		return
 	}
 
-	token, err := h.createToken(user.ID)
+	var token string
+	err = h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		var err error
+		token, err = h.createSession(ctx, r, user)
+		if err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			ActorID:    user.ID,
+			Action:     audit.ActionUserLogin,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+		})
+	})
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create token"})
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to create token")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, authResponse{Token: token, User: toPublicUser(user)})
-	log.Printf("auth.login.success email=%s user_id=%s", user.Email, user.ID)
+	w.Header().Set("Cache-Control", httpcache.NoStore)
+	writeJSON(w, http.StatusOK, api.AuthResponse{Token: token, User: toPublicUser(user)})
+	observability.SetUserID(r.Context(), user.ID)
+	slog.InfoContext(r.Context(), "auth.login.success", "email", user.Email)
+	h.Metrics.Inc(observability.EventLogin)
 }
 
 func (h AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "unauthorized")
 		return
 	}
 
 	user, err := h.Users.FindByID(r.Context(), userID)
 	if err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "unauthorized")
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to fetch user"})
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to fetch user")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]publicUser{"user": toPublicUser(user)})
+	flags := h.userFlags(user)
+	if httpcache.NotModified(w, r, meETag(user, flags), httpcache.Private) {
+		return
+	}
+	writeJSON(w, http.StatusOK, api.UserResponse{User: toPublicUser(user), Flags: flags})
 }
 
 func (h AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "unauthorized")
 		return
 	}
 
 	if r.Method != http.MethodPatch && r.Method != http.MethodPut {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	var req updateProfileRequest
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+	var req api.UpdateProfileRequest
+	if err := decodeJSON(r, &req); err != nil {
+		problem.BodyError(w, r, err)
 		return
 	}
 	if err := validateProfileUpdate(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeInvalid(w, r, err)
 		return
 	}
 
@@ -260,79 +278,148 @@ func (h AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		ProfileImageURL: req.PhotoURL,
 	}
 
-	user, err := h.Users.UpdateProfile(r.Context(), userID, userID, up)
+	var user models.User
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		before, err := h.Users.FindByIDForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := httpcache.CheckIfMatch(r, userETag(before)); err != nil {
+			return err
+		}
+		user, err = h.Users.UpdateProfile(ctx, userID, userID, up)
+		if err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			Action:     audit.ActionUserProfileUpdated,
+			TargetType: audit.TargetUser,
+			TargetID:   userID,
+			Before:     before,
+			After:      user,
+		})
+	})
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update profile"})
+		if writePreconditionError(w, r, err) {
+			return
+		}
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to update profile")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]publicUser{"user": toPublicUser(user)})
+	w.Header().Set("ETag", userETag(user))
+	writeJSON(w, http.StatusOK, api.UserResponse{User: toPublicUser(user)})
 }
 
 func (h AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "unauthorized")
+		return
+	}
+
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		if claims, ok := middleware.ClaimsFromContext(ctx); ok && claims.SessionID != "" {
+			if err := h.Sessions.Revoke(ctx, claims.SessionID); err != nil {
+				return err
+			}
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			Action:     audit.ActionUserLogout,
+			TargetType: audit.TargetUser,
+			TargetID:   userID,
+		})
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "auth.logout failed", "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to log out")
 		return
 	}
 
-	// Current auth is stateless JWT, so logout is client token disposal.
-	// Keeping this endpoint enables forward compatibility with token revocation.
-	writeJSON(w, http.StatusOK, map[string]string{"message": "logged out"})
-	log.Printf("auth.logout.success user_id=%s", userID)
+	writeJSON(w, http.StatusOK, api.MessageResponse{Message: "logged out"})
+	slog.InfoContext(r.Context(), "auth.logout.success")
 }
 
 func (h AuthHandler) DeactivateAccount(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "unauthorized")
 		return
 	}
 
-	if err := h.Users.DeactivateByID(r.Context(), userID, userID); err != nil {
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		before, err := h.Users.FindByIDForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := httpcache.CheckIfMatch(r, userETag(before)); err != nil {
+			return err
+		}
+		if err := h.Users.DeactivateByID(ctx, userID, userID); err != nil {
+			return err
+		}
+		if _, err := h.Sessions.RevokeAllByUserID(ctx, userID); err != nil {
+			return err
+		}
+		after, err := h.Users.FindByIDIncludingDeleted(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			Action:     audit.ActionUserDeactivated,
+			TargetType: audit.TargetUser,
+			TargetID:   userID,
+			Before:     before,
+			After:      after,
+		})
+	})
+	if err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "account not found"})
+			problem.Error(w, r, http.StatusNotFound, problem.CodeUserNotFound, "account not found")
+			return
+		}
+		if writePreconditionError(w, r, err) {
 			return
 		}
-		log.Printf("auth.deactivate failed user_id=%s err=%v", userID, err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to deactivate account"})
+		slog.ErrorContext(r.Context(), "auth.deactivate failed", "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to deactivate account")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"message": "account deactivated successfully"})
-	log.Printf("auth.deactivate.success user_id=%s", userID)
+	writeJSON(w, http.StatusOK, api.MessageResponse{Message: "account deactivated successfully"})
+	slog.InfoContext(r.Context(), "auth.deactivate.success")
 }
 
 func (h AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
-	var req passwordResetRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+	var req api.PasswordResetRequest
+	if err := decodeJSON(r, &req); err != nil {
+		problem.BodyError(w, r, err)
 		return
 	}
 
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 	if req.Email == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email is required"})
+		problem.Write(w, r, problem.Validation(problem.Field("email", problem.FieldRequired, "email is required")))
 		return
 	}
 
 	user, err := h.Users.FindByEmail(r.Context(), req.Email)
 	if err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
-			log.Printf("auth.password_reset.request ignored email=%s reason=user_not_found", req.Email)
-			writeJSON(w, http.StatusOK, map[string]string{"message": "If an account exists, an OTP has been sent to the registered email"})
+			slog.InfoContext(r.Context(), "auth.password_reset.request ignored", "email", req.Email, "reason", "user_not_found")
+			writeJSON(w, http.StatusOK, api.MessageResponse{Message: "If an account exists, an OTP has been sent to the registered email"})
 			return
 		}
-		log.Printf("auth.password_reset.request failed email=%s err=%v", req.Email, err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to process reset request"})
+		slog.ErrorContext(r.Context(), "auth.password_reset.request failed", "email", req.Email, "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to process reset request")
 		return
 	}
 
 	cooldown := h.passwordResetCooldownMinutes()
 	if cooldown > 0 {
-		lastRequestAt, recent, err := h.Users.GetLastPasswordResetRequestTime(r.Context(), user.ID, cooldown)
+		lastRequestAt, recent, err := h.PasswordResets.GetLastPasswordResetRequestTime(r.Context(), user.ID, cooldown)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to process reset request"})
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to process reset request")
 			return
 		}
 		if recent {
@@ -341,17 +428,17 @@ func (h AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request
 			if minutesLeft < 1 {
 				minutesLeft = 1
 			}
-			writeJSON(w, http.StatusTooManyRequests, map[string]any{
-				"error":                fmt.Sprintf("Please wait %d more minute(s) before requesting another reset code", minutesLeft),
-				"retry_after_minutes": minutesLeft,
-			})
+			w.Header().Set("Retry-After", strconv.Itoa(minutesLeft*60))
+			detail := fmt.Sprintf("Please wait %d more minute(s) before requesting another reset code", minutesLeft)
+			problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeResetCooldown, detail).
+				With("retry_after_minutes", minutesLeft))
 			return
 		}
 	}
 
 	otp, err := generateNumericOTP(h.passwordResetOTPDigits())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to process reset request"})
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to process reset request")
 		return
 	}
 
@@ -364,12 +451,23 @@ func (h AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request
 		MaxAttempts: h.passwordResetMaxAttempts(),
 	}
 
-	if err := h.Users.InvalidateActivePasswordResetOTPsByUserID(r.Context(), user.ID); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to process reset request"})
-		return
-	}
-	if err := h.Users.CreatePasswordResetOTP(r.Context(), resetOTP); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to process reset request"})
+	err = h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		if err := h.PasswordResets.InvalidateActivePasswordResetOTPsByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := h.PasswordResets.CreatePasswordResetOTP(ctx, resetOTP); err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			ActorID:    user.ID,
+			Action:     audit.ActionPasswordResetRequested,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]any{"expires_at": expiresAt},
+		})
+	})
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to process reset request")
 		return
 	}
 
@@ -381,23 +479,29 @@ func (h AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request
 	)
 
 	if h.EmailSender != nil {
-		if err := h.EmailSender.Send(req.Email, subject, body); err != nil {
-			log.Printf("failed to send password reset OTP email to %s: %v", req.Email, err)
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to process reset request"})
+		if err := h.EmailSender.Send(r.Context(), req.Email, subject, body); err != nil {
+			slog.ErrorContext(r.Context(), "auth.password_reset.request send failed", "email", req.Email, "error", err)
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to process reset request")
 			return
 		}
 	} else {
-		log.Printf("SMTP not configured. OTP for %s (expires %s): %s", req.Email, expiresAt.Format(time.RFC3339), otp)
+		slog.WarnContext(r.Context(), "SMTP not configured; password reset OTP not sent", "email", req.Email, "expires_at", expiresAt.Format(time.RFC3339))
+		if h.LogResetOTP {
+			// Development only: config refuses LOG_RESET_OTP in production. The key is
+			// not one of the redacted ones, so the code is readable.
+			slog.InfoContext(r.Context(), "password reset code for local development", "email", req.Email, "reset_code", otp)
+		}
 	}
+	h.Metrics.Inc(observability.EventPasswordResetOTPSent)
 
-	writeJSON(w, http.StatusOK, map[string]string{"message": "If an account exists, an OTP has been sent to the registered email"})
-	log.Printf("auth.password_reset.request success email=%s user_id=%s", req.Email, user.ID)
+	writeJSON(w, http.StatusOK, api.MessageResponse{Message: "If an account exists, an OTP has been sent to the registered email"})
+	slog.InfoContext(r.Context(), "auth.password_reset.request success", "email", req.Email, "account_id", user.ID)
 }
 
 func (h AuthHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
-	var req passwordResetConfirmRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+	var req api.PasswordResetConfirmRequest
+	if err := decodeJSON(r, &req); err != nil {
+		problem.BodyError(w, r, err)
 		return
 	}
 
@@ -405,70 +509,131 @@ func (h AuthHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request
 	req.OTP = strings.TrimSpace(req.OTP)
 	req.NewPassword = strings.TrimSpace(req.NewPassword)
 
-	if req.Email == "" || req.OTP == "" || req.NewPassword == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email, otp, and new_password are required"})
-		return
-	}
-	if err := validateEmail(req.Email); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-	if err := validatePassword(req.NewPassword); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": strings.Replace(err.Error(), "password", "new_password", 1)})
+	var invalid fieldErrors
+	invalid.check("email", req.Email, validateEmail)
+	invalid.check("otp", req.OTP, nil)
+	invalid.check("new_password", req.NewPassword, validatePassword)
+	if len(invalid) > 0 {
+		problem.Write(w, r, problem.Validation(invalid...))
 		return
 	}
 
 	user, err := h.Users.FindByEmail(r.Context(), req.Email)
 	if err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
-			log.Printf("auth.password_reset.confirm failed email=%s reason=user_not_found", req.Email)
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid or expired otp"})
+			slog.InfoContext(r.Context(), "auth.password_reset.confirm failed", "email", req.Email, "reason", "user_not_found")
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidOTP, "invalid or expired otp")
 			return
 		}
-		log.Printf("auth.password_reset.confirm failed email=%s reason=fetch_error err=%v", req.Email, err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset password"})
+		slog.ErrorContext(r.Context(), "auth.password_reset.confirm failed", "email", req.Email, "reason", "fetch_error", "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to reset password")
 		return
 	}
 
-	if err := h.Users.ConsumePasswordResetOTP(r.Context(), user.ID, passwordResetOTPHash(req.OTP)); err != nil {
-		if errors.Is(err, models.ErrPasswordResetOTPInvalid) {
-			log.Printf("auth.password_reset.confirm failed email=%s user_id=%s reason=invalid_otp", req.Email, user.ID)
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid or expired otp"})
-			return
-		}
-		log.Printf("auth.password_reset.confirm failed email=%s user_id=%s reason=consume_error err=%v", req.Email, user.ID, err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset password"})
+	// Hash before opening the transaction so the OTP row is not locked during bcrypt.
+	passwordHash, err := utils.HashPassword(r.Context(), req.NewPassword)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to reset password")
 		return
 	}
 
-	passwordHash, err := utils.HashPassword(req.NewPassword)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset password"})
+	// Consuming the OTP, changing the password and revoking sessions happen in one
+	// transaction, so a failure part way leaves the OTP usable and the old password in
+	// place. A wrong OTP still commits, to count the attempt.
+	otpInvalid := false
+	err = h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		otpInvalid = false
+		if err := h.PasswordResets.ConsumePasswordResetOTP(ctx, user.ID, passwordResetOTPHash(req.OTP)); err != nil {
+			if errors.Is(err, models.ErrPasswordResetOTPInvalid) {
+				otpInvalid = true
+				return nil
+			}
+			return err
+		}
+		if err := h.Users.UpdatePasswordHashByID(ctx, user.ID, passwordHash, user.ID); err != nil {
+			return err
+		}
+		if err := h.PasswordResets.InvalidateActivePasswordResetOTPsByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+		revoked, err := h.Sessions.RevokeAllByUserID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			ActorID:    user.ID,
+			Action:     audit.ActionPasswordResetCompleted,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]any{"sessions_revoked": revoked},
+		})
+	})
+	if err == nil && otpInvalid {
+		slog.InfoContext(r.Context(), "auth.password_reset.confirm failed", "email", req.Email, "account_id", user.ID, "reason", "invalid_otp")
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidOTP, "invalid or expired otp")
 		return
 	}
-
-	if err := h.Users.UpdatePasswordHashByID(r.Context(), user.ID, passwordHash, user.ID); err != nil {
+	if err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid or expired otp"})
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidOTP, "invalid or expired otp")
 			return
 		}
-		log.Printf("auth.password_reset.confirm failed email=%s user_id=%s reason=update_error err=%v", req.Email, user.ID, err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset password"})
+		slog.ErrorContext(r.Context(), "auth.password_reset.confirm failed", "email", req.Email, "account_id", user.ID, "reason", "update_error", "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to reset password")
 		return
 	}
 
-	if err := h.Users.InvalidateActivePasswordResetOTPsByUserID(r.Context(), user.ID); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset password"})
-		return
+	writeJSON(w, http.StatusOK, api.MessageResponse{Message: "password reset successful"})
+	slog.InfoContext(r.Context(), "auth.password_reset.confirm success", "email", req.Email, "account_id", user.ID)
+}
+
+// createSession issues a token for user and records the session it belongs to so it can
+// be revoked by logout or an admin.
+func (h AuthHandler) createSession(ctx context.Context, r *http.Request, user models.User) (string, error) {
+	permissions, err := h.Users.PermissionsForRole(ctx, user.Role)
+	if err != nil {
+		return "", err
+	}
+
+	sessionID := uuid.NewString()
+	expiresAt := time.Now().Add(time.Duration(h.TokenExpiryHours) * time.Hour)
+	token, err := h.TokenManager.CreateWithClaims(utils.Claims{
+		UserID:      user.ID,
+		Role:        user.Role,
+		Permissions: permissions,
+		SessionID:   sessionID,
+	}, expiresAt)
+	if err != nil {
+		return "", err
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"message": "password reset successful"})
-	log.Printf("auth.password_reset.confirm success email=%s user_id=%s", req.Email, user.ID)
+	err = h.Sessions.Create(ctx, models.Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		TokenFingerprint: utils.Fingerprint(token),
+		IPAddress:        clientip.FromRequest(r),
+		UserAgent:        r.UserAgent(),
+		ExpiresAt:        expiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
 }
 
-func (h AuthHandler) createToken(userID string) (string, error) {
-	expiresAt := time.Now().Add(time.Duration(h.TokenExpiryHours) * time.Hour)
-	return h.TokenManager.Create(userID, expiresAt)
+// recordLoginFailure is best effort: failing to audit a rejected login must not change
+// the response.
+func (h AuthHandler) recordLoginFailure(r *http.Request, userID, reason string) {
+	h.Metrics.Inc(observability.EventLoginFailure)
+	err := h.Audit.Record(r.Context(), audit.Event{
+		Action:     audit.ActionUserLoginFailed,
+		TargetType: audit.TargetUser,
+		TargetID:   userID,
+		Metadata:   map[string]any{"reason": reason},
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "auth.login.audit failed", "account_id", userID, "error", err)
+	}
 }
 
 func generateNumericOTP(length int) (string, error) {
@@ -517,53 +682,159 @@ func (h AuthHandler) passwordResetMaxAttempts() int {
 	return h.PasswordResetMaxAttempts
 }
 
-func toPublicUser(user models.User) publicUser {
-	return publicUser{
+func toPublicUser(user models.User) api.PublicUser {
+	return api.PublicUser{
 		ID:       user.ID,
 		Email:    user.Email,
 		FullName: user.FullName,
 		City:     user.City,
 		Bio:      user.Bio,
 		PhotoURL: user.ProfileImageURL,
+		Role:     user.Role,
 	}
 }
 
+// decodeJSON decodes the request body into dst, rejecting fields dst does not have so
+// that typos and stale clients fail loudly. Errors are reported with problem.BodyError.
+func decodeJSON(r *http.Request, dst any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(dst)
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
-func validateEmail(email string) error {
+// writeCacheableJSON writes payload with an ETag of its encoding, or 304 when the
+// client's copy is current. It suits resources without an updated_at.
+func writeCacheableJSON(w http.ResponseWriter, r *http.Request, cacheControl string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to encode response")
+		return
+	}
+	body = append(body, '\n')
+	if httpcache.NotModified(w, r, httpcache.ContentETag(body), cacheControl) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// userETag is the version of the profile /users/me writes check.
+func userETag(user models.User) string {
+	return httpcache.ETag(user.ID, user.UpdatedAt)
+}
+
+// meETag is the ETag of /auth/me, which also returns the user's feature flags: it is
+// derived from the profile version, so a flag change reaches clients holding a cached
+// copy, while If-Match with it still checks only the profile.
+func meETag(user models.User, flags map[string]bool) string {
+	if flags == nil {
+		return userETag(user)
+	}
+	keys := make([]string, 0, len(flags))
+	for key := range flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var values strings.Builder
+	for _, key := range keys {
+		values.WriteString(key + "=" + strconv.FormatBool(flags[key]) + ",")
+	}
+	return httpcache.Derive(userETag(user), []byte(values.String()))
+}
+
+// userFlags evaluates the feature flags for user, or returns nil without a flag
+// service.
+func (h AuthHandler) userFlags(user models.User) map[string]bool {
+	if h.Flags == nil {
+		return nil
+	}
+	return h.Flags.ForUser(featureflags.User{ID: user.ID, City: user.City})
+}
+
+// writePreconditionError reports a failed If-Match check and returns whether err was
+// one.
+func writePreconditionError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, httpcache.ErrPreconditionRequired):
+		problem.Error(w, r, http.StatusPreconditionRequired, problem.CodePreconditionRequired, "If-Match is required; send the ETag of the version you are changing")
+	case errors.Is(err, httpcache.ErrPreconditionFailed):
+		problem.Error(w, r, http.StatusPreconditionFailed, problem.CodePreconditionFailed, "the resource has changed since it was read; fetch it again and reapply the change")
+	default:
+		return false
+	}
+	return true
+}
+
+// writeInvalid reports a validation failure, with field details when err is a
+// problem.FieldError.
+func writeInvalid(w http.ResponseWriter, r *http.Request, err error) {
+	var field problem.FieldError
+	if errors.As(err, &field) {
+		problem.Write(w, r, problem.Validation(field))
+		return
+	}
+	problem.Error(w, r, http.StatusBadRequest, problem.CodeValidationFailed, err.Error())
+}
+
+// fieldErrors collects validation failures so one response reports every invalid field.
+type fieldErrors []problem.FieldError
+
+// check records field as required when value is empty, otherwise runs validate.
+func (f *fieldErrors) check(field, value string, validate func(field, value string) error) {
+	if value == "" {
+		*f = append(*f, problem.Field(field, problem.FieldRequired, "%s is required", field))
+		return
+	}
+	if validate == nil {
+		return
+	}
+	if err := validate(field, value); err != nil {
+		var fieldErr problem.FieldError
+		if !errors.As(err, &fieldErr) {
+			fieldErr = problem.Field(field, problem.FieldInvalid, "%s", err.Error())
+		}
+		*f = append(*f, fieldErr)
+	}
+}
+
+func validateEmail(field, email string) error {
 	if len(email) > maxEmailLength {
-		return fmt.Errorf("email must not exceed %d characters", maxEmailLength)
+		return problem.Field(field, problem.FieldTooLong, "%s must not exceed %d characters", field, maxEmailLength)
 	}
 
 	parsed, err := mail.ParseAddress(email)
 	if err != nil || parsed.Address != email || strings.Count(email, "@") != 1 {
-		return errors.New("email format is invalid")
+		return problem.Field(field, problem.FieldInvalid, "%s format is invalid", field)
 	}
 
 	return nil
 }
 
-func validateFullName(fullName string) error {
+func validateFullName(field, fullName string) error {
 	if len(fullName) < minFullNameLength {
-		return fmt.Errorf("full_name must be at least %d characters", minFullNameLength)
+		return problem.Field(field, problem.FieldTooShort, "%s must be at least %d characters", field, minFullNameLength)
 	}
 	if len(fullName) > maxFullNameLength {
-		return fmt.Errorf("full_name must not exceed %d characters", maxFullNameLength)
+		return problem.Field(field, problem.FieldTooLong, "%s must not exceed %d characters", field, maxFullNameLength)
 	}
 
 	return nil
 }
 
-func validatePassword(password string) error {
+func validatePassword(field, password string) error {
 	if len(password) < minPasswordLength {
-		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+		return problem.Field(field, problem.FieldTooShort, "%s must be at least %d characters", field, minPasswordLength)
 	}
-	if len(password) > maxPasswordLength {
-		return fmt.Errorf("password must not exceed %d characters", maxPasswordLength)
+	if err := validatePasswordLength(field, password); err != nil {
+		return err
 	}
 
 	var hasLetter bool
@@ -577,20 +848,29 @@ func validatePassword(password string) error {
 		}
 	}
 	if !hasLetter || !hasDigit {
-		return errors.New("password must include at least one letter and one number")
+		return problem.Field(field, problem.FieldInvalid, "%s must include at least one letter and one number", field)
 	}
 
 	return nil
 }
 
-func validateProfileUpdate(req *updateProfileRequest) error {
+// validatePasswordLength only enforces the bcrypt input limit, for login where older
+// passwords may predate the strength rules.
+func validatePasswordLength(field, password string) error {
+	if len(password) > maxPasswordLength {
+		return problem.Field(field, problem.FieldTooLong, "%s must not exceed %d characters", field, maxPasswordLength)
+	}
+	return nil
+}
+
+func validateProfileUpdate(req *api.UpdateProfileRequest) error {
 	if req.FullName == nil && req.City == nil && req.Bio == nil && req.PhotoURL == nil {
 		return errors.New("at least one profile field is required")
 	}
 
 	if req.FullName != nil {
 		trimmed := strings.TrimSpace(*req.FullName)
-		if err := validateFullName(trimmed); err != nil {
+		if err := validateFullName("full_name", trimmed); err != nil {
 			return err
 		}
 		*req.FullName = trimmed
@@ -599,7 +879,7 @@ func validateProfileUpdate(req *updateProfileRequest) error {
 	if req.City != nil {
 		trimmed := strings.TrimSpace(*req.City)
 		if utf8.RuneCountInString(trimmed) > maxCityLength {
-			return fmt.Errorf("city must not exceed %d characters", maxCityLength)
+			return problem.Field("city", problem.FieldTooLong, "city must not exceed %d characters", maxCityLength)
 		}
 		*req.City = trimmed
 	}
@@ -607,7 +887,7 @@ func validateProfileUpdate(req *updateProfileRequest) error {
 	if req.Bio != nil {
 		trimmed := strings.TrimSpace(*req.Bio)
 		if utf8.RuneCountInString(trimmed) > maxBioLength {
-			return fmt.Errorf("bio must not exceed %d characters", maxBioLength)
+			return problem.Field("bio", problem.FieldTooLong, "bio must not exceed %d characters", maxBioLength)
 		}
 		*req.Bio = trimmed
 	}
@@ -616,14 +896,14 @@ func validateProfileUpdate(req *updateProfileRequest) error {
 		trimmed := strings.TrimSpace(*req.PhotoURL)
 		if trimmed != "" {
 			if len(trimmed) > maxPhotoURLLength {
-				return fmt.Errorf("photo_url must not exceed %d characters", maxPhotoURLLength)
+				return problem.Field("photo_url", problem.FieldTooLong, "photo_url must not exceed %d characters", maxPhotoURLLength)
 			}
 			parsed, err := url.ParseRequestURI(trimmed)
 			if err != nil || parsed.Scheme == "" || parsed.Host == "" {
-				return errors.New("photo_url must be a valid absolute URL")
+				return problem.Field("photo_url", problem.FieldInvalid, "photo_url must be a valid absolute URL")
 			}
 			if parsed.Scheme != "http" && parsed.Scheme != "https" {
-				return errors.New("photo_url must start with http:// or https://")
+				return problem.Field("photo_url", problem.FieldInvalid, "photo_url must start with http:// or https://")
 			}
 		}
 		*req.PhotoURL = trimmed
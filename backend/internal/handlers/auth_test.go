@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/featureflags"
+	"resellution/backend/internal/memstore"
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/openapi"
+	"resellution/backend/internal/problem"
+	"resellution/backend/internal/utils"
+)
+
+type capturedEmail struct {
+	to, subject, body string
+}
+
+type fakeEmailSender struct {
+	sent []capturedEmail
+}
+
+func (f *fakeEmailSender) Send(_ context.Context, toEmail, subject, body string) error {
+	f.sent = append(f.sent, capturedEmail{toEmail, subject, body})
+	return nil
+}
+
+type authTestServer struct {
+	store   *memstore.Store
+	email   *fakeEmailSender
+	handler http.Handler
+}
+
+func newAuthTestServer(t *testing.T) authTestServer {
+	t.Helper()
+	store := memstore.New()
+	email := &fakeEmailSender{}
+	tokenManager := utils.NewTokenManager("test-secret")
+	flagStore := featureflags.NewMemoryStore(featureflags.Flag{Key: featureflags.Chat, Description: "Chat"})
+	flags := featureflags.NewService(flagStore, nil)
+	if err := flags.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h := AuthHandler{
+		Users:                        store.Users(),
+		PasswordResets:               store.Users(),
+		Sessions:                     store.Sessions(),
+		Tx:                           store,
+		Audit:                        store.Audit(),
+		TokenManager:                 tokenManager,
+		EmailSender:                  email,
+		Flags:                        flags,
+		TokenExpiryHours:             1,
+		PasswordResetExpiryMinutes:   10,
+		PasswordResetCooldownMinutes: 1,
+		PasswordResetOTPDigits:       6,
+		PasswordResetMaxAttempts:     5,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/register", middleware.MaxBodySize(4<<10, h.Register))
+	mux.HandleFunc("POST /api/v1/auth/login", h.Login)
+	mux.HandleFunc("POST /api/v1/auth/password/reset/request", h.RequestPasswordReset)
+	mux.HandleFunc("POST /api/v1/auth/password/reset/confirm", h.ConfirmPasswordReset)
+	mux.HandleFunc("GET /api/v1/auth/me", middleware.Auth(tokenManager, store.Sessions(), h.Me))
+	mux.HandleFunc("POST /api/v1/auth/logout", middleware.Auth(tokenManager, store.Sessions(), h.Logout))
+	mux.HandleFunc("PATCH /api/v1/users/me", middleware.Auth(tokenManager, store.Sessions(), h.UpdateProfile))
+	mux.HandleFunc("DELETE /api/v1/users/me", middleware.Auth(tokenManager, store.Sessions(), h.DeactivateAccount))
+	flagHandler := FeatureFlagHandler{Flags: flagStore, Cache: flags, Tx: store, Audit: store.Audit()}
+	mux.HandleFunc("GET /api/v1/admin/feature-flags", middleware.Auth(tokenManager, store.Sessions(), flagHandler.List))
+	mux.HandleFunc("PATCH /api/v1/admin/feature-flags/{key}", middleware.Auth(tokenManager, store.Sessions(), flagHandler.Update))
+	admin := AdminHandler{Users: store.Users(), Sessions: store.Sessions(), Tx: store, Audit: store.Audit()}
+	permitted := func(permission string, next http.HandlerFunc) http.HandlerFunc {
+		return middleware.Auth(tokenManager, store.Sessions(), middleware.Require(permission, next))
+	}
+	mux.HandleFunc("PUT /api/v1/admin/users/{id}/role", permitted(models.PermissionRolesManage, admin.SetUserRole))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/deactivate", permitted(models.PermissionUsersModerate, admin.DeactivateUser))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/suspend", permitted(models.PermissionUsersModerate, admin.SuspendUser))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/force-password-reset", permitted(models.PermissionUsersModerate, admin.ForcePasswordReset))
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every response must match the OpenAPI document.
+	handler := openapi.ValidateResponses(spec, func(r *http.Request, err error) {
+		t.Errorf("response does not match openapi.json: %v", err)
+	}, mux)
+	return authTestServer{store: store, email: email, handler: handler}
+}
+
+func (s authTestServer) do(t *testing.T, method, path, token string, body any) (int, map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+
+	var decoded map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &decoded)
+	return rec.Code, decoded
+}
+
+func (s authTestServer) register(t *testing.T, email, password string) string {
+	t.Helper()
+	status, body := s.do(t, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"email": email, "password": password, "full_name": "Kavya Iyer",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("register: status %d body %v", status, body)
+	}
+	return body["token"].(string)
+}
+
+func TestRegisterLoginAndLogout(t *testing.T) {
+	s := newAuthTestServer(t)
+	token := s.register(t, "Kavya@Example.com", "Password123")
+
+	status, body := s.do(t, http.MethodGet, "/api/v1/auth/me", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("me: status %d body %v", status, body)
+	}
+	if user, _ := body["user"].(map[string]any); user == nil || user["email"] != "kavya@example.com" {
+		t.Fatalf("me: unexpected body %v", body)
+	}
+
+	status, _ = s.do(t, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"email": "kavya@example.com", "password": "Password123", "full_name": "Someone Else",
+	})
+	if status != http.StatusConflict {
+		t.Fatalf("duplicate register: status %d, want 409", status)
+	}
+
+	status, _ = s.do(t, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": "kavya@example.com", "password": "wrong-password"})
+	if status != http.StatusUnauthorized {
+		t.Fatalf("bad login: status %d, want 401", status)
+	}
+	status, body = s.do(t, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": "kavya@example.com", "password": "Password123"})
+	if status != http.StatusOK {
+		t.Fatalf("login: status %d body %v", status, body)
+	}
+
+	if status, _ := s.do(t, http.MethodPost, "/api/v1/auth/logout", token, nil); status != http.StatusOK {
+		t.Fatalf("logout: status %d", status)
+	}
+	if status, _ := s.do(t, http.MethodGet, "/api/v1/auth/me", token, nil); status != http.StatusUnauthorized {
+		t.Fatalf("me after logout: status %d, want 401", status)
+	}
+
+	actions := map[string]int{}
+	for _, event := range s.store.Audit().Events() {
+		actions[event.Action]++
+	}
+	for _, action := range []string{audit.ActionUserRegistered, audit.ActionUserLogin, audit.ActionUserLoginFailed, audit.ActionUserLogout} {
+		if actions[action] == 0 {
+			t.Errorf("expected a %s audit event, got %v", action, actions)
+		}
+	}
+}
+
+func TestProfileConditionalRequests(t *testing.T) {
+	s := newAuthTestServer(t)
+	token := s.register(t, "meera@example.com", "Password123")
+
+	send := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		s.handler.ServeHTTP(rec, req)
+		return rec
+	}
+	expect := func(rec *httptest.ResponseRecorder, status int, code string) {
+		t.Helper()
+		if rec.Code != status {
+			t.Fatalf("status %d, want %d: %s", rec.Code, status, rec.Body)
+		}
+		if code != "" {
+			var p problem.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil || p.Code != code {
+				t.Fatalf("code %q, want %q", p.Code, code)
+			}
+		}
+	}
+
+	me := send(http.MethodGet, "/api/v1/auth/me", "", nil)
+	expect(me, http.StatusOK, "")
+	etag := me.Header().Get("ETag")
+	if etag == "" || me.Header().Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("me headers = %v", me.Header())
+	}
+	notModified := send(http.MethodGet, "/api/v1/auth/me", "", map[string]string{"If-None-Match": etag})
+	expect(notModified, http.StatusNotModified, "")
+	if notModified.Body.Len() != 0 {
+		t.Errorf("304 has a body: %s", notModified.Body)
+	}
+
+	update := `{"city":"Pune"}`
+	expect(send(http.MethodPatch, "/api/v1/users/me", update, nil), http.StatusPreconditionRequired, problem.CodePreconditionRequired)
+	updated := send(http.MethodPatch, "/api/v1/users/me", update, map[string]string{"If-Match": etag})
+	expect(updated, http.StatusOK, "")
+	newETag := updated.Header().Get("ETag")
+	if newETag == "" || newETag == etag {
+		t.Fatalf("ETag after update = %q, was %q", newETag, etag)
+	}
+
+	// A second tab still holding the old version cannot overwrite the change.
+	expect(send(http.MethodPatch, "/api/v1/users/me", `{"city":"Goa"}`, map[string]string{"If-Match": etag}), http.StatusPreconditionFailed, problem.CodePreconditionFailed)
+	expect(send(http.MethodGet, "/api/v1/auth/me", "", map[string]string{"If-None-Match": etag}), http.StatusOK, "")
+	expect(send(http.MethodDelete, "/api/v1/users/me", "", map[string]string{"If-Match": etag}), http.StatusPreconditionFailed, problem.CodePreconditionFailed)
+	expect(send(http.MethodDelete, "/api/v1/users/me", "", map[string]string{"If-Match": newETag}), http.StatusOK, "")
+}
+
+func TestFeatureFlags(t *testing.T) {
+	s := newAuthTestServer(t)
+	token := s.register(t, "farah@example.com", "Password123")
+
+	me := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		s.handler.ServeHTTP(rec, req)
+		return rec
+	}
+	chatFlag := func(rec *httptest.ResponseRecorder) (userID string, chat any) {
+		t.Helper()
+		var body struct {
+			User  struct{ ID string }
+			Flags map[string]bool
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("me: %v: %s", err, rec.Body)
+		}
+		value, ok := body.Flags[featureflags.Chat]
+		if !ok {
+			return body.User.ID, nil
+		}
+		return body.User.ID, value
+	}
+
+	before := me("")
+	userID, chat := chatFlag(before)
+	if chat != false {
+		t.Fatalf("chat = %v before rollout, want false", chat)
+	}
+
+	status, body := s.do(t, http.MethodPatch, "/api/v1/admin/feature-flags/chat", token, map[string]any{"rollout_percentage": 150})
+	if status != http.StatusBadRequest || body["code"] != problem.CodeValidationFailed {
+		t.Fatalf("bad percentage: status %d body %v", status, body)
+	}
+	status, body = s.do(t, http.MethodPatch, "/api/v1/admin/feature-flags/payments", token, map[string]any{"enabled": true})
+	if status != http.StatusNotFound || body["code"] != problem.CodeFeatureFlagNotFound {
+		t.Fatalf("unknown flag: status %d body %v", status, body)
+	}
+	status, body = s.do(t, http.MethodPatch, "/api/v1/admin/feature-flags/chat", token, map[string]any{
+		"enabled": true, "allowed_user_ids": []string{userID, userID},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("update: status %d body %v", status, body)
+	}
+	if flag := body["flag"].(map[string]any); len(flag["allowed_user_ids"].([]any)) != 1 || flag["updated_by"] != userID {
+		t.Fatalf("updated flag = %v", flag)
+	}
+
+	// The update refreshed the cache, and the new flag value invalidates the cached
+	// profile.
+	after := me(before.Header().Get("ETag"))
+	if after.Code != http.StatusOK {
+		t.Fatalf("me after rollout: status %d, want 200", after.Code)
+	}
+	if _, chat := chatFlag(after); chat != true {
+		t.Fatalf("chat = %v after rollout, want true", chat)
+	}
+	// Profile writes check only the profile, so the ETag read before the rollout is
+	// still current for them.
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/me", strings.NewReader(`{"city":"Pune"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", before.Header().Get("ETag"))
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update with the pre-rollout ETag: status %d body %s", rec.Code, rec.Body)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/v1/admin/feature-flags", token, nil)
+	if status != http.StatusOK || len(body["flags"].([]any)) != 1 {
+		t.Fatalf("list: status %d body %v", status, body)
+	}
+	events, _, _ := s.store.Audit().Query(context.Background(), audit.Filter{Action: audit.ActionAdminFeatureFlagUpdated, Limit: 10})
+	if len(events) != 1 || events[0].TargetID != featureflags.Chat {
+		t.Fatalf("audit events = %+v", events)
+	}
+}
+
+func TestPasswordResetRevokesSessions(t *testing.T) {
+	s := newAuthTestServer(t)
+	token := s.register(t, "reset@example.com", "Password123")
+
+	if status, _ := s.do(t, http.MethodPost, "/api/v1/auth/password/reset/request", "", map[string]string{"email": "reset@example.com"}); status != http.StatusOK {
+		t.Fatalf("reset request: status %d", status)
+	}
+	if len(s.email.sent) != 1 {
+		t.Fatalf("expected one email, got %d", len(s.email.sent))
+	}
+	otp := regexp.MustCompile(`\d{6}`).FindString(s.email.sent[0].body)
+
+	status, _ := s.do(t, http.MethodPost, "/api/v1/auth/password/reset/confirm", "", map[string]string{
+		"email": "reset@example.com", "otp": "000000", "new_password": "NewPassword456",
+	})
+	if otp != "000000" && status != http.StatusBadRequest {
+		t.Fatalf("wrong otp: status %d, want 400", status)
+	}
+
+	status, body := s.do(t, http.MethodPost, "/api/v1/auth/password/reset/confirm", "", map[string]string{
+		"email": "reset@example.com", "otp": otp, "new_password": "NewPassword456",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("reset confirm: status %d body %v", status, body)
+	}
+
+	if status, _ := s.do(t, http.MethodGet, "/api/v1/auth/me", token, nil); status != http.StatusUnauthorized {
+		t.Fatalf("old session after reset: status %d, want 401", status)
+	}
+	status, _ = s.do(t, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": "reset@example.com", "password": "NewPassword456"})
+	if status != http.StatusOK {
+		t.Fatalf("login with new password: status %d", status)
+	}
+}
+
+func TestErrorsUseProblemCodes(t *testing.T) {
+	s := newAuthTestServer(t)
+	token := s.register(t, "codes@example.com", "Password123")
+
+	status, body := s.do(t, http.MethodPost, "/api/v1/auth/register", "", map[string]string{"email": "not-an-email", "password": "short"})
+	if status != http.StatusBadRequest || body["code"] != problem.CodeValidationFailed {
+		t.Fatalf("invalid register: status %d body %v", status, body)
+	}
+	fields := map[string]string{}
+	for _, raw := range body["errors"].([]any) {
+		field := raw.(map[string]any)
+		fields[field["field"].(string)] = field["code"].(string)
+	}
+	want := map[string]string{"email": problem.FieldInvalid, "password": problem.FieldTooShort, "full_name": problem.FieldRequired}
+	for field, code := range want {
+		if fields[field] != code {
+			t.Errorf("field %s code = %q, want %q", field, fields[field], code)
+		}
+	}
+
+	for _, tc := range []struct {
+		name, method, path, token string
+		body                      any
+		status                    int
+		code                      string
+	}{
+		{"bad credentials", http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": "codes@example.com", "password": "Wrong1234"}, http.StatusUnauthorized, problem.CodeInvalidCredentials},
+		{"missing token", http.MethodGet, "/api/v1/auth/me", "", nil, http.StatusUnauthorized, problem.CodeUnauthenticated},
+		{"bad token", http.MethodGet, "/api/v1/auth/me", "nope", nil, http.StatusUnauthorized, problem.CodeInvalidToken},
+		{"email taken", http.MethodPost, "/api/v1/auth/register", "", map[string]string{"email": "codes@example.com", "password": "Password123", "full_name": "Other"}, http.StatusConflict, problem.CodeEmailTaken},
+		{"wrong otp", http.MethodPost, "/api/v1/auth/password/reset/confirm", "", map[string]string{"email": "codes@example.com", "otp": "x", "new_password": "Password456"}, http.StatusBadRequest, problem.CodeInvalidOTP},
+		{"unknown field", http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": "codes@example.com", "password": "Password123", "remember": "yes"}, http.StatusBadRequest, problem.CodeInvalidJSON},
+		{"body too large", http.MethodPost, "/api/v1/auth/register", "", map[string]string{"email": "big@example.com", "password": "Password123", "full_name": strings.Repeat("x", 8<<10)}, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge},
+	} {
+		status, body := s.do(t, tc.method, tc.path, tc.token, tc.body)
+		if status != tc.status || body["code"] != tc.code {
+			t.Errorf("%s: status %d code %v, want %d %s", tc.name, status, body["code"], tc.status, tc.code)
+		}
+	}
+
+	if status, _ := s.do(t, http.MethodPost, "/api/v1/auth/logout", token, nil); status != http.StatusOK {
+		t.Fatalf("logout: status %d", status)
+	}
+	if status, body := s.do(t, http.MethodGet, "/api/v1/auth/me", token, nil); body["code"] != problem.CodeSessionRevoked {
+		t.Fatalf("revoked session: status %d body %v", status, body)
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"resellution/backend/api"
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/httpcache"
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/problem"
+)
+
+type CategoryHandler struct {
+	Categories models.CategoryRepository
+	Tx         db.Transactor
+	Audit      AuditRecorder
+}
+
+const (
+	minCategoryNameLength = 2
+	maxCategoryNameLength = 100
+	maxCategorySlugLength = 100
+)
+
+var categorySlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func (h CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.Categories.List(r.Context())
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to fetch categories")
+		return
+	}
+
+	writeCacheableJSON(w, r, httpcache.Public, api.CategoryList{Categories: categories})
+}
+
+func (h CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req api.CreateCategoryRequest
+	if err := decodeJSON(r, &req); err != nil {
+		problem.BodyError(w, r, err)
+		return
+	}
+
+	category := models.Category{
+		ID:       uuid.NewString(),
+		Name:     strings.TrimSpace(req.Name),
+		Slug:     strings.TrimSpace(strings.ToLower(req.Slug)),
+		ParentID: strings.TrimSpace(req.ParentID),
+	}
+	if err := validateCategory(category); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	var created models.Category
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		var err error
+		created, err = h.Categories.Create(ctx, category)
+		if err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			Action:     audit.ActionAdminCategoryCreated,
+			TargetType: audit.TargetCategory,
+			TargetID:   created.ID,
+			After:      created,
+		})
+	})
+	if err != nil {
+		writeCategoryError(w, r, err, "failed to create category")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, api.CategoryResponse{Category: created})
+}
+
+func (h CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeCategoryNotFound, "category not found")
+		return
+	}
+
+	var req api.UpdateCategoryRequest
+	if err := decodeJSON(r, &req); err != nil {
+		problem.BodyError(w, r, err)
+		return
+	}
+	if req.Name == nil && req.Slug == nil && req.ParentID == nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "at least one category field is required")
+		return
+	}
+
+	var updated models.Category
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		before, err := h.Categories.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		category := before
+		if req.Name != nil {
+			category.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.Slug != nil {
+			category.Slug = strings.TrimSpace(strings.ToLower(*req.Slug))
+		}
+		if req.ParentID != nil {
+			category.ParentID = strings.TrimSpace(*req.ParentID)
+		}
+		if err := validateCategory(category); err != nil {
+			return err
+		}
+
+		updated, err = h.Categories.Update(ctx, category)
+		if err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			Action:     audit.ActionAdminCategoryUpdated,
+			TargetType: audit.TargetCategory,
+			TargetID:   id,
+			Before:     before,
+			After:      updated,
+		})
+	})
+	if err != nil {
+		// Validation runs inside the transaction, against the stored category.
+		var invalid problem.FieldError
+		if errors.As(err, &invalid) {
+			writeInvalid(w, r, invalid)
+			return
+		}
+		writeCategoryError(w, r, err, "failed to update category")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.CategoryResponse{Category: updated})
+}
+
+func (h CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeCategoryNotFound, "category not found")
+		return
+	}
+
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		before, err := h.Categories.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := h.Categories.Delete(ctx, id); err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			Action:     audit.ActionAdminCategoryDeleted,
+			TargetType: audit.TargetCategory,
+			TargetID:   id,
+			Before:     before,
+		})
+	})
+	if err != nil {
+		writeCategoryError(w, r, err, "failed to delete category")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, api.MessageResponse{Message: "category deleted"})
+}
+
+func writeCategoryError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	switch {
+	case errors.Is(err, models.ErrCategoryNotFound):
+		problem.Error(w, r, http.StatusNotFound, problem.CodeCategoryNotFound, "category not found")
+	case errors.Is(err, models.ErrCategoryConflict):
+		problem.Error(w, r, http.StatusConflict, problem.CodeCategoryConflict, "category name or slug already exists")
+	case errors.Is(err, models.ErrCategoryParentNotFound):
+		problem.Write(w, r, problem.Validation(problem.Field("parent_id", problem.FieldNotFound, "parent_id does not reference an existing category")))
+	default:
+		slog.ErrorContext(r.Context(), "category write failed", "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, fallback)
+	}
+}
+
+func validateCategory(category models.Category) error {
+	if len(category.Name) < minCategoryNameLength {
+		return problem.Field("name", problem.FieldTooShort, "name must be at least %d characters", minCategoryNameLength)
+	}
+	if len(category.Name) > maxCategoryNameLength {
+		return problem.Field("name", problem.FieldTooLong, "name must not exceed %d characters", maxCategoryNameLength)
+	}
+	if len(category.Slug) > maxCategorySlugLength {
+		return problem.Field("slug", problem.FieldTooLong, "slug must not exceed %d characters", maxCategorySlugLength)
+	}
+	if !categorySlugPattern.MatchString(category.Slug) {
+		return problem.Field("slug", problem.FieldInvalid, "slug must contain only lowercase letters, numbers and single hyphens")
+	}
+	if category.ParentID != "" {
+		if _, err := uuid.Parse(category.ParentID); err != nil {
+			return problem.Field("parent_id", problem.FieldInvalid, "parent_id must be a valid UUID")
+		}
+		if category.ParentID == category.ID {
+			return problem.Field("parent_id", problem.FieldInvalid, "parent_id must not reference the category itself")
+		}
+	}
+	return nil
+}
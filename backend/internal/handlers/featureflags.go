@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"resellution/backend/api"
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/featureflags"
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/problem"
+)
+
+// FeatureFlags evaluates every feature flag for a user.
+type FeatureFlags interface {
+	ForUser(user featureflags.User) map[string]bool
+}
+
+// FlagRefresher reloads cached feature flags.
+type FlagRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+type FeatureFlagHandler struct {
+	Flags featureflags.Store
+	// Cache is refreshed after a change so this instance applies it at once; other
+	// instances pick it up on their next periodic refresh.
+	Cache FlagRefresher
+	Tx    db.Transactor
+	Audit AuditRecorder
+}
+
+const (
+	maxFlagAllowedUsers = 1000
+	maxFlagCities       = 100
+	maxFlagCityLength   = maxCityLength
+)
+
+// List returns every flag straight from the store, not from the cache.
+func (h FeatureFlagHandler) List(w http.ResponseWriter, r *http.Request) {
+	flags, err := h.Flags.List(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "feature flag list failed", "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to fetch feature flags")
+		return
+	}
+	if flags == nil {
+		flags = []featureflags.Flag{}
+	}
+
+	writeJSON(w, http.StatusOK, api.FeatureFlagList{Flags: flags})
+}
+
+// Update changes the targeting of a flag: turning it on or off, the rollout percentage,
+// the allow-listed users and the targeted cities.
+func (h FeatureFlagHandler) Update(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "unauthorized")
+		return
+	}
+	key := r.PathValue("key")
+
+	var req api.UpdateFeatureFlagRequest
+	if err := decodeJSON(r, &req); err != nil {
+		problem.BodyError(w, r, err)
+		return
+	}
+	if req.Enabled == nil && req.RolloutPercentage == nil && req.AllowedUserIDs == nil && req.Cities == nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "at least one feature flag field is required")
+		return
+	}
+	if err := validateFlagRequest(&req); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	var updated featureflags.Flag
+	err := h.Tx.WithTx(r.Context(), func(ctx context.Context) error {
+		before, err := h.Flags.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		flag := before
+		if req.Enabled != nil {
+			flag.Enabled = *req.Enabled
+		}
+		if req.RolloutPercentage != nil {
+			flag.RolloutPercentage = *req.RolloutPercentage
+		}
+		if req.AllowedUserIDs != nil {
+			flag.AllowedUserIDs = *req.AllowedUserIDs
+		}
+		if req.Cities != nil {
+			flag.Cities = *req.Cities
+		}
+		flag.UpdatedBy = adminID
+
+		updated, err = h.Flags.Update(ctx, flag)
+		if err != nil {
+			return err
+		}
+		return h.Audit.Record(ctx, audit.Event{
+			ActorID:    adminID,
+			Action:     audit.ActionAdminFeatureFlagUpdated,
+			TargetType: audit.TargetFeatureFlag,
+			TargetID:   key,
+			Before:     before,
+			After:      updated,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, featureflags.ErrNotFound) {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeFeatureFlagNotFound, "feature flag not found")
+			return
+		}
+		slog.ErrorContext(r.Context(), audit.ActionAdminFeatureFlagUpdated+" failed", "admin_id", adminID, "flag", key, "error", err)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to update feature flag")
+		return
+	}
+
+	if h.Cache != nil {
+		if err := h.Cache.Refresh(r.Context()); err != nil {
+			slog.ErrorContext(r.Context(), "feature flag refresh failed", "error", err)
+		}
+	}
+	writeJSON(w, http.StatusOK, api.FeatureFlagResponse{Flag: updated})
+	slog.InfoContext(r.Context(), audit.ActionAdminFeatureFlagUpdated+" success", "admin_id", adminID, "flag", key, "enabled", updated.Enabled, "rollout_percentage", updated.RolloutPercentage)
+}
+
+// validateFlagRequest checks the fields that are set and normalizes the lists: user IDs
+// must be UUIDs, cities are trimmed, and duplicates are dropped.
+func validateFlagRequest(req *api.UpdateFeatureFlagRequest) error {
+	if req.RolloutPercentage != nil && (*req.RolloutPercentage < 0 || *req.RolloutPercentage > 100) {
+		return problem.Field("rollout_percentage", problem.FieldInvalid, "rollout_percentage must be between 0 and 100")
+	}
+	if req.AllowedUserIDs != nil {
+		if len(*req.AllowedUserIDs) > maxFlagAllowedUsers {
+			return problem.Field("allowed_user_ids", problem.FieldTooLong, "allowed_user_ids must not exceed %d users", maxFlagAllowedUsers)
+		}
+		ids := make([]string, 0, len(*req.AllowedUserIDs))
+		for _, raw := range *req.AllowedUserIDs {
+			id, err := uuid.Parse(strings.TrimSpace(raw))
+			if err != nil {
+				return problem.Field("allowed_user_ids", problem.FieldInvalid, "allowed_user_ids must contain user IDs")
+			}
+			ids = appendUnique(ids, id.String())
+		}
+		req.AllowedUserIDs = &ids
+	}
+	if req.Cities != nil {
+		if len(*req.Cities) > maxFlagCities {
+			return problem.Field("cities", problem.FieldTooLong, "cities must not exceed %d entries", maxFlagCities)
+		}
+		cities := make([]string, 0, len(*req.Cities))
+		for _, raw := range *req.Cities {
+			city := strings.TrimSpace(raw)
+			if city == "" || len(city) > maxFlagCityLength {
+				return problem.Field("cities", problem.FieldInvalid, "cities must be names of at most %d characters", maxFlagCityLength)
+			}
+			cities = appendUnique(cities, city)
+		}
+		req.Cities = &cities
+	}
+	return nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if strings.EqualFold(existing, value) {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,169 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDegraded    = "degraded"
+	StatusDisabled    = "disabled"
+	StatusShutdown    = "shutting_down"
+)
+
+// Check probes one dependency. A failing critical check makes the instance unready; a
+// failing optional check only reports the component as degraded.
+type Check struct {
+	Name     string
+	Critical bool
+	// Probe returns nil when the dependency is usable. A nil Probe reports the
+	// component as disabled.
+	Probe func(ctx context.Context) error
+}
+
+// ComponentStatus is what /readyz reports for a check. Probe errors are logged rather
+// than returned, since the endpoint is unauthenticated.
+type ComponentStatus struct {
+	Status string `json:"status"`
+}
+
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+// Checker serves /livez and /readyz. Checks run concurrently, each bounded by Timeout.
+type Checker struct {
+	Checks  []Check
+	Timeout time.Duration
+
+	shuttingDown atomic.Bool
+}
+
+// SetShuttingDown makes readiness fail from now on so load balancers stop routing new
+// requests to the instance while in-flight ones drain.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready runs every check and reports whether the instance should receive traffic.
+func (c *Checker) Ready(ctx context.Context) (Report, bool) {
+	if c.shuttingDown.Load() {
+		return Report{Status: StatusShutdown}, false
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make([]ComponentStatus, len(c.Checks))
+	var wg sync.WaitGroup
+	for i, check := range c.Checks {
+		if check.Probe == nil {
+			results[i] = ComponentStatus{Status: StatusDisabled}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			if err := check.Probe(ctx); err != nil {
+				status := StatusDegraded
+				if check.Critical {
+					status = StatusUnavailable
+				}
+				slog.WarnContext(ctx, "readiness check failed", "component", check.Name, "status", status, "error", err)
+				results[i] = ComponentStatus{Status: status}
+				return
+			}
+			results[i] = ComponentStatus{Status: StatusOK}
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Components: make(map[string]ComponentStatus, len(c.Checks))}
+	ready := true
+	for i, check := range c.Checks {
+		report.Components[check.Name] = results[i]
+		switch results[i].Status {
+		case StatusUnavailable:
+			ready = false
+			report.Status = StatusUnavailable
+		case StatusDegraded:
+			if ready {
+				report.Status = StatusDegraded
+			}
+		}
+	}
+	return report, ready
+}
+
+// Cached wraps probe so it runs at most once per ttl; calls in between get the last
+// result. It suits probes that are costly for the dependency, such as opening an SMTP
+// connection, which readiness probes every few seconds from every instance would
+// otherwise repeat.
+func Cached(probe func(ctx context.Context) error, ttl time.Duration) func(ctx context.Context) error {
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		last    error
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if checked.IsZero() || time.Since(checked) >= ttl {
+			last = probe(ctx)
+			checked = time.Now()
+		}
+		return last
+	}
+}
+
+// UntilOK wraps probe so it stops running once it has succeeded, for conditions that
+// cannot regress while the process runs, such as the schema being migrated.
+func UntilOK(probe func(ctx context.Context) error) func(ctx context.Context) error {
+	var ok atomic.Bool
+	return func(ctx context.Context) error {
+		if ok.Load() {
+			return nil
+		}
+		err := probe(ctx)
+		if err == nil {
+			ok.Store(true)
+		}
+		return err
+	}
+}
+
+// Livez reports that the process is up and serving HTTP. It does not touch
+// dependencies, so a database outage does not get healthy instances restarted.
+func (c *Checker) Livez(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+// Readyz answers 200 when every critical dependency is reachable and 503 otherwise,
+// with the status of each component.
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	report, ready := c.Ready(r.Context())
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,152 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func ok(context.Context) error { return nil }
+
+func failing(context.Context) error { return errors.New("connection refused") }
+
+func readyz(t *testing.T, checker *Checker) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	checker.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	return rec.Code, report
+}
+
+func TestReadyz(t *testing.T) {
+	tests := map[string]struct {
+		checks     []Check
+		wantCode   int
+		wantStatus string
+		wantSMTP   string
+	}{
+		"all ok": {
+			checks:     []Check{{Name: "database", Critical: true, Probe: ok}, {Name: "smtp", Probe: ok}},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOK,
+			wantSMTP:   StatusOK,
+		},
+		"optional failure degrades": {
+			checks:     []Check{{Name: "database", Critical: true, Probe: ok}, {Name: "smtp", Probe: failing}},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusDegraded,
+			wantSMTP:   StatusDegraded,
+		},
+		"critical failure is unready": {
+			checks:     []Check{{Name: "database", Critical: true, Probe: failing}, {Name: "smtp", Probe: failing}},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusUnavailable,
+			wantSMTP:   StatusDegraded,
+		},
+		"disabled component": {
+			checks:     []Check{{Name: "database", Critical: true, Probe: ok}, {Name: "smtp"}},
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOK,
+			wantSMTP:   StatusDisabled,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			code, report := readyz(t, &Checker{Checks: tc.checks})
+			if code != tc.wantCode || report.Status != tc.wantStatus {
+				t.Fatalf("got %d %q, want %d %q", code, report.Status, tc.wantCode, tc.wantStatus)
+			}
+			if got := report.Components["smtp"].Status; got != tc.wantSMTP {
+				t.Fatalf("smtp status = %q, want %q", got, tc.wantSMTP)
+			}
+		})
+	}
+}
+
+func TestReadyzTimesOutSlowChecks(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	checker := &Checker{Timeout: 20 * time.Millisecond, Checks: []Check{{Name: "database", Critical: true, Probe: slow}}}
+
+	code, report := readyz(t, checker)
+	if code != http.StatusServiceUnavailable || report.Components["database"].Status != StatusUnavailable {
+		t.Fatalf("expected a timed-out database check, got %d %+v", code, report)
+	}
+}
+
+func TestShuttingDown(t *testing.T) {
+	checker := &Checker{Checks: []Check{{Name: "database", Critical: true, Probe: ok}}}
+	checker.SetShuttingDown()
+
+	if code, report := readyz(t, checker); code != http.StatusServiceUnavailable || report.Status != StatusShutdown {
+		t.Fatalf("got %d %q, want 503 shutting_down", code, report.Status)
+	}
+
+	rec := httptest.NewRecorder()
+	checker.Livez(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("livez = %d during shutdown, want 200", rec.Code)
+	}
+}
+
+func TestCached(t *testing.T) {
+	calls := 0
+	var result error
+	probe := Cached(func(context.Context) error {
+		calls++
+		return result
+	}, time.Hour)
+
+	result = errors.New("connection refused")
+	for range 3 {
+		if err := probe(context.Background()); err == nil {
+			t.Fatal("cached probe lost the failure")
+		}
+	}
+	result = nil
+	if err := probe(context.Background()); err == nil || calls != 1 {
+		t.Fatalf("probe ran %d times within the TTL, err %v", calls, err)
+	}
+
+	expired := Cached(func(context.Context) error {
+		calls++
+		return nil
+	}, 0)
+	expired(context.Background())
+	expired(context.Background())
+	if calls != 3 {
+		t.Fatalf("probe ran %d times, want a run per call once the TTL has passed", calls)
+	}
+}
+
+func TestUntilOK(t *testing.T) {
+	calls := 0
+	probe := UntilOK(func(context.Context) error {
+		calls++
+		if calls < 2 {
+			return errors.New("2 migrations pending")
+		}
+		return nil
+	})
+	if err := probe(context.Background()); err == nil {
+		t.Fatal("first check passed")
+	}
+	for range 3 {
+		if err := probe(context.Background()); err != nil {
+			t.Fatalf("check after success: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("probe ran %d times, want 2", calls)
+	}
+}
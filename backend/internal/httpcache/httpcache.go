@@ -0,0 +1,120 @@
+// Package httpcache implements entity tags, conditional requests and the Cache-Control
+// policies of API responses.
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cache-Control values.
+const (
+	// Private suits responses about the signed-in user: the browser may keep them but
+	// must revalidate with the ETag before each use, and shared caches must not store
+	// them.
+	Private = "private, no-cache"
+	// Public suits anonymous data that changes rarely, such as the category tree.
+	Public = "public, max-age=60, stale-while-revalidate=300"
+	// NoStore suits responses that carry credentials.
+	NoStore = "no-store"
+)
+
+var (
+	// ErrPreconditionRequired means a write that needs If-Match did not send it.
+	ErrPreconditionRequired = errors.New("httpcache: If-Match required")
+	// ErrPreconditionFailed means If-Match does not match the current version.
+	ErrPreconditionFailed = errors.New("httpcache: resource has changed")
+)
+
+// ETag returns a strong entity tag for a row version. Every change to a row sets its
+// updated_at, so the pair identifies the version.
+func ETag(id string, updatedAt time.Time) string {
+	return tag([]byte(id + "\x00" + updatedAt.UTC().Format(time.RFC3339Nano)))
+}
+
+// ContentETag returns a strong entity tag for a response body, for resources without
+// an updated_at such as collections.
+func ContentETag(body []byte) string {
+	return tag(body)
+}
+
+// Derive returns a strong entity tag for a representation that adds extra data, such
+// as per-user settings, to the resource version etag. CheckIfMatch accepts tags derived
+// from its etag, so a write can send back either tag it read, and a change to the extra
+// data alone does not fail it.
+func Derive(etag string, extra []byte) string {
+	sum := sha256.Sum256(extra)
+	return strings.TrimSuffix(etag, `"`) + "." + base64.RawURLEncoding.EncodeToString(sum[:8]) + `"`
+}
+
+func tag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified sets etag and cacheControl on the response and reports whether the
+// client's copy is current according to If-None-Match. When it is, it has written 304
+// and the caller must not write a body.
+func NotModified(w http.ResponseWriter, r *http.Request, etag, cacheControl string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if !matches(r.Header.Get("If-None-Match"), etag, false) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// CheckIfMatch checks the If-Match header of a write against the current etag. Tags
+// are compared strongly, so a weak tag never matches; "*" matches any version, and a
+// tag derived from etag matches it.
+func CheckIfMatch(r *http.Request, etag string) error {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return ErrPreconditionRequired
+	}
+	if !matches(header, etag, true) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// matches reports whether the comma-separated tag list in header contains etag.
+func matches(header, etag string, strong bool) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak, ok := strings.CutPrefix(candidate, "W/"); ok {
+			if strong {
+				continue
+			}
+			candidate = weak
+		}
+		if candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+		if strong && derivedFrom(candidate, etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// derivedFrom reports whether candidate was made by Derive from etag.
+func derivedFrom(candidate, etag string) bool {
+	rest, ok := strings.CutPrefix(candidate, strings.TrimSuffix(etag, `"`)+".")
+	return ok && strings.HasSuffix(rest, `"`) && !strings.Contains(strings.TrimSuffix(rest, `"`), ".")
+}
@@ -0,0 +1,106 @@
+package httpcache
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestETag(t *testing.T) {
+	at := time.Date(2026, 3, 1, 10, 0, 0, 123456000, time.UTC)
+	tag := ETag("id-1", at)
+	if tag != ETag("id-1", at.In(time.FixedZone("IST", 19800))) {
+		t.Error("ETag depends on the time zone")
+	}
+	if tag == ETag("id-1", at.Add(time.Microsecond)) || tag == ETag("id-2", at) {
+		t.Error("ETag did not change with the version")
+	}
+	if tag[0] != '"' || tag[len(tag)-1] != '"' {
+		t.Errorf("ETag %s is not a quoted strong tag", tag)
+	}
+}
+
+func TestDerive(t *testing.T) {
+	const etag = `"v1"`
+	derived := Derive(etag, []byte("a"))
+	if derived == etag || derived == Derive(etag, []byte("b")) || derived != Derive(etag, []byte("a")) {
+		t.Errorf("Derive(%s) = %s did not follow the extra data", etag, derived)
+	}
+	if derived[0] != '"' || derived[len(derived)-1] != '"' {
+		t.Errorf("derived tag %s is not a quoted strong tag", derived)
+	}
+	// A conditional GET compares whole tags: the base version alone is not current.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	if NotModified(httptest.NewRecorder(), req, derived, Private) {
+		t.Error("base tag matched a derived tag in If-None-Match")
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	const etag = `"v1"`
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		want        bool
+	}{
+		{"no header", http.MethodGet, "", false},
+		{"match", http.MethodGet, `"v1"`, true},
+		{"weak match", http.MethodGet, `W/"v1"`, true},
+		{"in list", http.MethodGet, `"v0", "v1"`, true},
+		{"star", http.MethodGet, "*", true},
+		{"stale", http.MethodGet, `"v0"`, false},
+		{"unsafe method", http.MethodPatch, `"v1"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			got := NotModified(rec, req, etag, Private)
+			if got != tt.want {
+				t.Fatalf("NotModified = %v, want %v", got, tt.want)
+			}
+			if got && rec.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want 304", rec.Code)
+			}
+			if rec.Header().Get("ETag") != etag || rec.Header().Get("Cache-Control") != Private {
+				t.Errorf("headers = %v", rec.Header())
+			}
+		})
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	const etag = `"v1"`
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    error
+	}{
+		{"missing", "", ErrPreconditionRequired},
+		{"match", `"v1"`, nil},
+		{"in list", `"v0","v1"`, nil},
+		{"star", "*", nil},
+		{"stale", `"v0"`, ErrPreconditionFailed},
+		{"weak", `W/"v1"`, ErrPreconditionFailed},
+		{"derived", Derive(etag, []byte("flags")), nil},
+		{"derived from stale", Derive(`"v0"`, []byte("flags")), ErrPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			if err := CheckIfMatch(req, etag); !errors.Is(err, tt.want) {
+				t.Fatalf("CheckIfMatch = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
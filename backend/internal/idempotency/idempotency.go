@@ -0,0 +1,250 @@
+// Package idempotency lets clients retry unsafe requests safely. A request that carries
+// an Idempotency-Key header runs once; retries with the same key and body get the
+// stored response back instead of repeating the side effects.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"resellution/backend/internal/clientip"
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/problem"
+)
+
+const (
+	// Header carries the client's key for a request.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set to "true" on stored responses.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Middleware honours Idempotency-Key on next. Keys are scoped to the signed-in user, so
+// it must be wrapped by the auth middleware where there is one. A repeated key replays
+// the stored response; one still running gets 409 and one used with a different body
+// gets 422.
+//
+// Stored bodies are encrypted with a key derived from the Idempotency-Key and the
+// request body, neither of which is stored, so a response carrying a token can only be
+// replayed to a client that sends the same key and body, which for sign-up includes the
+// password. Clients behind a shared NAT have the same IP, so this is what keeps them
+// from reading each other's responses on anonymous routes.
+//
+// Server errors and 429s are not stored: they release the key so the retry runs again.
+func Middleware(store Store, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if !validKey(key) {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeIdempotencyKeyInvalid, "Idempotency-Key must be 1 to 255 printable ASCII characters")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			problem.BodyError(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		canonical := canonicalBody(body)
+		scope := requestScope(r)
+		stored, err := store.Begin(ctx, scope, key, fingerprint(r, canonical))
+		switch {
+		case errors.Is(err, ErrInProgress):
+			w.Header().Set("Retry-After", "1")
+			problem.Error(w, r, http.StatusConflict, problem.CodeIdempotencyInProgress, "a request with this Idempotency-Key is still in progress")
+			return
+		case errors.Is(err, ErrKeyReused):
+			problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused, "this Idempotency-Key was used for a different request")
+			return
+		case err != nil:
+			slog.ErrorContext(ctx, "idempotency key lookup failed", "error", err)
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to process request")
+			return
+		case stored != nil:
+			plain, err := openBody(key, canonical, stored.Body)
+			if err != nil {
+				slog.ErrorContext(ctx, "idempotency response decryption failed", "error", err)
+				problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to process request")
+				return
+			}
+			stored.Body = plain
+			replay(w, *stored)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, before: w.Header().Clone()}
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// Also runs while a panic unwinds, so the key is not left claimed.
+			if err := store.Release(context.WithoutCancel(ctx), scope, key); err != nil {
+				slog.WarnContext(ctx, "idempotency key release failed", "error", err)
+			}
+		}()
+		next(rec, r)
+
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError || rec.status == http.StatusTooManyRequests {
+			return
+		}
+		sealed, err := sealBody(key, canonical, rec.body.Bytes())
+		if err != nil {
+			slog.WarnContext(ctx, "idempotency response encryption failed", "error", err)
+			return
+		}
+		resp := Response{Status: rec.status, Header: rec.header, Body: sealed}
+		if err := store.Complete(context.WithoutCancel(ctx), scope, key, resp); err != nil {
+			slog.WarnContext(ctx, "idempotency key completion failed", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+func replay(w http.ResponseWriter, resp Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+func validKey(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestScope namespaces keys: by user when signed in, otherwise by client IP.
+func requestScope(r *http.Request) string {
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+		return "user:" + userID
+	}
+	return "ip:" + clientip.FromRequest(r)
+}
+
+// canonicalBody re-encodes JSON bodies so a retry that encodes the same value
+// differently still matches. Other bodies are used as they are.
+func canonicalBody(body []byte) []byte {
+	var value any
+	if err := json.Unmarshal(body, &value); err == nil {
+		if canonical, err := json.Marshal(value); err == nil {
+			return canonical
+		}
+	}
+	return body
+}
+
+// fingerprint identifies the request a key was first used for.
+func fingerprint(r *http.Request, canonical []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bodyKey derives the key stored bodies are encrypted with. It is a separate hash from
+// the fingerprint, so the stored fingerprint does not reveal it.
+func bodyKey(key string, canonical []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte("idempotency response\x00" + key + "\x00"))
+	h.Write(canonical)
+	return h.Sum(nil)
+}
+
+func bodyAEAD(key string, canonical []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(bodyKey(key, canonical))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealBody encrypts a response body for storage as nonce || ciphertext.
+func sealBody(key string, canonical, body []byte) ([]byte, error) {
+	aead, err := bodyAEAD(key, canonical)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, body, nil), nil
+}
+
+func openBody(key string, canonical, sealed []byte) ([]byte, error) {
+	aead, err := bodyAEAD(key, canonical)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("idempotency: stored body too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// recorder passes the response through and keeps a copy of it. Only the headers the
+// handler set are kept; those set by outer middleware, such as the request ID, belong to
+// each attempt.
+type recorder struct {
+	http.ResponseWriter
+	before http.Header
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.header = make(http.Header)
+		for name, values := range rec.Header() {
+			if !slices.Equal(values, rec.before[name]) {
+				rec.header[name] = slices.Clone(values)
+			}
+		}
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,249 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/problem"
+	"resellution/backend/internal/utils"
+)
+
+// testStores returns the memory store and, when TEST_DATABASE_URL is set, a Postgres
+// store against a migrated database.
+func testStores(t *testing.T) map[string]Store {
+	stores := map[string]Store{"memory": NewMemoryStore()}
+
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		return stores
+	}
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	stores["postgres"] = PostgresStore{DB: db}
+	return stores
+}
+
+func send(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func problemCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var p problem.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode problem: %v (%s)", err, rec.Body)
+	}
+	return p.Code
+}
+
+func TestMiddleware(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			var calls atomic.Int32
+			status := http.StatusCreated
+			handler := Middleware(store, func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				body, _ := io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Location", "/users/1")
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{"call":` + strconv.Itoa(int(n)) + `,"body":` + string(body) + `}`))
+			})
+			// Headers set by outer middleware belong to each attempt and are not stored.
+			tokens := utils.NewTokenManager("test-secret")
+			token, err := tokens.Create(uuid.NewString(), time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			authed := middleware.Auth(tokens, nil, handler)
+			outer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-ID", uuid.NewString())
+				r.Header.Set("Authorization", "Bearer "+token)
+				authed(w, r)
+			})
+
+			key := uuid.NewString()
+			first := send(outer, key, `{"email":"a@example.com","name":"A"}`)
+			if first.Code != http.StatusCreated || first.Header().Get(ReplayedHeader) != "" {
+				t.Fatalf("first: %d %v", first.Code, first.Header())
+			}
+
+			retry := send(outer, key, `{"name": "A", "email": "a@example.com"}`)
+			if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+				t.Fatalf("retry: %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+			}
+			if retry.Header().Get(ReplayedHeader) != "true" || retry.Header().Get("Location") != "/users/1" {
+				t.Errorf("retry headers = %v", retry.Header())
+			}
+			if retry.Header().Get("X-Request-ID") == first.Header().Get("X-Request-ID") {
+				t.Error("retry replayed the first request ID")
+			}
+
+			reused := send(outer, key, `{"email":"b@example.com","name":"B"}`)
+			if reused.Code != http.StatusUnprocessableEntity || problemCode(t, reused) != problem.CodeIdempotencyKeyReused {
+				t.Fatalf("reused key: %d %s", reused.Code, reused.Body)
+			}
+
+			if send(outer, "", `{}`).Code != http.StatusCreated || send(outer, "", `{}`).Code != http.StatusCreated {
+				t.Fatal("requests without a key were not passed through")
+			}
+			if got := calls.Load(); got != 3 {
+				t.Errorf("handler ran %d times, want 3", got)
+			}
+
+			// Server errors are not stored, so the retry runs again.
+			status = http.StatusServiceUnavailable
+			key = uuid.NewString()
+			send(outer, key, `{}`)
+			status = http.StatusCreated
+			if rec := send(outer, key, `{}`); rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "" {
+				t.Fatalf("retry after server error: %d %v", rec.Code, rec.Header())
+			}
+		})
+	}
+}
+
+func TestAnonymousKeys(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := Middleware(store, func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"token":"secret-token"}`))
+			})
+
+			// Anonymous keys are scoped to the client IP, so reusing one with another
+			// body is rejected rather than run as a new request.
+			key := uuid.NewString()
+			send(handler, key, `{"email":"a@example.com","password":"Password123"}`)
+			reused := send(handler, key, `{"email":"b@example.com","password":"guess"}`)
+			if reused.Code != http.StatusUnprocessableEntity || problemCode(t, reused) != problem.CodeIdempotencyKeyReused {
+				t.Fatalf("reused key: %d %s", reused.Code, reused.Body)
+			}
+			retry := send(handler, key, `{"email":"a@example.com","password":"Password123"}`)
+			if retry.Header().Get(ReplayedHeader) != "true" || retry.Body.String() != `{"token":"secret-token"}` {
+				t.Fatalf("retry: %v %s", retry.Header(), retry.Body)
+			}
+			if got := calls.Load(); got != 1 {
+				t.Errorf("handler ran %d times, want 1", got)
+			}
+		})
+	}
+}
+
+func TestStoredBodiesAreEncrypted(t *testing.T) {
+	store := NewMemoryStore()
+	handler := Middleware(store, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"token":"secret-token"}`))
+	})
+	send(handler, uuid.NewString(), `{"password":"Password123"}`)
+
+	for _, e := range store.entries {
+		if e.response == nil || strings.Contains(string(e.response.Body), "secret-token") {
+			t.Fatalf("stored body = %q, want ciphertext", e.response.Body)
+		}
+	}
+}
+
+func TestMiddlewareConcurrentDuplicate(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			started, release := make(chan struct{}), make(chan struct{})
+			handler := Middleware(store, func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			key := uuid.NewString()
+			done := make(chan *httptest.ResponseRecorder)
+			go func() { done <- send(handler, key, `{}`) }()
+			<-started
+
+			duplicate := send(handler, key, `{}`)
+			if duplicate.Code != http.StatusConflict || problemCode(t, duplicate) != problem.CodeIdempotencyInProgress {
+				t.Fatalf("duplicate: %d %s", duplicate.Code, duplicate.Body)
+			}
+			if duplicate.Header().Get("Retry-After") == "" {
+				t.Error("duplicate has no Retry-After")
+			}
+
+			close(release)
+			if first := <-done; first.Code != http.StatusCreated {
+				t.Fatalf("first: %d", first.Code)
+			}
+			if rec := send(handler, key, `{}`); rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "true" {
+				t.Fatalf("retry: %d %v", rec.Code, rec.Header())
+			}
+		})
+	}
+}
+
+func TestMiddlewareReleasesOnPanic(t *testing.T) {
+	store := NewMemoryStore()
+	panics := true
+	handler := Middleware(store, func(w http.ResponseWriter, r *http.Request) {
+		if panics {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	key := uuid.NewString()
+	func() {
+		defer func() { _ = recover() }()
+		send(handler, key, `{}`)
+	}()
+	panics = false
+	if rec := send(handler, key, `{}`); rec.Code != http.StatusCreated {
+		t.Fatalf("retry after panic: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestMiddlewareInvalidKey(t *testing.T) {
+	handler := Middleware(NewMemoryStore(), func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler ran for an invalid key")
+	})
+	for _, key := range []string{"has space", strings.Repeat("k", 256), "café"} {
+		rec := send(handler, key, `{}`)
+		if rec.Code != http.StatusBadRequest || problemCode(t, rec) != problem.CodeIdempotencyKeyInvalid {
+			t.Errorf("key %q: %d %s", key, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestKeysAreScoped(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	if _, err := store.Begin(ctx, "user:a", "key", "fp"); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := store.Begin(ctx, "user:b", "key", "other"); err != nil || resp != nil {
+		t.Fatalf("another scope: %v %v, want a fresh claim", resp, err)
+	}
+}
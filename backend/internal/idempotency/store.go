@@ -0,0 +1,207 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// Retention is how long a completed response is replayed for its key.
+	Retention = 24 * time.Hour
+	// lockTimeout bounds how long a claim blocks retries, so a key whose request died
+	// with its instance can be used again. It exceeds the server's write timeout.
+	lockTimeout = time.Minute
+)
+
+var (
+	// ErrInProgress means another request with the same key has not finished.
+	ErrInProgress = errors.New("idempotency: request in progress")
+	// ErrKeyReused means the key was used for a different request.
+	ErrKeyReused = errors.New("idempotency: key reused with a different request")
+)
+
+// Response is a stored response: its status, the headers the handler set and the body,
+// which Middleware encrypts before storing.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Store persists idempotency keys. Keys are unique within a scope, normally the user.
+type Store interface {
+	// Begin claims key for a request with the given fingerprint. It returns the stored
+	// response when the key already completed for the same fingerprint, ErrInProgress
+	// while another request holds the claim and ErrKeyReused when the key was used for
+	// a different request. Expired keys and abandoned claims are claimed afresh.
+	Begin(ctx context.Context, scope, key, fingerprint string) (*Response, error)
+	// Complete stores the response for a claimed key.
+	Complete(ctx context.Context, scope, key string, resp Response) error
+	// Release drops a claim that has no response, so the request can be retried.
+	Release(ctx context.Context, scope, key string) error
+}
+
+type memoryEntry struct {
+	fingerprint string
+	response    *Response
+	lockedUntil time.Time
+	expiresAt   time.Time
+}
+
+// MemoryStore keeps keys in process memory. Keys are per instance and are lost on
+// restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[[2]string]*memoryEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[[2]string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, scope, key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e, ok := s.entries[[2]string{scope, key}]
+	if ok && now.Before(e.expiresAt) && (e.response != nil || now.Before(e.lockedUntil)) {
+		return existing(e.fingerprint, e.response, fingerprint)
+	}
+	s.entries[[2]string{scope, key}] = &memoryEntry{
+		fingerprint: fingerprint,
+		lockedUntil: now.Add(lockTimeout),
+		expiresAt:   now.Add(Retention),
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, scope, key string, resp Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[[2]string{scope, key}]; ok && e.response == nil {
+		resp.Header = resp.Header.Clone()
+		e.response = &resp
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[[2]string{scope, key}]; ok && e.response == nil {
+		delete(s.entries, [2]string{scope, key})
+	}
+	return nil
+}
+
+// PostgresStore keeps keys in the idempotency_keys table so a retry that lands on
+// another instance still finds them.
+type PostgresStore struct {
+	DB *sql.DB
+}
+
+func (s PostgresStore) Begin(ctx context.Context, scope, key, fingerprint string) (*Response, error) {
+	// The insert claims a new key, or takes over one that expired or was abandoned; it
+	// returns no row when the key is held, and the select below explains why.
+	claim := `
+		INSERT INTO idempotency_keys (scope, key, fingerprint, locked_until, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 millisecond', NOW() + $5 * INTERVAL '1 millisecond')
+		ON CONFLICT (scope, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint,
+			status = NULL,
+			header = NULL,
+			body = NULL,
+			locked_until = EXCLUDED.locked_until,
+			created_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+			OR (idempotency_keys.status IS NULL AND idempotency_keys.locked_until <= NOW())
+		RETURNING true
+	`
+	var claimed bool
+	err := s.DB.QueryRowContext(ctx, claim, scope, key, fingerprint, lockTimeout.Milliseconds(), Retention.Milliseconds()).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	query := `
+		SELECT fingerprint, status, header, body
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+	`
+	var (
+		storedFingerprint string
+		status            sql.NullInt64
+		header            []byte
+		body              []byte
+	)
+	err = s.DB.QueryRowContext(ctx, query, scope, key).Scan(&storedFingerprint, &status, &header, &body)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released between the two statements; the retry will claim it.
+		return nil, ErrInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !status.Valid {
+		return existing(storedFingerprint, nil, fingerprint)
+	}
+	resp := &Response{Status: int(status.Int64), Body: body}
+	if len(header) > 0 {
+		if err := json.Unmarshal(header, &resp.Header); err != nil {
+			return nil, err
+		}
+	}
+	return existing(storedFingerprint, resp, fingerprint)
+}
+
+func (s PostgresStore) Complete(ctx context.Context, scope, key string, resp Response) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE idempotency_keys
+		SET status = $3, header = $4, body = $5
+		WHERE scope = $1 AND key = $2 AND status IS NULL
+	`
+	_, err = s.DB.ExecContext(ctx, query, scope, key, resp.Status, header, resp.Body)
+	return err
+}
+
+func (s PostgresStore) Release(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status IS NULL`
+	_, err := s.DB.ExecContext(ctx, query, scope, key)
+	return err
+}
+
+// Purge deletes keys that expired before cutoff and returns how many were removed.
+func (s PostgresStore) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// existing resolves a Begin call that found a live key.
+func existing(storedFingerprint string, resp *Response, fingerprint string) (*Response, error) {
+	if storedFingerprint != fingerprint {
+		return nil, ErrKeyReused
+	}
+	if resp == nil {
+		return nil, ErrInProgress
+	}
+	return resp, nil
+}
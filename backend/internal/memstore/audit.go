@@ -0,0 +1,99 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/clientip"
+	"resellution/backend/internal/middleware"
+	"resellution/backend/internal/observability"
+)
+
+// AuditLog keeps audit events with the store, so they are rolled back with the changes
+// they describe.
+type AuditLog struct {
+	s *Store
+}
+
+func (a AuditLog) Record(ctx context.Context, event audit.Event) error {
+	if event.ActorID == "" {
+		event.ActorID, _ = middleware.UserIDFromContext(ctx)
+	}
+	requestID, _ := observability.RequestIDFromContext(ctx)
+	ipAddress, _ := clientip.FromContext(ctx)
+
+	changes, err := audit.Diff(event.Before, event.After)
+	if err != nil {
+		return err
+	}
+
+	return a.s.update(func(t *tables) error {
+		t.nextAuditID++
+		t.auditEvents = append(t.auditEvents, audit.StoredEvent{
+			ID:         t.nextAuditID,
+			OccurredAt: now(),
+			ActorID:    event.ActorID,
+			Action:     event.Action,
+			TargetType: event.TargetType,
+			TargetID:   event.TargetID,
+			IPAddress:  ipAddress,
+			RequestID:  requestID,
+			Changes:    changes,
+			Metadata:   event.Metadata,
+		})
+		return nil
+	})
+}
+
+func (a AuditLog) Query(_ context.Context, filter audit.Filter) ([]audit.StoredEvent, int, error) {
+	var matches []audit.StoredEvent
+	a.s.read(func(t *tables) {
+		for _, event := range t.auditEvents {
+			if filter.ActorID != "" && event.ActorID != filter.ActorID {
+				continue
+			}
+			if filter.Action != "" && event.Action != filter.Action {
+				continue
+			}
+			if filter.TargetType != "" && event.TargetType != filter.TargetType {
+				continue
+			}
+			if filter.TargetID != "" && event.TargetID != filter.TargetID {
+				continue
+			}
+			if !filter.From.IsZero() && event.OccurredAt.Before(filter.From) {
+				continue
+			}
+			if !filter.To.IsZero() && !event.OccurredAt.Before(filter.To) {
+				continue
+			}
+			matches = append(matches, event)
+		}
+	})
+
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].OccurredAt.Equal(matches[j].OccurredAt) {
+			return matches[i].OccurredAt.After(matches[j].OccurredAt)
+		}
+		return matches[i].ID > matches[j].ID
+	})
+
+	events := []audit.StoredEvent{}
+	for i := filter.Offset; i < len(matches) && len(events) < filter.Limit; i++ {
+		events = append(events, matches[i])
+	}
+	if len(events) == 0 {
+		return events, 0, nil
+	}
+	return events, len(matches), nil
+}
+
+// Events returns every recorded event in the order they were recorded.
+func (a AuditLog) Events() []audit.StoredEvent {
+	var events []audit.StoredEvent
+	a.s.read(func(t *tables) {
+		events = append(events, t.auditEvents...)
+	})
+	return events
+}
@@ -0,0 +1,116 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"resellution/backend/internal/models"
+)
+
+// Categories implements models.CategoryRepository.
+type Categories struct {
+	s *Store
+}
+
+var _ models.CategoryRepository = Categories{}
+
+func (r Categories) List(_ context.Context) ([]models.Category, error) {
+	categories := []models.Category{}
+	r.s.read(func(t *tables) {
+		for _, category := range t.categories {
+			categories = append(categories, category)
+		}
+	})
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+	return categories, nil
+}
+
+func (r Categories) FindByID(_ context.Context, id string) (models.Category, error) {
+	var category models.Category
+	var ok bool
+	r.s.read(func(t *tables) {
+		category, ok = t.categories[id]
+	})
+	if !ok {
+		return models.Category{}, models.ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+func (r Categories) Create(_ context.Context, category models.Category) (models.Category, error) {
+	err := r.s.update(func(t *tables) error {
+		if _, exists := t.categories[category.ID]; exists {
+			return models.ErrCategoryConflict
+		}
+		if err := checkCategory(t, category); err != nil {
+			return err
+		}
+		category.CreatedAt = now()
+		t.categories[category.ID] = category
+		return nil
+	})
+	if err != nil {
+		return models.Category{}, err
+	}
+	return category, nil
+}
+
+func (r Categories) Update(_ context.Context, category models.Category) (models.Category, error) {
+	err := r.s.update(func(t *tables) error {
+		existing, ok := t.categories[category.ID]
+		if !ok {
+			return models.ErrCategoryNotFound
+		}
+		if err := checkCategory(t, category); err != nil {
+			return err
+		}
+		category.CreatedAt = existing.CreatedAt
+		t.categories[category.ID] = category
+		return nil
+	})
+	if err != nil {
+		return models.Category{}, err
+	}
+	return category, nil
+}
+
+// Delete removes the category and clears references to it, like the ON DELETE SET NULL
+// foreign keys.
+func (r Categories) Delete(_ context.Context, id string) error {
+	return r.s.update(func(t *tables) error {
+		if _, ok := t.categories[id]; !ok {
+			return models.ErrCategoryNotFound
+		}
+		delete(t.categories, id)
+		for childID, child := range t.categories {
+			if child.ParentID == id {
+				child.ParentID = ""
+				t.categories[childID] = child
+			}
+		}
+		for listingID, listing := range t.listings {
+			if listing.CategoryID == id {
+				listing.CategoryID = ""
+				t.listings[listingID] = listing
+			}
+		}
+		return nil
+	})
+}
+
+// checkCategory enforces the unique name and slug and the parent foreign key.
+func checkCategory(t *tables, category models.Category) error {
+	for id, other := range t.categories {
+		if id != category.ID && (other.Name == category.Name || other.Slug == category.Slug) {
+			return models.ErrCategoryConflict
+		}
+	}
+	if category.ParentID != "" {
+		if _, ok := t.categories[category.ParentID]; !ok {
+			return models.ErrCategoryParentNotFound
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"resellution/backend/internal/models"
+)
+
+// Listings implements models.ListingRepository.
+type Listings struct {
+	s *Store
+}
+
+var _ models.ListingRepository = Listings{}
+
+var listingConditions = map[string]bool{"new": true, "like_new": true, "good": true, "fair": true, "poor": true}
+
+var listingStatuses = map[string]bool{
+	models.ListingStatusActive:   true,
+	models.ListingStatusReserved: true,
+	models.ListingStatusSold:     true,
+}
+
+func (r Listings) Create(_ context.Context, listing models.Listing) (models.Listing, error) {
+	err := r.s.update(func(t *tables) error {
+		if _, exists := t.listings[listing.ID]; exists {
+			return constraintError("duplicate listing id %s", listing.ID)
+		}
+		if err := checkListing(t, listing); err != nil {
+			return err
+		}
+
+		if listing.Currency == "" {
+			listing.Currency = "INR"
+		}
+		listing.Status = models.ListingStatusActive
+		listing.ViewCount = 0
+		listing.DeletedAt = nil
+		listing.CreatedAt = now()
+		listing.UpdatedAt = listing.CreatedAt
+		listing.UpdatedBy = ""
+		t.listings[listing.ID] = listing
+		return nil
+	})
+	if err != nil {
+		return models.Listing{}, err
+	}
+	return listing, nil
+}
+
+func (r Listings) FindByID(_ context.Context, id string) (models.Listing, error) {
+	var listing models.Listing
+	var ok bool
+	r.s.read(func(t *tables) {
+		listing, ok = t.listings[id]
+	})
+	if !ok || listing.DeletedAt != nil {
+		return models.Listing{}, models.ErrListingNotFound
+	}
+	return listing, nil
+}
+
+func (r Listings) ListBySeller(_ context.Context, sellerID string) ([]models.Listing, error) {
+	listings := []models.Listing{}
+	r.s.read(func(t *tables) {
+		for _, listing := range t.listings {
+			if listing.SellerID == sellerID && listing.DeletedAt == nil {
+				listings = append(listings, listing)
+			}
+		}
+	})
+	sort.Slice(listings, func(i, j int) bool {
+		if !listings[i].CreatedAt.Equal(listings[j].CreatedAt) {
+			return listings[i].CreatedAt.After(listings[j].CreatedAt)
+		}
+		return listings[i].ID < listings[j].ID
+	})
+	return listings, nil
+}
+
+func (r Listings) Update(_ context.Context, listing models.Listing, updatedBy string) (models.Listing, error) {
+	var updated models.Listing
+	err := r.s.update(func(t *tables) error {
+		existing, ok := t.listings[listing.ID]
+		if !ok || existing.DeletedAt != nil {
+			return models.ErrListingNotFound
+		}
+		listing.SellerID = existing.SellerID
+		if err := checkListing(t, listing); err != nil {
+			return err
+		}
+		if !listingStatuses[listing.Status] {
+			return constraintError("invalid listing status %q", listing.Status)
+		}
+
+		updated = existing
+		updated.CategoryID = listing.CategoryID
+		updated.Title = listing.Title
+		updated.Description = listing.Description
+		updated.Condition = listing.Condition
+		updated.Price = listing.Price
+		updated.City = listing.City
+		updated.State = listing.State
+		updated.Status = listing.Status
+		updated.UpdatedAt = now()
+		updated.UpdatedBy = updatedBy
+		t.listings[listing.ID] = updated
+		return nil
+	})
+	if err != nil {
+		return models.Listing{}, err
+	}
+	return updated, nil
+}
+
+func (r Listings) DeleteByID(_ context.Context, id, updatedBy string) error {
+	return r.s.update(func(t *tables) error {
+		listing, ok := t.listings[id]
+		if !ok || listing.DeletedAt != nil {
+			return models.ErrListingNotFound
+		}
+		current := now()
+		listing.Status = models.ListingStatusDeleted
+		listing.DeletedAt = &current
+		listing.UpdatedAt = current
+		listing.UpdatedBy = updatedBy
+		t.listings[id] = listing
+		return nil
+	})
+}
+
+// checkListing enforces the listings CHECK constraints and foreign keys.
+func checkListing(t *tables, listing models.Listing) error {
+	if _, ok := t.users[listing.SellerID]; !ok {
+		return constraintError("listing references missing seller %s", listing.SellerID)
+	}
+	if listing.CategoryID != "" {
+		if _, ok := t.categories[listing.CategoryID]; !ok {
+			return constraintError("listing references missing category %s", listing.CategoryID)
+		}
+	}
+	if !listingConditions[listing.Condition] {
+		return constraintError("invalid listing condition %q", listing.Condition)
+	}
+	if listing.Price < 0 {
+		return constraintError("listing price must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Package memstore is an in-memory implementation of the models repositories, used to
+// test handlers without Postgres. It follows the SQL semantics of the Postgres stores
+// (soft deletes, unique and foreign key constraints, expiry) closely enough to pass the
+// shared conformance suite in internal/models/repotest.
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"resellution/backend/internal/audit"
+	"resellution/backend/internal/models"
+)
+
+// ErrConstraint is returned where Postgres would reject a write with a constraint
+// violation that the stores do not map to a sentinel error.
+var ErrConstraint = errors.New("memstore: constraint violation")
+
+func constraintError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrConstraint, fmt.Sprintf(format, args...))
+}
+
+// Store holds every table. Use the accessor methods to get repositories that share it.
+type Store struct {
+	mu   sync.Mutex
+	txMu sync.Mutex
+	data tables
+}
+
+type tables struct {
+	users           map[string]models.User
+	resetTokens     []resetToken
+	resetOTPs       []resetOTP
+	sessions        map[string]session
+	categories      map[string]models.Category
+	listings        map[string]models.Listing
+	rolePermissions map[string][]string
+	auditEvents     []audit.StoredEvent
+	nextAuditID     int64
+}
+
+func (t tables) clone() tables {
+	return tables{
+		users:           maps.Clone(t.users),
+		resetTokens:     slices.Clone(t.resetTokens),
+		resetOTPs:       slices.Clone(t.resetOTPs),
+		sessions:        maps.Clone(t.sessions),
+		categories:      maps.Clone(t.categories),
+		listings:        maps.Clone(t.listings),
+		rolePermissions: t.rolePermissions,
+		auditEvents:     slices.Clone(t.auditEvents),
+		nextAuditID:     t.nextAuditID,
+	}
+}
+
+// New returns an empty store with the roles and permissions seeded by the migrations.
+func New() *Store {
+	return &Store{data: tables{
+		users:      make(map[string]models.User),
+		sessions:   make(map[string]session),
+		categories: make(map[string]models.Category),
+		listings:   make(map[string]models.Listing),
+		rolePermissions: map[string][]string{
+			models.RoleUser: nil,
+			models.RoleModerator: {
+				models.PermissionListingsModerate,
+				models.PermissionUsersModerate,
+				models.PermissionUsersRead,
+			},
+			models.RoleAdmin: {
+				models.PermissionAuditRead,
+				models.PermissionCategoriesManage,
+				models.PermissionFeatureFlagsManage,
+				models.PermissionListingsModerate,
+				models.PermissionRolesManage,
+				models.PermissionUsersModerate,
+				models.PermissionUsersRead,
+			},
+		},
+	}}
+}
+
+func (s *Store) Users() Users           { return Users{s} }
+func (s *Store) Sessions() Sessions     { return Sessions{s} }
+func (s *Store) Categories() Categories { return Categories{s} }
+func (s *Store) Listings() Listings     { return Listings{s} }
+func (s *Store) Audit() AuditLog        { return AuditLog{s} }
+
+type txContextKey struct{}
+
+// WithTx runs fn and restores the tables to their state before fn when it returns an
+// error. Transactions are serialized with each other but not isolated from writes made
+// outside a transaction, which a rollback also discards. Nested calls join the outer
+// transaction, as with db.TxManager.
+func (s *Store) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txContextKey{}) == s {
+		return fn(ctx)
+	}
+
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	snapshot := s.data.clone()
+	s.mu.Unlock()
+
+	if err := fn(context.WithValue(ctx, txContextKey{}, s)); err != nil {
+		s.mu.Lock()
+		s.data = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// update runs fn with the tables locked.
+func (s *Store) update(fn func(t *tables) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(&s.data)
+}
+
+func (s *Store) read(fn func(t *tables)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.data)
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,21 @@
+package memstore
+
+import (
+	"testing"
+
+	"resellution/backend/internal/models/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		store := New()
+		return repotest.Repositories{
+			Users:          store.Users(),
+			PasswordResets: store.Users(),
+			Sessions:       store.Sessions(),
+			Categories:     store.Categories(),
+			Listings:       store.Listings(),
+			Tx:             store,
+		}
+	})
+}
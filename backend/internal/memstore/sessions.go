@@ -0,0 +1,75 @@
+package memstore
+
+import (
+	"context"
+	"time"
+
+	"resellution/backend/internal/models"
+)
+
+// Sessions implements models.SessionRepository.
+type Sessions struct {
+	s *Store
+}
+
+var _ models.SessionRepository = Sessions{}
+
+type session struct {
+	models.Session
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (r Sessions) Create(_ context.Context, sess models.Session) error {
+	return r.s.update(func(t *tables) error {
+		if _, ok := t.users[sess.UserID]; !ok {
+			return constraintError("session references missing user %s", sess.UserID)
+		}
+		if _, exists := t.sessions[sess.ID]; exists {
+			return constraintError("duplicate session id %s", sess.ID)
+		}
+		for _, existing := range t.sessions {
+			if existing.TokenFingerprint == sess.TokenFingerprint {
+				return constraintError("duplicate session token fingerprint")
+			}
+		}
+		t.sessions[sess.ID] = session{Session: sess, CreatedAt: now()}
+		return nil
+	})
+}
+
+func (r Sessions) IsActive(_ context.Context, id string) (bool, error) {
+	var active bool
+	r.s.read(func(t *tables) {
+		sess, ok := t.sessions[id]
+		active = ok && sess.RevokedAt == nil && sess.ExpiresAt.After(now())
+	})
+	return active, nil
+}
+
+func (r Sessions) Revoke(_ context.Context, id string) error {
+	return r.s.update(func(t *tables) error {
+		if sess, ok := t.sessions[id]; ok && sess.RevokedAt == nil {
+			sess.RevokedAt = ptr(now())
+			t.sessions[id] = sess
+		}
+		return nil
+	})
+}
+
+func (r Sessions) RevokeAllByUserID(_ context.Context, userID string) (int64, error) {
+	var revoked int64
+	err := r.s.update(func(t *tables) error {
+		current := now()
+		for id, sess := range t.sessions {
+			if sess.UserID != userID || sess.RevokedAt != nil || !sess.ExpiresAt.After(current) {
+				continue
+			}
+			sess.RevokedAt = ptr(current)
+			t.sessions[id] = sess
+			revoked++
+		}
+		return nil
+	})
+	return revoked, err
+}
@@ -0,0 +1,412 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"resellution/backend/internal/models"
+)
+
+// Users implements models.UserRepository and models.PasswordResetRepository.
+type Users struct {
+	s *Store
+}
+
+var (
+	_ models.UserRepository          = Users{}
+	_ models.PasswordResetRepository = Users{}
+)
+
+type resetToken struct {
+	models.PasswordResetToken
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type resetOTP struct {
+	models.PasswordResetOTP
+	AttemptCount int
+	UsedAt       *time.Time
+	CreatedAt    time.Time
+}
+
+func (u Users) Create(_ context.Context, user models.User) (models.User, error) {
+	user.Email = strings.ToLower(user.Email)
+	err := u.s.update(func(t *tables) error {
+		if _, exists := t.users[user.ID]; exists {
+			return constraintError("duplicate user id %s", user.ID)
+		}
+		for _, existing := range t.users {
+			if existing.Email == user.Email {
+				return models.ErrEmailTaken
+			}
+		}
+
+		created := models.User{
+			ID:           user.ID,
+			Email:        user.Email,
+			PasswordHash: user.PasswordHash,
+			FullName:     user.FullName,
+			Role:         models.RoleUser,
+			CreatedAt:    now(),
+		}
+		created.UpdatedAt = created.CreatedAt
+		t.users[user.ID] = created
+
+		user.Role = created.Role
+		user.CreatedAt = created.CreatedAt
+		user.UpdatedAt = created.UpdatedAt
+		return nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func (u Users) FindByEmail(_ context.Context, email string) (models.User, error) {
+	email = strings.ToLower(email)
+	var found models.User
+	var ok bool
+	u.s.read(func(t *tables) {
+		for _, user := range t.users {
+			if user.Email == email && user.DeletedAt == nil {
+				found, ok = user, true
+				return
+			}
+		}
+	})
+	if !ok {
+		return models.User{}, models.ErrUserNotFound
+	}
+	return found, nil
+}
+
+func (u Users) FindByID(_ context.Context, id string) (models.User, error) {
+	return u.find(id, false)
+}
+
+func (u Users) FindByIDIncludingDeleted(_ context.Context, id string) (models.User, error) {
+	return u.find(id, true)
+}
+
+// FindByIDForUpdate needs no row lock: transactions already run one at a time.
+func (u Users) FindByIDForUpdate(_ context.Context, id string) (models.User, error) {
+	return u.find(id, false)
+}
+
+func (u Users) find(id string, includeDeleted bool) (models.User, error) {
+	var user models.User
+	var ok bool
+	u.s.read(func(t *tables) {
+		user, ok = t.users[id]
+	})
+	if !ok || (!includeDeleted && user.DeletedAt != nil) {
+		return models.User{}, models.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// SearchUsers mirrors the SQL query, including the total being 0 when Offset is past
+// the last match (COUNT(*) OVER () only exists on returned rows).
+func (u Users) SearchUsers(_ context.Context, filter models.UserFilter) ([]models.User, int, error) {
+	var matches []models.User
+	u.s.read(func(t *tables) {
+		for _, user := range t.users {
+			if filter.Email != "" && !strings.Contains(user.Email, strings.ToLower(filter.Email)) {
+				continue
+			}
+			if filter.Phone != "" && !strings.Contains(user.Phone, filter.Phone) {
+				continue
+			}
+			if filter.City != "" && !strings.EqualFold(user.City, filter.City) {
+				continue
+			}
+			if !filter.CreatedAfter.IsZero() && user.CreatedAt.Before(filter.CreatedAfter) {
+				continue
+			}
+			if !filter.CreatedBefore.IsZero() && !user.CreatedAt.Before(filter.CreatedBefore) {
+				continue
+			}
+			if !filter.IncludeDeleted && user.DeletedAt != nil {
+				continue
+			}
+			matches = append(matches, user)
+		}
+	})
+
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	users := []models.User{}
+	for i := filter.Offset; i < len(matches) && len(users) < filter.Limit; i++ {
+		users = append(users, matches[i])
+	}
+	if len(users) == 0 {
+		return users, 0, nil
+	}
+	return users, len(matches), nil
+}
+
+func (u Users) UpdateProfile(_ context.Context, userID string, updatedBy string, p models.ProfileUpdate) (models.User, error) {
+	var user models.User
+	err := u.s.update(func(t *tables) error {
+		var ok bool
+		user, ok = t.users[userID]
+		if !ok || user.DeletedAt != nil {
+			return models.ErrUserNotFound
+		}
+
+		if p.FullName != nil {
+			if trimmed := strings.TrimSpace(*p.FullName); trimmed != "" {
+				user.FullName = trimmed
+			}
+		}
+		if p.City != nil {
+			user.City = strings.TrimSpace(*p.City)
+		}
+		if p.Bio != nil {
+			user.Bio = strings.TrimSpace(*p.Bio)
+		}
+		if p.ProfileImageURL != nil {
+			user.ProfileImageURL = strings.TrimSpace(*p.ProfileImageURL)
+		}
+		user.UpdatedAt = now()
+		user.UpdatedBy = updatedBy
+		t.users[userID] = user
+		return nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// modify applies fn to the user when its deleted state matches wantDeleted, the
+// equivalent of the stores' UPDATE ... WHERE deleted_at IS [NOT] NULL.
+func (u Users) modify(userID, updatedBy string, wantDeleted bool, fn func(user *models.User)) error {
+	return u.s.update(func(t *tables) error {
+		user, ok := t.users[userID]
+		if !ok || (user.DeletedAt != nil) != wantDeleted {
+			return models.ErrUserNotFound
+		}
+		fn(&user)
+		user.UpdatedAt = now()
+		user.UpdatedBy = updatedBy
+		t.users[userID] = user
+		return nil
+	})
+}
+
+func (u Users) UpdatePasswordHashByID(_ context.Context, userID, passwordHash, updatedBy string) error {
+	return u.modify(userID, updatedBy, false, func(user *models.User) {
+		user.PasswordHash = passwordHash
+		user.PasswordResetRequired = false
+	})
+}
+
+func (u Users) DeactivateByID(_ context.Context, userID, updatedBy string) error {
+	return u.modify(userID, updatedBy, false, func(user *models.User) {
+		user.DeletedAt = ptr(now())
+	})
+}
+
+func (u Users) RestoreByID(_ context.Context, userID, updatedBy string) error {
+	return u.modify(userID, updatedBy, true, func(user *models.User) {
+		user.DeletedAt = nil
+	})
+}
+
+func (u Users) Suspend(_ context.Context, userID, reason, updatedBy string) error {
+	return u.modify(userID, updatedBy, false, func(user *models.User) {
+		user.SuspendedAt = ptr(now())
+		user.SuspendedReason = reason
+	})
+}
+
+func (u Users) Unsuspend(_ context.Context, userID, updatedBy string) error {
+	return u.modify(userID, updatedBy, false, func(user *models.User) {
+		user.SuspendedAt = nil
+		user.SuspendedReason = ""
+	})
+}
+
+func (u Users) RequirePasswordReset(_ context.Context, userID, updatedBy string) error {
+	return u.modify(userID, updatedBy, false, func(user *models.User) {
+		user.PasswordResetRequired = true
+	})
+}
+
+func (u Users) MarkEmailVerified(_ context.Context, userID, updatedBy string) error {
+	return u.modify(userID, updatedBy, false, func(user *models.User) {
+		user.IsVerified = true
+	})
+}
+
+func (u Users) PermissionsForRole(_ context.Context, role string) ([]string, error) {
+	var permissions []string
+	u.s.read(func(t *tables) {
+		permissions = slices.Clone(t.rolePermissions[role])
+	})
+	return permissions, nil
+}
+
+func (u Users) SetRole(_ context.Context, userID, role, updatedBy string) error {
+	return u.s.update(func(t *tables) error {
+		user, ok := t.users[userID]
+		if !ok || user.DeletedAt != nil {
+			return models.ErrUserNotFound
+		}
+		if _, ok := t.rolePermissions[role]; !ok {
+			return models.ErrRoleNotFound
+		}
+		user.Role = role
+		user.UpdatedAt = now()
+		user.UpdatedBy = updatedBy
+		t.users[userID] = user
+		return nil
+	})
+}
+
+func (u Users) SetRoleByEmail(ctx context.Context, email, role string) error {
+	user, err := u.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+	return u.SetRole(ctx, user.ID, role, "")
+}
+
+func (u Users) InvalidateActivePasswordResetTokensByUserID(_ context.Context, userID string) error {
+	return u.s.update(func(t *tables) error {
+		current := now()
+		for i, token := range t.resetTokens {
+			if token.UserID == userID && token.UsedAt == nil && token.ExpiresAt.After(current) {
+				t.resetTokens[i].UsedAt = ptr(current)
+			}
+		}
+		return nil
+	})
+}
+
+func (u Users) CreatePasswordResetToken(_ context.Context, token models.PasswordResetToken) error {
+	return u.s.update(func(t *tables) error {
+		if _, ok := t.users[token.UserID]; !ok {
+			return constraintError("password reset token references missing user %s", token.UserID)
+		}
+		for _, existing := range t.resetTokens {
+			if existing.ID == token.ID || existing.TokenHash == token.TokenHash {
+				return constraintError("duplicate password reset token")
+			}
+		}
+		t.resetTokens = append(t.resetTokens, resetToken{PasswordResetToken: token, CreatedAt: now()})
+		return nil
+	})
+}
+
+func (u Users) ConsumePasswordResetToken(_ context.Context, tokenHash string) (string, error) {
+	var userID string
+	err := u.s.update(func(t *tables) error {
+		current := now()
+		latest := -1
+		for i, token := range t.resetTokens {
+			if token.TokenHash != tokenHash || token.UsedAt != nil || !token.ExpiresAt.After(current) {
+				continue
+			}
+			if latest < 0 || token.CreatedAt.After(t.resetTokens[latest].CreatedAt) {
+				latest = i
+			}
+		}
+		if latest < 0 {
+			return models.ErrPasswordResetTokenInvalid
+		}
+		t.resetTokens[latest].UsedAt = ptr(current)
+		userID = t.resetTokens[latest].UserID
+		return nil
+	})
+	return userID, err
+}
+
+func (u Users) GetLastPasswordResetRequestTime(_ context.Context, userID string, cooldownMinutes int) (time.Time, bool, error) {
+	if cooldownMinutes <= 0 {
+		return time.Time{}, false, nil
+	}
+
+	var last time.Time
+	u.s.read(func(t *tables) {
+		since := now().Add(-time.Duration(cooldownMinutes) * time.Minute)
+		for _, otp := range t.resetOTPs {
+			if otp.UserID == userID && otp.CreatedAt.After(since) && otp.CreatedAt.After(last) {
+				last = otp.CreatedAt
+			}
+		}
+	})
+	return last, !last.IsZero(), nil
+}
+
+func (u Users) InvalidateActivePasswordResetOTPsByUserID(_ context.Context, userID string) error {
+	return u.s.update(func(t *tables) error {
+		current := now()
+		for i, otp := range t.resetOTPs {
+			if otp.UserID == userID && otp.UsedAt == nil && otp.ExpiresAt.After(current) {
+				t.resetOTPs[i].UsedAt = ptr(current)
+			}
+		}
+		return nil
+	})
+}
+
+func (u Users) CreatePasswordResetOTP(_ context.Context, otp models.PasswordResetOTP) error {
+	return u.s.update(func(t *tables) error {
+		if _, ok := t.users[otp.UserID]; !ok {
+			return constraintError("password reset otp references missing user %s", otp.UserID)
+		}
+		for _, existing := range t.resetOTPs {
+			if existing.ID == otp.ID {
+				return constraintError("duplicate password reset otp id %s", otp.ID)
+			}
+		}
+		t.resetOTPs = append(t.resetOTPs, resetOTP{PasswordResetOTP: otp, CreatedAt: now()})
+		return nil
+	})
+}
+
+// ConsumePasswordResetOTP checks otpHash against the user's latest active OTP. A wrong
+// hash counts as an attempt and uses the OTP up once MaxAttempts is reached.
+func (u Users) ConsumePasswordResetOTP(_ context.Context, userID, otpHash string) error {
+	return u.s.update(func(t *tables) error {
+		current := now()
+		latest := -1
+		for i, otp := range t.resetOTPs {
+			if otp.UserID != userID || otp.UsedAt != nil || !otp.ExpiresAt.After(current) {
+				continue
+			}
+			if latest < 0 || otp.CreatedAt.After(t.resetOTPs[latest].CreatedAt) {
+				latest = i
+			}
+		}
+		if latest < 0 {
+			return models.ErrPasswordResetOTPInvalid
+		}
+
+		otp := &t.resetOTPs[latest]
+		if otp.OTPHash != otpHash {
+			otp.AttemptCount++
+			if otp.AttemptCount >= otp.MaxAttempts {
+				otp.UsedAt = ptr(current)
+			}
+			return models.ErrPasswordResetOTPInvalid
+		}
+		otp.UsedAt = ptr(current)
+		return nil
+	})
+}
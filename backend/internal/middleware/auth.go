@@ -5,34 +5,65 @@ import (
 	"net/http"
 	"strings"
 
+	"resellution/backend/internal/observability"
+	"resellution/backend/internal/problem"
 	"resellution/backend/internal/utils"
 )
 
 type contextKey string
 
-const userIDContextKey contextKey = "user_id"
+const (
+	userIDContextKey contextKey = "user_id"
+	claimsContextKey contextKey = "claims"
+)
+
+// SessionChecker reports whether a session referenced by a token is still active, so
+// revoked sessions are rejected before their tokens expire.
+type SessionChecker interface {
+	IsActive(ctx context.Context, sessionID string) (bool, error)
+}
 
-func Auth(tokenManager utils.TokenManager, next http.HandlerFunc) http.HandlerFunc {
+// Auth validates the bearer token. When sessions is non-nil the token must also reference
+// an active session.
+func Auth(tokenManager utils.TokenManager, sessions SessionChecker, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "missing authorization header", http.StatusUnauthorized)
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "missing authorization header")
 			return
 		}
 
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			http.Error(w, "invalid authorization header", http.StatusUnauthorized)
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "invalid authorization header")
 			return
 		}
 
-		userID, err := tokenManager.Parse(parts[1])
+		claims, err := tokenManager.ParseClaims(parts[1])
 		if err != nil {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "invalid token")
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), userIDContextKey, userID)
+		if sessions != nil {
+			if claims.SessionID == "" {
+				problem.Error(w, r, http.StatusUnauthorized, problem.CodeInvalidToken, "invalid token")
+				return
+			}
+			active, err := sessions.IsActive(r.Context(), claims.SessionID)
+			if err != nil {
+				problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to validate session")
+				return
+			}
+			if !active {
+				problem.Error(w, r, http.StatusUnauthorized, problem.CodeSessionRevoked, "session revoked")
+				return
+			}
+		}
+
+		observability.SetUserID(r.Context(), claims.UserID)
+		ctx := context.WithValue(r.Context(), userIDContextKey, claims.UserID)
+		ctx = context.WithValue(ctx, claimsContextKey, claims)
 		next(w, r.WithContext(ctx))
 	}
 }
@@ -41,3 +72,34 @@ func UserIDFromContext(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(userIDContextKey).(string)
 	return userID, ok
 }
+
+// Require allows the request through only when the token claims placed in context by
+// Auth grant permission. It must be wrapped by Auth.
+func Require(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthenticated, "unauthorized")
+			return
+		}
+		if !claims.HasPermission(permission) {
+			problem.Error(w, r, http.StatusForbidden, problem.CodeForbidden, "forbidden")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func ClaimsFromContext(ctx context.Context) (utils.Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(utils.Claims)
+	return claims, ok
+}
+
+// bearerToken returns the token of a well-formed "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
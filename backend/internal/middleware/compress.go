@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Compress encodes responses with Brotli or gzip, whichever the client's
+// Accept-Encoding prefers; Brotli wins a tie because it compresses JSON smaller.
+// Bodies under minSize bytes are sent as they are, since compressing them costs more
+// than it saves, and so are media types that are already compressed and responses the
+// handler encoded itself.
+//
+// ETags are left alone: they name the resource version, not the encoded bytes, and the
+// API serves no range requests that would tell the encodings apart.
+func Compress(minSize int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+		defer cw.finish()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Encodings Compress can produce, in order of preference.
+var supportedEncodings = []string{"br", "gzip"}
+
+// negotiateEncoding returns the supported encoding with the highest q-value in
+// header, or "" when the client accepts none of them.
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supportedEncodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		zw, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return zw
+	}}
+	brotliWriters = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}}
+)
+
+type resetWriteCloser interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// compressWriter buffers the start of the body until it knows whether the response is
+// worth compressing: once minSize bytes arrive it starts the encoder, and if the
+// handler finishes first the buffer is sent as it is.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status      int
+	buf         bytes.Buffer
+	encoder     resetWriteCloser
+	passthrough bool
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if status < http.StatusOK {
+		// Informational responses such as 103 Early Hints precede the real one.
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified ||
+		cw.Header().Get("Content-Encoding") != "" || !compressible(cw.Header().Get("Content-Type")) {
+		cw.startPassthrough()
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case cw.passthrough:
+		return cw.ResponseWriter.Write(b)
+	case cw.encoder != nil:
+		return cw.encoder.Write(b)
+	}
+
+	cw.buf.Write(b)
+	if cw.buf.Len() >= cw.minSize {
+		if err := cw.startEncoder(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends what has been written so far, compressed if the encoder has started.
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case cw.encoder != nil:
+		if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+			_ = f.Flush()
+		}
+	case !cw.passthrough:
+		// Streaming responses are not worth holding back for a size decision.
+		cw.startPassthrough()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) startPassthrough() {
+	cw.passthrough = true
+	cw.writeHeader()
+	if cw.buf.Len() > 0 {
+		_, _ = cw.ResponseWriter.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+	}
+}
+
+func (cw *compressWriter) startEncoder() error {
+	header := cw.Header()
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+	cw.writeHeader()
+
+	if cw.encoding == "br" {
+		cw.encoder = brotliWriters.Get().(*brotli.Writer)
+	} else {
+		cw.encoder = gzipWriters.Get().(*gzip.Writer)
+	}
+	cw.encoder.Reset(cw.ResponseWriter)
+	_, err := cw.encoder.Write(cw.buf.Bytes())
+	cw.buf.Reset()
+	return err
+}
+
+func (cw *compressWriter) writeHeader() {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// finish completes the response after the handler returns.
+func (cw *compressWriter) finish() {
+	if cw.encoder != nil {
+		_ = cw.encoder.Close()
+		cw.encoder.Reset(io.Discard)
+		if cw.encoding == "br" {
+			brotliWriters.Put(cw.encoder)
+		} else {
+			gzipWriters.Put(cw.encoder)
+		}
+		return
+	}
+	if cw.status == 0 {
+		// The handler wrote nothing; let net/http send its implicit 200.
+		return
+	}
+	if !cw.passthrough {
+		cw.startPassthrough()
+	}
+}
+
+// compressible reports whether contentType is worth compressing: text, JSON, XML and
+// JavaScript are, while images, audio, video, archives and fonts are already
+// compressed.
+func compressible(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		mediaType == "image/svg+xml":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"gzip;q=0, br;q=0", ""},
+		{"identity", ""},
+		{"*", "br"},
+		{"*;q=0.1, gzip;q=0.5", "gzip"},
+		{"BR", "br"},
+		{"gzip;q=abc", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"listings":"` + strings.Repeat("Royal Enfield Classic 350, Pune. ", 100) + `"}`
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		method         string
+		contentType    string
+		encoded        bool
+		status         int
+		body           string
+		wantEncoding   string
+	}{
+		{"brotli", "gzip, br", http.MethodGet, "application/json", false, http.StatusOK, large, "br"},
+		{"gzip", "gzip", http.MethodGet, "application/json", false, http.StatusOK, large, "gzip"},
+		{"problem json", "gzip", http.MethodGet, "application/problem+json", false, http.StatusBadRequest, large, "gzip"},
+		{"not accepted", "", http.MethodGet, "application/json", false, http.StatusOK, large, ""},
+		{"small body", "br", http.MethodGet, "application/json", false, http.StatusOK, `{"ok":true}`, ""},
+		{"image", "br", http.MethodGet, "image/jpeg", false, http.StatusOK, large, ""},
+		{"already encoded", "gzip", http.MethodGet, "application/json", true, http.StatusOK, large, "identity"},
+		{"HEAD", "gzip", http.MethodHead, "application/json", false, http.StatusOK, "", ""},
+		{"not modified", "gzip", http.MethodGet, "application/json", false, http.StatusNotModified, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(1024, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.encoded {
+					w.Header().Set("Content-Encoding", "identity")
+				}
+				w.WriteHeader(tt.status)
+				// Write in pieces, as streaming encoders do.
+				for i := 0; i < len(tt.body); i += 100 {
+					_, _ = io.WriteString(w, tt.body[i:min(i+100, len(tt.body))])
+				}
+			}))
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Vary = %q", rec.Header().Get("Vary"))
+			}
+
+			var body io.Reader = rec.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			case "br":
+				body = brotli.NewReader(rec.Body)
+			}
+			if tt.wantEncoding == "br" || tt.wantEncoding == "gzip" {
+				if rec.Body.Len() >= len(tt.body) {
+					t.Errorf("compressed body is %d bytes, original %d", rec.Body.Len(), len(tt.body))
+				}
+			}
+			decoded, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(decoded) != tt.body {
+				t.Errorf("body = %.60q..., want %.60q...", decoded, tt.body)
+			}
+		})
+	}
+}
+
+func TestCompressImplicitStatus(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+	handler := Compress(1024, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, large)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("got %d %v", rec.Code, rec.Header())
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Content-Type = %q, want the sniffed type", rec.Header().Get("Content-Type"))
+	}
+}
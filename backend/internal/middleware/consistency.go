@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/utils"
+)
+
+// maxTrackedWriters caps the tracker so it cannot grow without bound. When it is full
+// the writer seen longest ago is dropped, which at worst sends that user's next reads
+// to a replica.
+const maxTrackedWriters = 10000
+
+// WriteTracker remembers which signed-in users wrote recently. Users are identified by
+// the user ID in a verified bearer token; anonymous requests are not tracked. The state
+// is per instance: behind a load balancer without sticky sessions a user can still read
+// from a replica that lags by up to the router's lag limit.
+type WriteTracker struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	writes map[string]time.Time
+}
+
+func NewWriteTracker(window time.Duration) *WriteTracker {
+	return &WriteTracker{window: window, writes: make(map[string]time.Time)}
+}
+
+func (t *WriteTracker) recordWrite(userID string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.writes[userID]; !ok && len(t.writes) >= maxTrackedWriters {
+		t.evict(now)
+	}
+	t.writes[userID] = now
+}
+
+// evict drops expired entries and, if that frees nothing, the oldest one.
+func (t *WriteTracker) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for key, at := range t.writes {
+		if now.Sub(at) > t.window {
+			delete(t.writes, key)
+			continue
+		}
+		if oldestKey == "" || at.Before(oldest) {
+			oldestKey, oldest = key, at
+		}
+	}
+	if len(t.writes) >= maxTrackedWriters {
+		delete(t.writes, oldestKey)
+	}
+}
+
+func (t *WriteTracker) wroteRecently(userID string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at, ok := t.writes[userID]
+	if ok && now.Sub(at) > t.window {
+		delete(t.writes, userID)
+		return false
+	}
+	return ok
+}
+
+// ReadYourWrites sends every query of a write request (any method other than GET, HEAD
+// or OPTIONS) to the primary. After a signed-in user's write succeeds, their reads stay
+// on the primary for the tracker's window, so they see their own changes despite replica
+// lag. Failed writes (4xx and 5xx) and requests without a valid token are not recorded.
+func ReadYourWrites(tracker *WriteTracker, tokens utils.TokenManager, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, signedIn := verifiedUserID(tokens, r)
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if signedIn && tracker.wroteRecently(userID, time.Now()) {
+				r = r.WithContext(db.WithPrimary(r.Context()))
+			}
+			next.ServeHTTP(w, r)
+		default:
+			r = r.WithContext(db.WithPrimary(r.Context()))
+			if !signedIn {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rec := &statusTracker{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if rec.status < http.StatusBadRequest {
+				tracker.recordWrite(userID, time.Now())
+			}
+		}
+	})
+}
+
+func verifiedUserID(tokens utils.TokenManager, r *http.Request) (string, bool) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return "", false
+	}
+	claims, err := tokens.ParseClaims(token)
+	if err != nil || claims.UserID == "" {
+		return "", false
+	}
+	return claims.UserID, true
+}
+
+type statusTracker struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (t *statusTracker) WriteHeader(status int) {
+	if !t.wroteHeader {
+		t.status, t.wroteHeader = status, true
+	}
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *statusTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (t *statusTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
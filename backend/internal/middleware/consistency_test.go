@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"resellution/backend/internal/utils"
+)
+
+func TestReadYourWritesRecordsOnlySuccessfulSignedInWrites(t *testing.T) {
+	tokens := utils.NewTokenManager("test-secret-test-secret-test-secret")
+	token, err := tokens.Create("user-1", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		auth   string
+		status int
+		want   bool
+	}{
+		{"successful write", "Bearer " + token, http.StatusCreated, true},
+		{"rejected write", "Bearer " + token, http.StatusUnprocessableEntity, false},
+		{"anonymous write", "", http.StatusCreated, false},
+		{"forged token", "Bearer not-a-token", http.StatusCreated, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewWriteTracker(time.Minute)
+			handler := ReadYourWrites(tracker, tokens, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/listings", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got := tracker.wroteRecently("user-1", time.Now()); got != tt.want {
+				t.Fatalf("wroteRecently = %v, want %v", got, tt.want)
+			}
+			if n := len(tracker.writes); n > 1 {
+				t.Fatalf("tracker holds %d entries", n)
+			}
+		})
+	}
+}
+
+func TestWriteTrackerEvictsOldestWhenFull(t *testing.T) {
+	tracker := NewWriteTracker(time.Hour)
+	start := time.Now()
+	for i := 0; i < maxTrackedWriters; i++ {
+		tracker.recordWrite(fmt.Sprintf("user-%d", i), start.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	now := start.Add(time.Minute)
+	tracker.recordWrite("newcomer", now)
+	if n := len(tracker.writes); n != maxTrackedWriters {
+		t.Fatalf("tracker holds %d entries, want %d", n, maxTrackedWriters)
+	}
+	if tracker.wroteRecently("user-0", now) {
+		t.Fatal("the oldest writer was not evicted")
+	}
+	if !tracker.wroteRecently("user-1", now) || !tracker.wroteRecently("newcomer", now) {
+		t.Fatal("a recent writer was evicted")
+	}
+}
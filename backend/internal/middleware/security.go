@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"resellution/backend/internal/problem"
+)
+
+// SecurityHeaders sets headers that harden every response. The API is never meant to
+// be framed or sniffed, and it sends no referrer. HSTS is sent when hstsMaxAge is
+// positive; browsers ignore it over plain HTTP.
+func SecurityHeaders(hstsMaxAge int, next http.Handler) http.Handler {
+	hsts := fmt.Sprintf("max-age=%d; includeSubDomains", hstsMaxAge)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", "no-referrer")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Content-Security-Policy", "frame-ancestors 'none'")
+		if hstsMaxAge > 0 {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MaxBodySize limits request bodies to limit bytes. A request whose Content-Length is
+// already too large is answered with 413 without reading it; otherwise reading past the
+// limit fails with *http.MaxBytesError, which problem.BodyError reports as 413. Nested
+// limits apply the smallest.
+func MaxBodySize(limit int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			problem.BodyError(w, r, &http.MaxBytesError{Limit: limit})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(w, r)
+	}
+}
+
+// Recover turns a panic in next into a 500 internal.error problem and logs it with the
+// stack trace. http.ErrAbortHandler is re-panicked so the server still aborts the
+// response as intended.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &headerTracker{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			slog.ErrorContext(r.Context(), "panic serving request",
+				"panic", fmt.Sprint(recovered),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"stack", string(debug.Stack()),
+			)
+			// A response already under way cannot be replaced; the client sees it cut off.
+			if !rec.wroteHeader {
+				problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "internal server error")
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *headerTracker) WriteHeader(status int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *headerTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (t *headerTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
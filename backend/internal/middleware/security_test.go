@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"resellution/backend/internal/problem"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		name     string
+		hstsAge  int
+		wantHSTS string
+	}{
+		{"with HSTS", 3600, "max-age=3600; includeSubDomains"},
+		{"HSTS disabled", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			SecurityHeaders(tt.hstsAge, ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			want := map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"Referrer-Policy":           "no-referrer",
+				"X-Frame-Options":           "DENY",
+				"Content-Security-Policy":   "frame-ancestors 'none'",
+				"Strict-Transport-Security": tt.wantHSTS,
+			}
+			for header, value := range want {
+				if got := rec.Header().Get(header); got != value {
+					t.Errorf("%s = %q, want %q", header, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	decode := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			problem.BodyError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		wantStatus    int
+	}{
+		{"within limit", "0123456789", 10, http.StatusNoContent},
+		{"declared too large", "0123456789abcdef", 16, http.StatusRequestEntityTooLarge},
+		// Chunked requests have no Content-Length; the limit applies while reading.
+		{"streamed too large", "0123456789abcdef", -1, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			rec := httptest.NewRecorder()
+			MaxBodySize(12, decode)(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !strings.Contains(rec.Body.String(), problem.CodeBodyTooLarge) {
+				t.Fatalf("body = %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("before the response", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil))
+
+		var p problem.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusInternalServerError || p.Code != problem.CodeInternal || strings.Contains(rec.Body.String(), "boom") {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("after the response started", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"partial":`))
+			panic("boom")
+		})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Code != http.StatusOK || rec.Body.String() != `{"partial":` {
+			t.Fatalf("got %d %s, want the partial response untouched", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("abort handler", func(t *testing.T) {
+		defer func() {
+			if recovered := recover(); !errors.Is(recovered.(error), http.ErrAbortHandler) {
+				t.Fatalf("recovered %v, want http.ErrAbortHandler", recovered)
+			}
+		}()
+		Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		t.Fatal("ErrAbortHandler was swallowed")
+	})
+}
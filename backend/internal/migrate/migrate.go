@@ -0,0 +1,340 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// advisoryLockKey serialises migration runs across replicas.
+const advisoryLockKey int64 = 0x7265_7365_6c6c // "resell"
+
+var ErrSchemaBehind = errors.New("database schema is not up to date")
+
+// Migration is an up script and its optional down script.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// State of a migration relative to the database.
+const (
+	StatePending  = "pending"
+	StateApplied  = "applied"
+	StateModified = "modified" // applied, but the file changed since
+	StateMissing  = "missing"  // applied, but the file no longer exists
+)
+
+type Status struct {
+	Version   int64
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+// Migrator applies migrations from FS, recorded in the schema_migrations table.
+type Migrator struct {
+	DB *sql.DB
+	FS fs.FS
+}
+
+// Load reads migrations from m.FS. Files are named NNNN_name.sql for the up script and
+// NNNN_name.down.sql for the down script.
+func (m Migrator) Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || path.Ext(fileName) != ".sql" {
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, ".sql")
+		isDown := strings.HasSuffix(base, ".down")
+		base = strings.TrimSuffix(base, ".down")
+
+		rawVersion, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.sql", fileName)
+		}
+		version, err := strconv.ParseInt(rawVersion, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version %q", fileName, rawVersion)
+		}
+
+		content, err := fs.ReadFile(m.FS, fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration version %d used by %q and %q", version, migration.Name, name)
+		}
+		if isDown {
+			migration.Down = string(content)
+		} else {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has a down script but no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Status compares the embedded migrations with the database. It only reads: before the
+// first Up there is no schema_migrations table and every migration is pending.
+func (m Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err := m.DB.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]appliedRecord)
+	if exists {
+		if applied, err = m.applied(ctx, m.DB); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []Status
+	for _, migration := range migrations {
+		status := Status{Version: migration.Version, Name: migration.Name, State: StatePending}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.appliedAt
+			status.State = StateApplied
+			if record.checksum != migration.Checksum {
+				status.State = StateModified
+			}
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		appliedAt := record.appliedAt
+		statuses = append(statuses, Status{Version: version, Name: record.name, State: StateMissing, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind when any migration is pending, modified or missing.
+func (m Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, status := range statuses {
+		if status.State != StateApplied {
+			problems = append(problems, fmt.Sprintf("%04d_%s is %s", status.Version, status.Name, status.State))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaBehind, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// Up applies every pending migration in order, each in its own transaction, and returns
+// the applied migrations.
+func (m Migrator) Up(ctx context.Context) ([]Migration, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			if record, ok := applied[migration.Version]; ok {
+				if record.checksum != migration.Checksum {
+					return fmt.Errorf("migration %04d_%s was modified after it was applied", migration.Version, migration.Name)
+				}
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+					migration.Version, migration.Name, migration.Checksum)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("apply %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, newest first, and returns them.
+func (m Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var done []Migration
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			migration, ok := byVersion[versions[i]]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but its files are missing", versions[i])
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("roll back %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+type appliedRecord struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+func (m Migrator) ensureTable(ctx context.Context, q queryer) error {
+	_, err := q.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	return err
+}
+
+func (m Migrator) applied(ctx context.Context, q queryer) (map[int64]appliedRecord, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedRecord)
+	for rows.Next() {
+		var version int64
+		var record appliedRecord
+		if err := rows.Scan(&version, &record.name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+// withLock runs fn on a single connection holding the migration advisory lock, so
+// replicas starting together apply migrations one at a time.
+func (m Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Waiting for the lock and running migrations can take longer than the per-query
+	// statement_timeout configured for the pool; RESET restores it before the connection
+	// is reused.
+	if _, err := conn.ExecContext(ctx, `SET statement_timeout = 0`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `RESET statement_timeout`)
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
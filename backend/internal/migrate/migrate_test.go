@@ -0,0 +1,41 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"resellution/backend/migrations"
+)
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	loaded, err := Migrator{FS: migrations.FS}.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	for i, migration := range loaded {
+		if migration.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d, expected contiguous versions", i, migration.Version)
+		}
+		if migration.Down == "" {
+			t.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"down without up":   {"0001_init.down.sql": {Data: []byte("SELECT 1")}},
+		"duplicate version": {"0001_a.sql": {Data: []byte("SELECT 1")}, "0001_b.sql": {Data: []byte("SELECT 1")}},
+		"bad version":       {"abc_init.sql": {Data: []byte("SELECT 1")}},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := (Migrator{FS: fsys}).Load(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
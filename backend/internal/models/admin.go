@@ -0,0 +1,181 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UserFilter narrows an admin user search. Empty fields are ignored.
+type UserFilter struct {
+	Email          string
+	Phone          string
+	City           string
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	IncludeDeleted bool
+	Limit          int
+	Offset         int
+}
+
+// SearchUsers returns users matching filter, newest first, together with the total
+// number of matches ignoring Limit and Offset.
+func (s UserStore) SearchUsers(ctx context.Context, filter UserFilter) ([]User, int, error) {
+	var conditions []string
+	var args []any
+	addCondition := func(format string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.Email != "" {
+		addCondition("email LIKE '%%' || $%d || '%%'", escapeLike(strings.ToLower(filter.Email)))
+	}
+	if filter.Phone != "" {
+		addCondition("phone LIKE '%%' || $%d || '%%'", escapeLike(filter.Phone))
+	}
+	if filter.City != "" {
+		addCondition("LOWER(city) = LOWER($%d)", filter.City)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		addCondition("created_at >= $%d", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		addCondition("created_at < $%d", filter.CreatedBefore)
+	}
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s, COUNT(*) OVER ()
+		FROM users
+		%s
+		ORDER BY created_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, userColumns, where, len(args)-1, len(args))
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	total := 0
+	for rows.Next() {
+		user, err := scanUser(totalScanner{rows: rows, total: &total})
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	return users, total, rows.Err()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// totalScanner appends the COUNT(*) OVER () column to a user row scan.
+type totalScanner struct {
+	rows  rowScanner
+	total *int
+}
+
+func (t totalScanner) Scan(dest ...any) error {
+	return t.rows.Scan(append(dest, t.total)...)
+}
+
+// FindByIDIncludingDeleted returns the user even when deactivated, for admin views.
+func (s UserStore) FindByIDIncludingDeleted(ctx context.Context, id string) (User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1
+	`
+
+	return scanUser(s.conn(ctx).QueryRowContext(ctx, query, id))
+}
+
+// Suspend blocks login for an account without deactivating it. Unlike DeactivateByID,
+// the account stays visible and keeps its data.
+func (s UserStore) Suspend(ctx context.Context, userID, reason, updatedBy string) error {
+	query := `
+		UPDATE users
+		SET suspended_at = NOW(), suspended_reason = $2, updated_at = NOW(), updated_by = $3
+		WHERE id = $1
+		  AND deleted_at IS NULL
+	`
+
+	return s.execUserUpdate(ctx, query, userID, nullIfEmpty(reason), nullIfEmpty(updatedBy))
+}
+
+func (s UserStore) Unsuspend(ctx context.Context, userID, updatedBy string) error {
+	query := `
+		UPDATE users
+		SET suspended_at = NULL, suspended_reason = NULL, updated_at = NOW(), updated_by = $2
+		WHERE id = $1
+		  AND deleted_at IS NULL
+	`
+
+	return s.execUserUpdate(ctx, query, userID, nullIfEmpty(updatedBy))
+}
+
+// RequirePasswordReset blocks login until the user completes the password reset flow.
+func (s UserStore) RequirePasswordReset(ctx context.Context, userID, updatedBy string) error {
+	query := `
+		UPDATE users
+		SET password_reset_required = TRUE, updated_at = NOW(), updated_by = $2
+		WHERE id = $1
+		  AND deleted_at IS NULL
+	`
+
+	return s.execUserUpdate(ctx, query, userID, nullIfEmpty(updatedBy))
+}
+
+func (s UserStore) MarkEmailVerified(ctx context.Context, userID, updatedBy string) error {
+	query := `
+		UPDATE users
+		SET is_verified = TRUE, updated_at = NOW(), updated_by = $2
+		WHERE id = $1
+		  AND deleted_at IS NULL
+	`
+
+	return s.execUserUpdate(ctx, query, userID, nullIfEmpty(updatedBy))
+}
+
+// RestoreByID reverses DeactivateByID.
+func (s UserStore) RestoreByID(ctx context.Context, userID, updatedBy string) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, updated_at = NOW(), updated_by = $2
+		WHERE id = $1
+		  AND deleted_at IS NOT NULL
+	`
+
+	return s.execUserUpdate(ctx, query, userID, nullIfEmpty(updatedBy))
+}
+
+func (s UserStore) execUserUpdate(ctx context.Context, query string, args ...any) error {
+	result, err := s.conn(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"resellution/backend/internal/db"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+var ErrCategoryConflict = errors.New("category name or slug already exists")
+var ErrCategoryParentNotFound = errors.New("parent category not found")
+
+type Category struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CategoryStore struct {
+	DB *sql.DB
+	// Replicas, when set, serves the read-only queries that tolerate replication lag.
+	Replicas *db.Router
+}
+
+func (s CategoryStore) conn(ctx context.Context) db.Querier {
+	return db.Conn(ctx, s.DB)
+}
+
+func (s CategoryStore) readConn(ctx context.Context) db.Querier {
+	return db.ReadConn(ctx, s.DB, s.Replicas)
+}
+
+// List returns every category. It reads from a replica unless ctx carries a transaction
+// or was marked with db.WithPrimary.
+func (s CategoryStore) List(ctx context.Context) ([]Category, error) {
+	query := `
+		SELECT id, name, slug, parent_id, created_at
+		FROM categories
+		ORDER BY name
+	`
+
+	rows, err := s.readConn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var category Category
+		var parentID sql.NullString
+		if err := rows.Scan(&category.ID, &category.Name, &category.Slug, &parentID, &category.CreatedAt); err != nil {
+			return nil, err
+		}
+		category.ParentID = parentID.String
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (s CategoryStore) FindByID(ctx context.Context, id string) (Category, error) {
+	query := `
+		SELECT id, name, slug, parent_id, created_at
+		FROM categories
+		WHERE id = $1
+	`
+
+	var category Category
+	var parentID sql.NullString
+	err := s.conn(ctx).QueryRowContext(ctx, query, id).Scan(&category.ID, &category.Name, &category.Slug, &parentID, &category.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Category{}, ErrCategoryNotFound
+		}
+		return Category{}, err
+	}
+
+	category.ParentID = parentID.String
+	return category, nil
+}
+
+func (s CategoryStore) Create(ctx context.Context, category Category) (Category, error) {
+	query := `
+		INSERT INTO categories (id, name, slug, parent_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+
+	err := s.conn(ctx).QueryRowContext(ctx, query, category.ID, category.Name, category.Slug, nullIfEmpty(category.ParentID)).
+		Scan(&category.CreatedAt)
+	if err != nil {
+		return Category{}, categoryWriteError(err)
+	}
+	return category, nil
+}
+
+func (s CategoryStore) Update(ctx context.Context, category Category) (Category, error) {
+	query := `
+		UPDATE categories
+		SET name = $2, slug = $3, parent_id = $4
+		WHERE id = $1
+		RETURNING created_at
+	`
+
+	err := s.conn(ctx).QueryRowContext(ctx, query, category.ID, category.Name, category.Slug, nullIfEmpty(category.ParentID)).
+		Scan(&category.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Category{}, ErrCategoryNotFound
+		}
+		return Category{}, categoryWriteError(err)
+	}
+	return category, nil
+}
+
+// Delete removes a category. Listings and child categories keep existing with their
+// category_id/parent_id set to NULL by the foreign keys.
+func (s CategoryStore) Delete(ctx context.Context, id string) error {
+	result, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+func categoryWriteError(err error) error {
+	lowerErr := strings.ToLower(err.Error())
+	if strings.Contains(lowerErr, "duplicate") || strings.Contains(lowerErr, "unique") {
+		return ErrCategoryConflict
+	}
+	if isForeignKeyViolation(err) {
+		return ErrCategoryParentNotFound
+	}
+	return err
+}
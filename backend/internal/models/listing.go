@@ -0,0 +1,210 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"resellution/backend/internal/db"
+)
+
+var ErrListingNotFound = errors.New("listing not found")
+
+const (
+	ListingStatusActive   = "active"
+	ListingStatusReserved = "reserved"
+	ListingStatusSold     = "sold"
+	ListingStatusDeleted  = "deleted"
+)
+
+type Listing struct {
+	ID          string     `json:"id"`
+	SellerID    string     `json:"seller_id"`
+	CategoryID  string     `json:"category_id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Condition   string     `json:"condition"`
+	Price       float64    `json:"price"`
+	Currency    string     `json:"currency"`
+	City        string     `json:"city"`
+	State       string     `json:"state,omitempty"`
+	Status      string     `json:"status"`
+	ViewCount   int        `json:"view_count"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+}
+
+type ListingStore struct {
+	DB *sql.DB
+	// Replicas, when set, serves the read-only queries that tolerate replication lag.
+	Replicas *db.Router
+}
+
+func (s ListingStore) conn(ctx context.Context) db.Querier {
+	return db.Conn(ctx, s.DB)
+}
+
+func (s ListingStore) readConn(ctx context.Context) db.Querier {
+	return db.ReadConn(ctx, s.DB, s.Replicas)
+}
+
+const listingColumns = `
+	id, seller_id, category_id, title, description, condition, price, currency, city, state,
+	status, view_count, deleted_at, created_at, updated_at, updated_by
+`
+
+func scanListing(row rowScanner) (Listing, error) {
+	var listing Listing
+	var categoryID, state, updatedBy sql.NullString
+	var deletedAt sql.NullTime
+	err := row.Scan(
+		&listing.ID,
+		&listing.SellerID,
+		&categoryID,
+		&listing.Title,
+		&listing.Description,
+		&listing.Condition,
+		&listing.Price,
+		&listing.Currency,
+		&listing.City,
+		&state,
+		&listing.Status,
+		&listing.ViewCount,
+		&deletedAt,
+		&listing.CreatedAt,
+		&listing.UpdatedAt,
+		&updatedBy,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Listing{}, ErrListingNotFound
+		}
+		return Listing{}, err
+	}
+
+	listing.CategoryID = categoryID.String
+	listing.State = state.String
+	listing.UpdatedBy = updatedBy.String
+	if deletedAt.Valid {
+		listing.DeletedAt = &deletedAt.Time
+	}
+	return listing, nil
+}
+
+// Create inserts an active listing. Currency defaults to INR.
+func (s ListingStore) Create(ctx context.Context, listing Listing) (Listing, error) {
+	if listing.Currency == "" {
+		listing.Currency = "INR"
+	}
+
+	query := `
+		INSERT INTO listings (id, seller_id, category_id, title, description, condition, price, currency, city, state)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + listingColumns
+
+	return scanListing(s.conn(ctx).QueryRowContext(ctx, query,
+		listing.ID,
+		listing.SellerID,
+		nullIfEmpty(listing.CategoryID),
+		listing.Title,
+		listing.Description,
+		listing.Condition,
+		listing.Price,
+		listing.Currency,
+		listing.City,
+		nullIfEmpty(listing.State),
+	))
+}
+
+// FindByID returns a listing that has not been deleted. It reads from a replica unless
+// ctx carries a transaction or was marked with db.WithPrimary.
+func (s ListingStore) FindByID(ctx context.Context, id string) (Listing, error) {
+	query := `
+		SELECT ` + listingColumns + `
+		FROM listings
+		WHERE id = $1
+		  AND deleted_at IS NULL
+	`
+
+	return scanListing(s.readConn(ctx).QueryRowContext(ctx, query, id))
+}
+
+// ListBySeller returns the seller's listings that have not been deleted, newest first,
+// reading from a replica like FindByID.
+func (s ListingStore) ListBySeller(ctx context.Context, sellerID string) ([]Listing, error) {
+	query := `
+		SELECT ` + listingColumns + `
+		FROM listings
+		WHERE seller_id = $1
+		  AND deleted_at IS NULL
+		ORDER BY created_at DESC, id
+	`
+
+	rows, err := s.readConn(ctx).QueryContext(ctx, query, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	listings := []Listing{}
+	for rows.Next() {
+		listing, err := scanListing(rows)
+		if err != nil {
+			return nil, err
+		}
+		listings = append(listings, listing)
+	}
+	return listings, rows.Err()
+}
+
+// Update saves the editable fields and status of a listing that has not been deleted.
+func (s ListingStore) Update(ctx context.Context, listing Listing, updatedBy string) (Listing, error) {
+	query := `
+		UPDATE listings
+		SET category_id = $2, title = $3, description = $4, condition = $5, price = $6,
+			city = $7, state = $8, status = $9, updated_at = NOW(), updated_by = $10
+		WHERE id = $1
+		  AND deleted_at IS NULL
+		RETURNING ` + listingColumns
+
+	return scanListing(s.conn(ctx).QueryRowContext(ctx, query,
+		listing.ID,
+		nullIfEmpty(listing.CategoryID),
+		listing.Title,
+		listing.Description,
+		listing.Condition,
+		listing.Price,
+		listing.City,
+		nullIfEmpty(listing.State),
+		listing.Status,
+		nullIfEmpty(updatedBy),
+	))
+}
+
+// DeleteByID soft-deletes the listing; the status and deleted_at change together to
+// satisfy listings_status_deleted_at_consistency.
+func (s ListingStore) DeleteByID(ctx context.Context, id, updatedBy string) error {
+	query := `
+		UPDATE listings
+		SET status = 'deleted', deleted_at = NOW(), updated_at = NOW(), updated_by = $2
+		WHERE id = $1
+		  AND deleted_at IS NULL
+	`
+
+	result, err := s.conn(ctx).ExecContext(ctx, query, id, nullIfEmpty(updatedBy))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrListingNotFound
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// The repository interfaces are what handlers depend on. The Postgres stores in this
+// package implement them, and so does internal/memstore for tests; both are held to the
+// same behavior by the conformance suite in internal/models/repotest.
+//
+// updatedBy is the acting user's ID. It is empty for changes made by an operator with
+// resellctl, which have no acting user.
+
+type UserRepository interface {
+	Create(ctx context.Context, user User) (User, error)
+	FindByEmail(ctx context.Context, email string) (User, error)
+	FindByID(ctx context.Context, id string) (User, error)
+	FindByIDIncludingDeleted(ctx context.Context, id string) (User, error)
+	FindByIDForUpdate(ctx context.Context, id string) (User, error)
+	SearchUsers(ctx context.Context, filter UserFilter) ([]User, int, error)
+	UpdateProfile(ctx context.Context, userID string, updatedBy string, u ProfileUpdate) (User, error)
+	UpdatePasswordHashByID(ctx context.Context, userID, passwordHash, updatedBy string) error
+	DeactivateByID(ctx context.Context, userID, updatedBy string) error
+	RestoreByID(ctx context.Context, userID, updatedBy string) error
+	Suspend(ctx context.Context, userID, reason, updatedBy string) error
+	Unsuspend(ctx context.Context, userID, updatedBy string) error
+	RequirePasswordReset(ctx context.Context, userID, updatedBy string) error
+	MarkEmailVerified(ctx context.Context, userID, updatedBy string) error
+	PermissionsForRole(ctx context.Context, role string) ([]string, error)
+	SetRole(ctx context.Context, userID, role, updatedBy string) error
+	SetRoleByEmail(ctx context.Context, email, role string) error
+}
+
+type PasswordResetRepository interface {
+	InvalidateActivePasswordResetTokensByUserID(ctx context.Context, userID string) error
+	CreatePasswordResetToken(ctx context.Context, token PasswordResetToken) error
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error)
+	GetLastPasswordResetRequestTime(ctx context.Context, userID string, cooldownMinutes int) (time.Time, bool, error)
+	InvalidateActivePasswordResetOTPsByUserID(ctx context.Context, userID string) error
+	CreatePasswordResetOTP(ctx context.Context, otp PasswordResetOTP) error
+	ConsumePasswordResetOTP(ctx context.Context, userID, otpHash string) error
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session Session) error
+	IsActive(ctx context.Context, id string) (bool, error)
+	Revoke(ctx context.Context, id string) error
+	RevokeAllByUserID(ctx context.Context, userID string) (int64, error)
+}
+
+type CategoryRepository interface {
+	List(ctx context.Context) ([]Category, error)
+	FindByID(ctx context.Context, id string) (Category, error)
+	Create(ctx context.Context, category Category) (Category, error)
+	Update(ctx context.Context, category Category) (Category, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type ListingRepository interface {
+	Create(ctx context.Context, listing Listing) (Listing, error)
+	FindByID(ctx context.Context, id string) (Listing, error)
+	ListBySeller(ctx context.Context, sellerID string) ([]Listing, error)
+	Update(ctx context.Context, listing Listing, updatedBy string) (Listing, error)
+	DeleteByID(ctx context.Context, id, updatedBy string) error
+}
+
+var (
+	_ UserRepository          = UserStore{}
+	_ PasswordResetRepository = UserStore{}
+	_ SessionRepository       = SessionStore{}
+	_ CategoryRepository      = CategoryStore{}
+	_ ListingRepository       = ListingStore{}
+)
@@ -0,0 +1,102 @@
+package models_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/migrate"
+	"resellution/backend/internal/models"
+	"resellution/backend/internal/models/repotest"
+	"resellution/backend/migrations"
+)
+
+// TestConformance runs the repository suite against Postgres when TEST_DATABASE_URL
+// points at a scratch database. Its tables are truncated before every test.
+func TestConformance(t *testing.T) {
+	database := openTestDatabase(t)
+	ctx := context.Background()
+
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		if _, err := database.ExecContext(ctx, `TRUNCATE users, categories CASCADE`); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		users := models.UserStore{DB: database}
+		return repotest.Repositories{
+			Users:          users,
+			PasswordResets: users,
+			Sessions:       models.SessionStore{DB: database},
+			Categories:     models.CategoryStore{DB: database},
+			Listings:       models.ListingStore{DB: database},
+			Tx:             db.TxManager{DB: database},
+		}
+	})
+}
+
+func TestPurgeExpired(t *testing.T) {
+	database := openTestDatabase(t)
+	ctx := context.Background()
+	if _, err := database.ExecContext(ctx, `TRUNCATE users, categories CASCADE`); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	users := models.UserStore{DB: database}
+	sessions := models.SessionStore{DB: database}
+	user, err := users.Create(ctx, models.User{ID: uuid.NewString(), Email: "purge@example.com", PasswordHash: "hash", FullName: "Purge Test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for i, expiresAt := range []time.Time{now.Add(-time.Hour), now.Add(time.Hour)} {
+		if err := users.CreatePasswordResetOTP(ctx, models.PasswordResetOTP{ID: uuid.NewString(), UserID: user.ID, OTPHash: "otp", ExpiresAt: expiresAt, MaxAttempts: 5}); err != nil {
+			t.Fatal(err)
+		}
+		if err := users.CreatePasswordResetToken(ctx, models.PasswordResetToken{ID: uuid.NewString(), UserID: user.ID, TokenHash: fmt.Sprint("token-", i), ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+		if err := sessions.Create(ctx, models.Session{ID: uuid.NewString(), UserID: user.ID, TokenFingerprint: fmt.Sprint("fp-", i), ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tokens, otps, err := users.PurgePasswordResets(ctx, now)
+	if err != nil || tokens != 1 || otps != 1 {
+		t.Fatalf("PurgePasswordResets() = %d, %d, %v; want 1, 1", tokens, otps, err)
+	}
+	if purged, err := sessions.PurgeExpired(ctx, now); err != nil || purged != 1 {
+		t.Fatalf("PurgeExpired() = %d, %v; want 1", purged, err)
+	}
+	// Revoking the remaining session makes it purgeable once the cutoff passes it.
+	if _, err := sessions.RevokeAllByUserID(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := sessions.PurgeExpired(ctx, time.Now().Add(time.Minute)); err != nil || purged != 1 {
+		t.Fatalf("PurgeExpired() after revoke = %d, %v; want 1", purged, err)
+	}
+}
+
+// openTestDatabase connects to TEST_DATABASE_URL and migrates it, or skips the test.
+func openTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := db.Connect(databaseURL)
+	if err != nil {
+		t.Fatalf("connect test database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	if _, err := (migrate.Migrator{DB: database, FS: migrations.FS}).Up(context.Background()); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return database
+}
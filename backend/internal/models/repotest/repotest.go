@@ -0,0 +1,560 @@
+// Package repotest is a conformance suite for the models repositories. It runs against
+// the Postgres stores and the in-memory ones in internal/memstore so both keep the same
+// semantics.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/models"
+)
+
+// Repositories is one implementation of every repository, sharing the same data.
+type Repositories struct {
+	Users          models.UserRepository
+	PasswordResets models.PasswordResetRepository
+	Sessions       models.SessionRepository
+	Categories     models.CategoryRepository
+	Listings       models.ListingRepository
+	Tx             db.Transactor
+}
+
+// Run runs the suite. newRepos is called for every test and must return repositories
+// over empty tables (roles and permissions seeded as by the migrations).
+func Run(t *testing.T, newRepos func(t *testing.T) Repositories) {
+	tests := map[string]func(t *testing.T, r Repositories){
+		"UserCreateAndFind":       testUserCreateAndFind,
+		"UserEmailUnique":         testUserEmailUnique,
+		"UserSoftDelete":          testUserSoftDelete,
+		"UserAdminActions":        testUserAdminActions,
+		"UserProfile":             testUserProfile,
+		"UserRoles":               testUserRoles,
+		"UserSearch":              testUserSearch,
+		"PasswordResetToken":      testPasswordResetToken,
+		"PasswordResetOTP":        testPasswordResetOTP,
+		"PasswordResetOTPLockout": testPasswordResetOTPLockout,
+		"Sessions":                testSessions,
+		"Categories":              testCategories,
+		"Listings":                testListings,
+		"Transactions":            testTransactions,
+	}
+
+	names := make([]string, 0, len(tests))
+	for name := range tests {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			tests[name](t, newRepos(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, r Repositories, email string) models.User {
+	t.Helper()
+	user, err := r.Users.Create(context.Background(), models.User{
+		ID:           uuid.NewString(),
+		Email:        email,
+		PasswordHash: "hash",
+		FullName:     "Test User",
+	})
+	if err != nil {
+		t.Fatalf("Create(%s): %v", email, err)
+	}
+	return user
+}
+
+func expectErr(t *testing.T, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+}
+
+func expectOK(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func testUserCreateAndFind(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	created := createUser(t, r, "Asha@Example.com")
+	if created.Email != "asha@example.com" || created.Role != models.RoleUser || created.CreatedAt.IsZero() {
+		t.Fatalf("unexpected created user %+v", created)
+	}
+
+	byEmail, err := r.Users.FindByEmail(ctx, "ASHA@example.com")
+	expectOK(t, err)
+	byID, err := r.Users.FindByID(ctx, created.ID)
+	expectOK(t, err)
+	locked, err := r.Users.FindByIDForUpdate(ctx, created.ID)
+	expectOK(t, err)
+	if byEmail.ID != created.ID || byID.Email != created.Email || byID.PasswordHash != "hash" || !locked.UpdatedAt.Equal(byID.UpdatedAt) {
+		t.Fatalf("lookups disagree: %+v %+v %+v", byEmail, byID, locked)
+	}
+
+	_, err = r.Users.FindByID(ctx, uuid.NewString())
+	expectErr(t, err, models.ErrUserNotFound)
+	_, err = r.Users.FindByEmail(ctx, "nobody@example.com")
+	expectErr(t, err, models.ErrUserNotFound)
+}
+
+func testUserEmailUnique(t *testing.T, r Repositories) {
+	user := createUser(t, r, "dup@example.com")
+	_, err := r.Users.Create(context.Background(), models.User{
+		ID: uuid.NewString(), Email: "DUP@example.com", PasswordHash: "hash", FullName: "Other",
+	})
+	expectErr(t, err, models.ErrEmailTaken)
+
+	// Deactivated accounts keep their email.
+	expectOK(t, r.Users.DeactivateByID(context.Background(), user.ID, user.ID))
+	_, err = r.Users.Create(context.Background(), models.User{
+		ID: uuid.NewString(), Email: "dup@example.com", PasswordHash: "hash", FullName: "Other",
+	})
+	expectErr(t, err, models.ErrEmailTaken)
+}
+
+func testUserSoftDelete(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	user := createUser(t, r, "soft@example.com")
+
+	expectOK(t, r.Users.DeactivateByID(ctx, user.ID, user.ID))
+	expectErr(t, r.Users.DeactivateByID(ctx, user.ID, user.ID), models.ErrUserNotFound)
+
+	_, err := r.Users.FindByID(ctx, user.ID)
+	expectErr(t, err, models.ErrUserNotFound)
+	_, err = r.Users.FindByEmail(ctx, user.Email)
+	expectErr(t, err, models.ErrUserNotFound)
+	deleted, err := r.Users.FindByIDIncludingDeleted(ctx, user.ID)
+	expectOK(t, err)
+	if deleted.DeletedAt == nil || deleted.UpdatedBy != user.ID {
+		t.Fatalf("expected deleted_at and updated_by to be set, got %+v", deleted)
+	}
+
+	// Updates only apply to active users.
+	expectErr(t, r.Users.UpdatePasswordHashByID(ctx, user.ID, "new", user.ID), models.ErrUserNotFound)
+	expectErr(t, r.Users.Suspend(ctx, user.ID, "spam", user.ID), models.ErrUserNotFound)
+	expectErr(t, r.Users.SetRole(ctx, user.ID, models.RoleAdmin, user.ID), models.ErrUserNotFound)
+
+	expectOK(t, r.Users.RestoreByID(ctx, user.ID, user.ID))
+	expectErr(t, r.Users.RestoreByID(ctx, user.ID, user.ID), models.ErrUserNotFound)
+	restored, err := r.Users.FindByEmail(ctx, user.Email)
+	expectOK(t, err)
+	if restored.DeletedAt != nil {
+		t.Fatal("expected restored user to have no deleted_at")
+	}
+}
+
+func testUserAdminActions(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	user := createUser(t, r, "admin-actions@example.com")
+
+	expectOK(t, r.Users.Suspend(ctx, user.ID, "spam", user.ID))
+	got, err := r.Users.FindByID(ctx, user.ID)
+	expectOK(t, err)
+	if got.SuspendedAt == nil || got.SuspendedReason != "spam" {
+		t.Fatalf("expected suspension, got %+v", got)
+	}
+
+	expectOK(t, r.Users.Unsuspend(ctx, user.ID, user.ID))
+	got, err = r.Users.FindByID(ctx, user.ID)
+	expectOK(t, err)
+	if got.SuspendedAt != nil || got.SuspendedReason != "" {
+		t.Fatalf("expected suspension cleared, got %+v", got)
+	}
+
+	expectOK(t, r.Users.RequirePasswordReset(ctx, user.ID, user.ID))
+	got, _ = r.Users.FindByID(ctx, user.ID)
+	if !got.PasswordResetRequired {
+		t.Fatal("expected password reset to be required")
+	}
+	expectOK(t, r.Users.UpdatePasswordHashByID(ctx, user.ID, "new-hash", user.ID))
+	got, _ = r.Users.FindByID(ctx, user.ID)
+	if got.PasswordResetRequired || got.PasswordHash != "new-hash" {
+		t.Fatalf("expected new hash and reset cleared, got %+v", got)
+	}
+
+	expectOK(t, r.Users.MarkEmailVerified(ctx, user.ID, user.ID))
+	got, _ = r.Users.FindByID(ctx, user.ID)
+	if !got.IsVerified {
+		t.Fatal("expected verified user")
+	}
+
+	expectErr(t, r.Users.Suspend(ctx, uuid.NewString(), "", user.ID), models.ErrUserNotFound)
+}
+
+func testUserProfile(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	user := createUser(t, r, "profile@example.com")
+
+	name, city, blank := "  Meera Nair ", " Kochi ", "   "
+	updated, err := r.Users.UpdateProfile(ctx, user.ID, user.ID, models.ProfileUpdate{FullName: &name, City: &city})
+	expectOK(t, err)
+	if updated.FullName != "Meera Nair" || updated.City != "Kochi" || updated.UpdatedBy != user.ID {
+		t.Fatalf("unexpected profile %+v", updated)
+	}
+
+	// A blank name is ignored; a blank city clears it.
+	updated, err = r.Users.UpdateProfile(ctx, user.ID, user.ID, models.ProfileUpdate{FullName: &blank, City: &blank})
+	expectOK(t, err)
+	if updated.FullName != "Meera Nair" || updated.City != "" {
+		t.Fatalf("unexpected profile %+v", updated)
+	}
+
+	stored, _ := r.Users.FindByID(ctx, user.ID)
+	if stored.FullName != "Meera Nair" || stored.City != "" {
+		t.Fatalf("update not persisted: %+v", stored)
+	}
+
+	_, err = r.Users.UpdateProfile(ctx, uuid.NewString(), user.ID, models.ProfileUpdate{})
+	expectErr(t, err, models.ErrUserNotFound)
+}
+
+func testUserRoles(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	user := createUser(t, r, "roles@example.com")
+
+	permissions, err := r.Users.PermissionsForRole(ctx, models.RoleAdmin)
+	expectOK(t, err)
+	if !slices.Contains(permissions, models.PermissionUsersRead) || !slices.IsSorted(permissions) {
+		t.Fatalf("unexpected admin permissions %v", permissions)
+	}
+	permissions, err = r.Users.PermissionsForRole(ctx, models.RoleUser)
+	expectOK(t, err)
+	if len(permissions) != 0 {
+		t.Fatalf("expected no permissions for users, got %v", permissions)
+	}
+
+	expectOK(t, r.Users.SetRole(ctx, user.ID, models.RoleModerator, user.ID))
+	got, _ := r.Users.FindByID(ctx, user.ID)
+	if got.Role != models.RoleModerator {
+		t.Fatalf("expected moderator, got %s", got.Role)
+	}
+
+	expectErr(t, r.Users.SetRole(ctx, user.ID, "superuser", user.ID), models.ErrRoleNotFound)
+	expectErr(t, r.Users.SetRole(ctx, uuid.NewString(), models.RoleAdmin, user.ID), models.ErrUserNotFound)
+
+	expectOK(t, r.Users.SetRoleByEmail(ctx, "ROLES@example.com", models.RoleAdmin))
+	got, _ = r.Users.FindByID(ctx, user.ID)
+	if got.Role != models.RoleAdmin {
+		t.Fatalf("expected admin, got %s", got.Role)
+	}
+}
+
+func testUserSearch(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	first := createUser(t, r, "search-one@example.com")
+	createUser(t, r, "search-two@example.com")
+	createUser(t, r, "other@example.com")
+	expectOK(t, r.Users.DeactivateByID(ctx, first.ID, first.ID))
+
+	users, total, err := r.Users.SearchUsers(ctx, models.UserFilter{Email: "SEARCH", Limit: 10})
+	expectOK(t, err)
+	if total != 1 || len(users) != 1 || users[0].Email != "search-two@example.com" {
+		t.Fatalf("expected only the active match, got total=%d %+v", total, users)
+	}
+
+	users, total, err = r.Users.SearchUsers(ctx, models.UserFilter{Email: "search", IncludeDeleted: true, Limit: 1})
+	expectOK(t, err)
+	if total != 2 || len(users) != 1 {
+		t.Fatalf("expected 1 of 2 matches, got total=%d len=%d", total, len(users))
+	}
+
+	// LIKE wildcards in the filter are matched literally.
+	users, _, err = r.Users.SearchUsers(ctx, models.UserFilter{Email: "%", Limit: 10})
+	expectOK(t, err)
+	if len(users) != 0 {
+		t.Fatalf("expected no matches for a literal %%, got %d", len(users))
+	}
+
+	users, _, err = r.Users.SearchUsers(ctx, models.UserFilter{CreatedAfter: time.Now().Add(time.Hour), Limit: 10})
+	expectOK(t, err)
+	if len(users) != 0 {
+		t.Fatalf("expected no users created in the future, got %d", len(users))
+	}
+}
+
+func testPasswordResetToken(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	user := createUser(t, r, "token@example.com")
+
+	expectOK(t, r.PasswordResets.CreatePasswordResetToken(ctx, models.PasswordResetToken{
+		ID: uuid.NewString(), UserID: user.ID, TokenHash: "valid", ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	expectOK(t, r.PasswordResets.CreatePasswordResetToken(ctx, models.PasswordResetToken{
+		ID: uuid.NewString(), UserID: user.ID, TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+	if err := r.PasswordResets.CreatePasswordResetToken(ctx, models.PasswordResetToken{
+		ID: uuid.NewString(), UserID: user.ID, TokenHash: "valid", ExpiresAt: time.Now().Add(time.Hour),
+	}); err == nil {
+		t.Fatal("expected duplicate token hash to be rejected")
+	}
+
+	userID, err := r.PasswordResets.ConsumePasswordResetToken(ctx, "valid")
+	expectOK(t, err)
+	if userID != user.ID {
+		t.Fatalf("got user %s, want %s", userID, user.ID)
+	}
+	_, err = r.PasswordResets.ConsumePasswordResetToken(ctx, "valid")
+	expectErr(t, err, models.ErrPasswordResetTokenInvalid)
+	_, err = r.PasswordResets.ConsumePasswordResetToken(ctx, "expired")
+	expectErr(t, err, models.ErrPasswordResetTokenInvalid)
+
+	expectOK(t, r.PasswordResets.CreatePasswordResetToken(ctx, models.PasswordResetToken{
+		ID: uuid.NewString(), UserID: user.ID, TokenHash: "invalidated", ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	expectOK(t, r.PasswordResets.InvalidateActivePasswordResetTokensByUserID(ctx, user.ID))
+	_, err = r.PasswordResets.ConsumePasswordResetToken(ctx, "invalidated")
+	expectErr(t, err, models.ErrPasswordResetTokenInvalid)
+}
+
+func testPasswordResetOTP(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	user := createUser(t, r, "otp@example.com")
+
+	_, recent, err := r.PasswordResets.GetLastPasswordResetRequestTime(ctx, user.ID, 5)
+	expectOK(t, err)
+	if recent {
+		t.Fatal("expected no recent request before creating an otp")
+	}
+
+	expectOK(t, r.PasswordResets.CreatePasswordResetOTP(ctx, models.PasswordResetOTP{
+		ID: uuid.NewString(), UserID: user.ID, OTPHash: "old", ExpiresAt: time.Now().Add(time.Hour), MaxAttempts: 5,
+	}))
+	expectOK(t, r.PasswordResets.InvalidateActivePasswordResetOTPsByUserID(ctx, user.ID))
+	expectErr(t, r.PasswordResets.ConsumePasswordResetOTP(ctx, user.ID, "old"), models.ErrPasswordResetOTPInvalid)
+
+	expectOK(t, r.PasswordResets.CreatePasswordResetOTP(ctx, models.PasswordResetOTP{
+		ID: uuid.NewString(), UserID: user.ID, OTPHash: "right", ExpiresAt: time.Now().Add(time.Hour), MaxAttempts: 5,
+	}))
+	last, recent, err := r.PasswordResets.GetLastPasswordResetRequestTime(ctx, user.ID, 5)
+	expectOK(t, err)
+	if !recent || time.Since(last) > time.Minute {
+		t.Fatalf("expected a recent request, got %v %v", last, recent)
+	}
+	_, recent, _ = r.PasswordResets.GetLastPasswordResetRequestTime(ctx, user.ID, 0)
+	if recent {
+		t.Fatal("expected no cooldown when cooldown is disabled")
+	}
+
+	expectErr(t, r.PasswordResets.ConsumePasswordResetOTP(ctx, user.ID, "wrong"), models.ErrPasswordResetOTPInvalid)
+	expectOK(t, r.PasswordResets.ConsumePasswordResetOTP(ctx, user.ID, "right"))
+	expectErr(t, r.PasswordResets.ConsumePasswordResetOTP(ctx, user.ID, "right"), models.ErrPasswordResetOTPInvalid)
+
+	expectOK(t, r.PasswordResets.CreatePasswordResetOTP(ctx, models.PasswordResetOTP{
+		ID: uuid.NewString(), UserID: user.ID, OTPHash: "expired", ExpiresAt: time.Now().Add(-time.Minute), MaxAttempts: 5,
+	}))
+	expectErr(t, r.PasswordResets.ConsumePasswordResetOTP(ctx, user.ID, "expired"), models.ErrPasswordResetOTPInvalid)
+}
+
+func testPasswordResetOTPLockout(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	user := createUser(t, r, "lockout@example.com")
+
+	expectOK(t, r.PasswordResets.CreatePasswordResetOTP(ctx, models.PasswordResetOTP{
+		ID: uuid.NewString(), UserID: user.ID, OTPHash: "right", ExpiresAt: time.Now().Add(time.Hour), MaxAttempts: 2,
+	}))
+	expectErr(t, r.PasswordResets.ConsumePasswordResetOTP(ctx, user.ID, "wrong"), models.ErrPasswordResetOTPInvalid)
+	expectErr(t, r.PasswordResets.ConsumePasswordResetOTP(ctx, user.ID, "wrong"), models.ErrPasswordResetOTPInvalid)
+	// The OTP is used up once the attempts run out, even with the right code.
+	expectErr(t, r.PasswordResets.ConsumePasswordResetOTP(ctx, user.ID, "right"), models.ErrPasswordResetOTPInvalid)
+}
+
+func testSessions(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	user := createUser(t, r, "sessions@example.com")
+
+	newSession := func(fingerprint string, expiresAt time.Time) models.Session {
+		t.Helper()
+		session := models.Session{ID: uuid.NewString(), UserID: user.ID, TokenFingerprint: fingerprint, ExpiresAt: expiresAt}
+		expectOK(t, r.Sessions.Create(ctx, session))
+		return session
+	}
+	first := newSession("one", time.Now().Add(time.Hour))
+	second := newSession("two", time.Now().Add(time.Hour))
+	expired := newSession("three", time.Now().Add(-time.Minute))
+
+	if err := r.Sessions.Create(ctx, models.Session{ID: uuid.NewString(), UserID: user.ID, TokenFingerprint: "one", ExpiresAt: time.Now().Add(time.Hour)}); err == nil {
+		t.Fatal("expected duplicate fingerprint to be rejected")
+	}
+
+	for _, tc := range []struct {
+		id   string
+		want bool
+	}{{first.ID, true}, {expired.ID, false}, {uuid.NewString(), false}} {
+		active, err := r.Sessions.IsActive(ctx, tc.id)
+		expectOK(t, err)
+		if active != tc.want {
+			t.Fatalf("IsActive(%s) = %v, want %v", tc.id, active, tc.want)
+		}
+	}
+
+	expectOK(t, r.Sessions.Revoke(ctx, first.ID))
+	expectOK(t, r.Sessions.Revoke(ctx, first.ID))
+	if active, _ := r.Sessions.IsActive(ctx, first.ID); active {
+		t.Fatal("expected revoked session to be inactive")
+	}
+
+	revoked, err := r.Sessions.RevokeAllByUserID(ctx, user.ID)
+	expectOK(t, err)
+	if revoked != 1 {
+		t.Fatalf("expected only the remaining active session to be revoked, got %d", revoked)
+	}
+	if active, _ := r.Sessions.IsActive(ctx, second.ID); active {
+		t.Fatal("expected session to be revoked")
+	}
+}
+
+func testCategories(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	parent, err := r.Categories.Create(ctx, models.Category{ID: uuid.NewString(), Name: "Vehicles", Slug: "vehicles"})
+	expectOK(t, err)
+	if parent.CreatedAt.IsZero() {
+		t.Fatal("expected created_at to be set")
+	}
+	child, err := r.Categories.Create(ctx, models.Category{ID: uuid.NewString(), Name: "Bikes", Slug: "bikes", ParentID: parent.ID})
+	expectOK(t, err)
+
+	_, err = r.Categories.Create(ctx, models.Category{ID: uuid.NewString(), Name: "Cars", Slug: "vehicles"})
+	expectErr(t, err, models.ErrCategoryConflict)
+	_, err = r.Categories.Create(ctx, models.Category{ID: uuid.NewString(), Name: "Cars", Slug: "cars", ParentID: uuid.NewString()})
+	expectErr(t, err, models.ErrCategoryParentNotFound)
+
+	categories, err := r.Categories.List(ctx)
+	expectOK(t, err)
+	if len(categories) != 2 || categories[0].Name != "Bikes" {
+		t.Fatalf("expected categories sorted by name, got %+v", categories)
+	}
+
+	child.Name = "Motorbikes"
+	updated, err := r.Categories.Update(ctx, child)
+	expectOK(t, err)
+	if updated.Name != "Motorbikes" || !updated.CreatedAt.Equal(child.CreatedAt) {
+		t.Fatalf("unexpected update result %+v", updated)
+	}
+	child.Slug = "vehicles"
+	_, err = r.Categories.Update(ctx, child)
+	expectErr(t, err, models.ErrCategoryConflict)
+	_, err = r.Categories.Update(ctx, models.Category{ID: uuid.NewString(), Name: "Missing", Slug: "missing"})
+	expectErr(t, err, models.ErrCategoryNotFound)
+
+	expectOK(t, r.Categories.Delete(ctx, parent.ID))
+	expectErr(t, r.Categories.Delete(ctx, parent.ID), models.ErrCategoryNotFound)
+	orphan, err := r.Categories.FindByID(ctx, child.ID)
+	expectOK(t, err)
+	if orphan.ParentID != "" {
+		t.Fatalf("expected parent to be cleared, got %q", orphan.ParentID)
+	}
+}
+
+func testListings(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	seller := createUser(t, r, "seller@example.com")
+	category, err := r.Categories.Create(ctx, models.Category{ID: uuid.NewString(), Name: "Furniture", Slug: "furniture"})
+	expectOK(t, err)
+
+	newListing := func(title string) models.Listing {
+		return models.Listing{
+			ID: uuid.NewString(), SellerID: seller.ID, CategoryID: category.ID, Title: title,
+			Description: "Solid teak", Condition: "good", Price: 4500, City: "Pune", State: "Maharashtra",
+		}
+	}
+
+	created, err := r.Listings.Create(ctx, newListing("Dining table"))
+	expectOK(t, err)
+	if created.Status != models.ListingStatusActive || created.Currency != "INR" || created.CreatedAt.IsZero() {
+		t.Fatalf("unexpected defaults %+v", created)
+	}
+	second, err := r.Listings.Create(ctx, newListing("Bookshelf"))
+	expectOK(t, err)
+
+	invalid := newListing("Broken")
+	invalid.Condition = "mint"
+	if _, err := r.Listings.Create(ctx, invalid); err == nil {
+		t.Fatal("expected invalid condition to be rejected")
+	}
+	invalid = newListing("Orphan")
+	invalid.SellerID = uuid.NewString()
+	if _, err := r.Listings.Create(ctx, invalid); err == nil {
+		t.Fatal("expected missing seller to be rejected")
+	}
+
+	created.Price = 4000
+	created.Status = models.ListingStatusReserved
+	updated, err := r.Listings.Update(ctx, created, seller.ID)
+	expectOK(t, err)
+	if updated.Price != 4000 || updated.Status != models.ListingStatusReserved || updated.UpdatedBy != seller.ID {
+		t.Fatalf("unexpected update result %+v", updated)
+	}
+
+	expectOK(t, r.Listings.DeleteByID(ctx, second.ID, seller.ID))
+	expectErr(t, r.Listings.DeleteByID(ctx, second.ID, seller.ID), models.ErrListingNotFound)
+	_, err = r.Listings.FindByID(ctx, second.ID)
+	expectErr(t, err, models.ErrListingNotFound)
+	_, err = r.Listings.Update(ctx, second, seller.ID)
+	expectErr(t, err, models.ErrListingNotFound)
+
+	listings, err := r.Listings.ListBySeller(ctx, seller.ID)
+	expectOK(t, err)
+	if len(listings) != 1 || listings[0].ID != created.ID {
+		t.Fatalf("expected only the remaining listing, got %+v", listings)
+	}
+
+	// Deleting the category keeps the listing without a category.
+	expectOK(t, r.Categories.Delete(ctx, category.ID))
+	found, err := r.Listings.FindByID(ctx, created.ID)
+	expectOK(t, err)
+	if found.CategoryID != "" {
+		t.Fatalf("expected category to be cleared, got %q", found.CategoryID)
+	}
+}
+
+func testTransactions(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	var rolledBack models.User
+	err := r.Tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		rolledBack, err = r.Users.Create(ctx, models.User{
+			ID: uuid.NewString(), Email: "rollback@example.com", PasswordHash: "hash", FullName: "Rollback",
+		})
+		if err != nil {
+			return err
+		}
+		// Reads inside the transaction see its writes.
+		if _, err := r.Users.FindByID(ctx, rolledBack.ID); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	expectErr(t, err, errRollback)
+	_, err = r.Users.FindByID(ctx, rolledBack.ID)
+	expectErr(t, err, models.ErrUserNotFound)
+
+	var committed models.User
+	err = r.Tx.WithTx(ctx, func(ctx context.Context) error {
+		return r.Tx.WithTx(ctx, func(ctx context.Context) error {
+			var err error
+			committed, err = r.Users.Create(ctx, models.User{
+				ID: uuid.NewString(), Email: "commit@example.com", PasswordHash: "hash", FullName: "Commit",
+			})
+			return err
+		})
+	})
+	expectOK(t, err)
+	if _, err := r.Users.FindByEmail(ctx, strings.ToUpper(committed.Email)); err != nil {
+		t.Fatalf("expected committed user, got %v", err)
+	}
+}
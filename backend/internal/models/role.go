@@ -0,0 +1,112 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrRoleNotFound = errors.New("role not found")
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// RoleRank orders the built-in roles by authority, so staff cannot act on accounts
+// above their own. Unknown roles rank with RoleUser.
+func RoleRank(role string) int {
+	switch role {
+	case RoleAdmin:
+		return 2
+	case RoleModerator:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Permissions granted to roles through the role_permissions table.
+const (
+	PermissionUsersRead          = "users.read"
+	PermissionUsersModerate      = "users.moderate"
+	PermissionRolesManage        = "roles.manage"
+	PermissionCategoriesManage   = "categories.manage"
+	PermissionListingsModerate   = "listings.moderate"
+	PermissionAuditRead          = "audit.read"
+	PermissionFeatureFlagsManage = "feature_flags.manage"
+)
+
+func (s UserStore) PermissionsForRole(ctx context.Context, role string) ([]string, error) {
+	query := `
+		SELECT permission
+		FROM role_permissions
+		WHERE role = $1
+		ORDER BY permission
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+// SetRole assigns role to an active user. Tokens issued before the change keep their old
+// claims, so callers changing the role of an existing user revoke their sessions in the
+// same transaction.
+func (s UserStore) SetRole(ctx context.Context, userID, role, updatedBy string) error {
+	query := `
+		UPDATE users
+		SET role = $2, updated_at = NOW(), updated_by = $3
+		WHERE id = $1
+		  AND deleted_at IS NULL
+	`
+
+	result, err := s.conn(ctx).ExecContext(ctx, query, userID, role, nullIfEmpty(updatedBy))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetRoleByEmail is used to bootstrap the first admin, before any admin exists to
+// call the role API.
+func (s UserStore) SetRoleByEmail(ctx context.Context, email, role string) error {
+	user, err := s.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+	return s.SetRole(ctx, user.ID, role, "")
+}
+
+func isForeignKeyViolation(err error) bool {
+	lowerErr := strings.ToLower(err.Error())
+	return strings.Contains(lowerErr, "foreign key") || strings.Contains(lowerErr, "sqlstate 23503")
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"resellution/backend/internal/db"
+)
+
+type Session struct {
+	ID               string
+	UserID           string
+	TokenFingerprint string
+	IPAddress        string
+	UserAgent        string
+	ExpiresAt        time.Time
+}
+
+type SessionStore struct {
+	DB *sql.DB
+}
+
+func (s SessionStore) conn(ctx context.Context) db.Querier {
+	return db.Conn(ctx, s.DB)
+}
+
+func (s SessionStore) Create(ctx context.Context, session Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, token_fingerprint, ip_address, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := s.conn(ctx).ExecContext(ctx, query,
+		session.ID,
+		session.UserID,
+		session.TokenFingerprint,
+		nullIfEmpty(session.IPAddress),
+		nullIfEmpty(session.UserAgent),
+		session.ExpiresAt,
+	)
+	return err
+}
+
+// IsActive reports whether the session exists, has not been revoked and has not expired.
+func (s SessionStore) IsActive(ctx context.Context, id string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM sessions
+			WHERE id = $1
+			  AND revoked_at IS NULL
+			  AND expires_at > NOW()
+		)
+	`
+
+	var active bool
+	if err := s.conn(ctx).QueryRowContext(ctx, query, id).Scan(&active); err != nil {
+		return false, err
+	}
+	return active, nil
+}
+
+func (s SessionStore) Revoke(ctx context.Context, id string) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	return err
+}
+
+// RevokeAllByUserID revokes every active session of the user and returns how many were
+// revoked.
+func (s SessionStore) RevokeAllByUserID(ctx context.Context, userID string) (int64, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE user_id = $1
+		  AND revoked_at IS NULL
+		  AND expires_at > NOW()
+	`
+
+	result, err := s.conn(ctx).ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeExpired deletes sessions that expired or were revoked before cutoff and returns
+// how many were removed.
+func (s SessionStore) PurgeExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < $1 OR revoked_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
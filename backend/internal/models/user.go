@@ -6,28 +6,45 @@ import (
 	"errors"
 	"strings"
 	"time"
+
+	"resellution/backend/internal/db"
 )
 
 var ErrUserNotFound = errors.New("user not found")
+var ErrEmailTaken = errors.New("email already registered")
 var ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
 var ErrPasswordResetOTPInvalid = errors.New("password reset otp is invalid or expired")
 
 type User struct {
-	ID               string    `json:"id"`
-	Email            string    `json:"email"`
-	PasswordHash     string    `json:"-"`
-	FullName         string    `json:"full_name"`
-	City             string    `json:"city,omitempty"`
-	Bio              string    `json:"bio,omitempty"`
-	ProfileImageURL  string    `json:"profile_image_url,omitempty"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                    string     `json:"id"`
+	Email                 string     `json:"email"`
+	PasswordHash          string     `json:"-"`
+	FullName              string     `json:"full_name"`
+	Phone                 string     `json:"phone,omitempty"`
+	City                  string     `json:"city,omitempty"`
+	Bio                   string     `json:"bio,omitempty"`
+	ProfileImageURL       string     `json:"profile_image_url,omitempty"`
+	Role                  string     `json:"role"`
+	IsVerified            bool       `json:"is_verified"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	SuspendedReason       string     `json:"suspended_reason,omitempty"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	UpdatedBy             string     `json:"updated_by,omitempty"`
 }
 
 type UserStore struct {
 	DB *sql.DB
 }
 
+// conn traces every statement; the user store sits on the login and password reset
+// paths, where query time matters most.
+func (s UserStore) conn(ctx context.Context) db.Querier {
+	return db.Traced(db.Conn(ctx, s.DB), "users")
+}
+
 type PasswordResetToken struct {
 	ID        string
 	UserID    string
@@ -47,12 +64,16 @@ func (s UserStore) Create(ctx context.Context, user User) (User, error) {
 	query := `
 		INSERT INTO users (id, email, password_hash, full_name)
 		VALUES ($1, $2, $3, $4)
-		RETURNING created_at, updated_at
+		RETURNING role, created_at, updated_at
 	`
 
-	err := s.DB.QueryRowContext(ctx, query, user.ID, strings.ToLower(user.Email), user.PasswordHash, user.FullName).
-		Scan(&user.CreatedAt, &user.UpdatedAt)
+	err := s.conn(ctx).QueryRowContext(ctx, query, user.ID, strings.ToLower(user.Email), user.PasswordHash, user.FullName).
+		Scan(&user.Role, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
+		lowerErr := strings.ToLower(err.Error())
+		if strings.Contains(lowerErr, "duplicate") || strings.Contains(lowerErr, "unique") {
+			return User{}, ErrEmailTaken
+		}
 		return User{}, err
 	}
 
@@ -60,26 +81,38 @@ func (s UserStore) Create(ctx context.Context, user User) (User, error) {
 	return user, nil
 }
 
-func (s UserStore) FindByEmail(ctx context.Context, email string) (User, error) {
-	query := `
-		SELECT id, email, password_hash, full_name, city, bio, profile_image_url, created_at, updated_at
-		FROM users
-		WHERE email = $1
-		  AND deleted_at IS NULL
-	`
+const userColumns = `
+	id, email, password_hash, full_name, phone, city, bio, profile_image_url, role,
+	is_verified, password_reset_required, suspended_at, suspended_reason, deleted_at,
+	created_at, updated_at, updated_by
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
+func scanUser(row rowScanner) (User, error) {
 	var user User
-	var city, bio, profileImageURL sql.NullString
-	err := s.DB.QueryRowContext(ctx, query, strings.ToLower(email)).Scan(
+	var phone, city, bio, profileImageURL, suspendedReason, updatedBy sql.NullString
+	var suspendedAt, deletedAt sql.NullTime
+	err := row.Scan(
 		&user.ID,
 		&user.Email,
 		&user.PasswordHash,
 		&user.FullName,
+		&phone,
 		&city,
 		&bio,
 		&profileImageURL,
+		&user.Role,
+		&user.IsVerified,
+		&user.PasswordResetRequired,
+		&suspendedAt,
+		&suspendedReason,
+		&deletedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&updatedBy,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -88,44 +121,57 @@ func (s UserStore) FindByEmail(ctx context.Context, email string) (User, error)
 		return User{}, err
 	}
 
+	user.Phone = phone.String
 	user.City = city.String
 	user.Bio = bio.String
 	user.ProfileImageURL = profileImageURL.String
+	user.SuspendedReason = suspendedReason.String
+	user.UpdatedBy = updatedBy.String
+	if suspendedAt.Valid {
+		user.SuspendedAt = &suspendedAt.Time
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
 	return user, nil
 }
 
+// FindByEmail returns an active (not deactivated) user. Suspended users are returned so
+// callers can report the suspension.
+func (s UserStore) FindByEmail(ctx context.Context, email string) (User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE email = $1
+		  AND deleted_at IS NULL
+	`
+
+	return scanUser(s.conn(ctx).QueryRowContext(ctx, query, strings.ToLower(email)))
+}
+
 func (s UserStore) FindByID(ctx context.Context, id string) (User, error) {
 	query := `
-		SELECT id, email, password_hash, full_name, city, bio, profile_image_url, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE id = $1
 		  AND deleted_at IS NULL
 	`
 
-	var user User
-	var city, bio, profileImageURL sql.NullString
-	err := s.DB.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.FullName,
-		&city,
-		&bio,
-		&profileImageURL,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return User{}, ErrUserNotFound
-		}
-		return User{}, err
-	}
+	return scanUser(s.conn(ctx).QueryRowContext(ctx, query, id))
+}
 
-	user.City = city.String
-	user.Bio = bio.String
-	user.ProfileImageURL = profileImageURL.String
-	return user, nil
+// FindByIDForUpdate is FindByID that also locks the row until the transaction in ctx
+// ends, so a version check made on the result still holds when the write follows.
+func (s UserStore) FindByIDForUpdate(ctx context.Context, id string) (User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1
+		  AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	return scanUser(s.conn(ctx).QueryRowContext(ctx, query, id))
 }
 
 func (s UserStore) InvalidateActivePasswordResetTokensByUserID(ctx context.Context, userID string) error {
@@ -137,7 +183,7 @@ func (s UserStore) InvalidateActivePasswordResetTokensByUserID(ctx context.Conte
 		  AND expires_at > NOW()
 	`
 
-	_, err := s.DB.ExecContext(ctx, query, userID)
+	_, err := s.conn(ctx).ExecContext(ctx, query, userID)
 	return err
 }
 
@@ -147,46 +193,42 @@ func (s UserStore) CreatePasswordResetToken(ctx context.Context, token PasswordR
 		VALUES ($1, $2, $3, $4)
 	`
 
-	_, err := s.DB.ExecContext(ctx, query, token.ID, token.UserID, token.TokenHash, token.ExpiresAt)
+	_, err := s.conn(ctx).ExecContext(ctx, query, token.ID, token.UserID, token.TokenHash, token.ExpiresAt)
 	return err
 }
 
+// ConsumePasswordResetToken marks the token used and returns its user. It joins the
+// transaction carried by ctx, if any, so the token stays locked until the caller's
+// changes commit.
 func (s UserStore) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
-	tx, err := s.DB.BeginTx(ctx, nil)
-	if err != nil {
-		return "", err
-	}
-	defer tx.Rollback()
-
-	query := `
-		SELECT id, user_id
-		FROM password_reset_tokens
-		WHERE token_hash = $1
-		  AND used_at IS NULL
-		  AND expires_at > NOW()
-		ORDER BY created_at DESC
-		LIMIT 1
-		FOR UPDATE
-	`
-
-	var tokenID string
 	var userID string
-	err = tx.QueryRowContext(ctx, query, tokenHash).Scan(&tokenID, &userID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", ErrPasswordResetTokenInvalid
+	err := db.TxManager{DB: s.DB}.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			SELECT id, user_id
+			FROM password_reset_tokens
+			WHERE token_hash = $1
+			  AND used_at IS NULL
+			  AND expires_at > NOW()
+			ORDER BY created_at DESC
+			LIMIT 1
+			FOR UPDATE
+		`
+
+		var tokenID string
+		err := s.conn(ctx).QueryRowContext(ctx, query, tokenHash).Scan(&tokenID, &userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrPasswordResetTokenInvalid
+			}
+			return err
 		}
-		return "", err
-	}
 
-	if _, err := tx.ExecContext(ctx, `UPDATE password_reset_tokens SET used_at = NOW() WHERE id = $1`, tokenID); err != nil {
-		return "", err
-	}
-
-	if err := tx.Commit(); err != nil {
+		_, err = s.conn(ctx).ExecContext(ctx, `UPDATE password_reset_tokens SET used_at = NOW() WHERE id = $1`, tokenID)
+		return err
+	})
+	if err != nil {
 		return "", err
 	}
-
 	return userID, nil
 }
 
@@ -224,17 +266,17 @@ func (s UserStore) UpdateProfile(ctx context.Context, userID string, updatedBy s
 		UPDATE users
 		SET full_name = $2, city = $3, bio = $4, profile_image_url = $5, updated_at = NOW(), updated_by = $6
 		WHERE id = $1
-		RETURNING created_at, updated_at
+		RETURNING created_at, updated_at, updated_by
 	`
 
-	err = s.DB.QueryRowContext(ctx, query,
+	err = s.conn(ctx).QueryRowContext(ctx, query,
 		userID,
 		user.FullName,
 		nullIfEmpty(user.City),
 		nullIfEmpty(user.Bio),
 		nullIfEmpty(user.ProfileImageURL),
 		updatedBy,
-	).Scan(&user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.CreatedAt, &user.UpdatedAt, &user.UpdatedBy)
 	if err != nil {
 		return User{}, err
 	}
@@ -252,12 +294,12 @@ func nullIfEmpty(s string) interface{} {
 func (s UserStore) UpdatePasswordHashByID(ctx context.Context, userID, passwordHash, updatedBy string) error {
 	query := `
 		UPDATE users
-		SET password_hash = $2, updated_at = NOW(), updated_by = $3
+		SET password_hash = $2, password_reset_required = FALSE, updated_at = NOW(), updated_by = $3
 		WHERE id = $1
 		  AND deleted_at IS NULL
 	`
 
-	result, err := s.DB.ExecContext(ctx, query, userID, passwordHash, updatedBy)
+	result, err := s.conn(ctx).ExecContext(ctx, query, userID, passwordHash, nullIfEmpty(updatedBy))
 	if err != nil {
 		return err
 	}
@@ -283,7 +325,7 @@ func (s UserStore) DeactivateByID(ctx context.Context, userID, updatedBy string)
 		  AND deleted_at IS NULL
 	`
 
-	result, err := s.DB.ExecContext(ctx, query, userID, updatedBy)
+	result, err := s.conn(ctx).ExecContext(ctx, query, userID, nullIfEmpty(updatedBy))
 	if err != nil {
 		return err
 	}
@@ -317,7 +359,7 @@ func (s UserStore) GetLastPasswordResetRequestTime(ctx context.Context, userID s
 	`
 
 	var createdAt time.Time
-	err := s.DB.QueryRowContext(ctx, query, userID, cooldownMinutes).Scan(&createdAt)
+	err := s.conn(ctx).QueryRowContext(ctx, query, userID, cooldownMinutes).Scan(&createdAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return time.Time{}, false, nil
@@ -336,7 +378,7 @@ func (s UserStore) InvalidateActivePasswordResetOTPsByUserID(ctx context.Context
 		  AND expires_at > NOW()
 	`
 
-	_, err := s.DB.ExecContext(ctx, query, userID)
+	_, err := s.conn(ctx).ExecContext(ctx, query, userID)
 	return err
 }
 
@@ -346,64 +388,81 @@ func (s UserStore) CreatePasswordResetOTP(ctx context.Context, otp PasswordReset
 		VALUES ($1, $2, $3, $4, $5)
 	`
 
-	_, err := s.DB.ExecContext(ctx, query, otp.ID, otp.UserID, otp.OTPHash, otp.ExpiresAt, otp.MaxAttempts)
+	_, err := s.conn(ctx).ExecContext(ctx, query, otp.ID, otp.UserID, otp.OTPHash, otp.ExpiresAt, otp.MaxAttempts)
 	return err
 }
 
+// ConsumePasswordResetOTP checks otpHash against the user's latest active OTP and marks
+// it used. A wrong hash counts as an attempt, which is saved before
+// ErrPasswordResetOTPInvalid is returned unless the caller's transaction (carried by
+// ctx) rolls back; callers that want the attempt counted must commit.
 func (s UserStore) ConsumePasswordResetOTP(ctx context.Context, userID, otpHash string) error {
-	tx, err := s.DB.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
+	var mismatch bool
+	err := db.TxManager{DB: s.DB}.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			SELECT id, otp_hash, attempt_count, max_attempts
+			FROM password_reset_otps
+			WHERE user_id = $1
+			  AND used_at IS NULL
+			  AND expires_at > NOW()
+			ORDER BY created_at DESC
+			LIMIT 1
+			FOR UPDATE
+		`
+
+		var id string
+		var storedHash string
+		var attemptCount int
+		var maxAttempts int
+		err := s.conn(ctx).QueryRowContext(ctx, query, userID).Scan(&id, &storedHash, &attemptCount, &maxAttempts)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrPasswordResetOTPInvalid
+			}
+			return err
+		}
 
-	query := `
-		SELECT id, otp_hash, attempt_count, max_attempts
-		FROM password_reset_otps
-		WHERE user_id = $1
-		  AND used_at IS NULL
-		  AND expires_at > NOW()
-		ORDER BY created_at DESC
-		LIMIT 1
-		FOR UPDATE
-	`
+		if storedHash != otpHash {
+			mismatch = true
+			nextAttemptCount := attemptCount + 1
+			if nextAttemptCount >= maxAttempts {
+				_, err = s.conn(ctx).ExecContext(ctx, `UPDATE password_reset_otps SET attempt_count = $2, used_at = NOW() WHERE id = $1`, id, nextAttemptCount)
+			} else {
+				_, err = s.conn(ctx).ExecContext(ctx, `UPDATE password_reset_otps SET attempt_count = $2 WHERE id = $1`, id, nextAttemptCount)
+			}
+			return err
+		}
 
-	var id string
-	var storedHash string
-	var attemptCount int
-	var maxAttempts int
-	err = tx.QueryRowContext(ctx, query, userID).Scan(&id, &storedHash, &attemptCount, &maxAttempts)
+		_, err = s.conn(ctx).ExecContext(ctx, `UPDATE password_reset_otps SET used_at = NOW() WHERE id = $1`, id)
+		return err
+	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrPasswordResetOTPInvalid
-		}
 		return err
 	}
+	if mismatch {
+		return ErrPasswordResetOTPInvalid
+	}
+	return nil
+}
 
-	if storedHash != otpHash {
-		nextAttemptCount := attemptCount + 1
-		if nextAttemptCount >= maxAttempts {
-			if _, err := tx.ExecContext(ctx, `UPDATE password_reset_otps SET attempt_count = $2, used_at = NOW() WHERE id = $1`, id, nextAttemptCount); err != nil {
-				return err
-			}
-		} else {
-			if _, err := tx.ExecContext(ctx, `UPDATE password_reset_otps SET attempt_count = $2 WHERE id = $1`, id, nextAttemptCount); err != nil {
-				return err
-			}
+// PurgePasswordResets deletes password reset tokens and OTPs that expired or were used
+// before cutoff, and returns how many of each were removed.
+func (s UserStore) PurgePasswordResets(ctx context.Context, cutoff time.Time) (tokens, otps int64, err error) {
+	err = db.TxManager{DB: s.DB}.WithTx(ctx, func(ctx context.Context) error {
+		result, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM password_reset_tokens WHERE expires_at < $1 OR used_at < $1`, cutoff)
+		if err != nil {
+			return err
 		}
-
-		if err := tx.Commit(); err != nil {
+		if tokens, err = result.RowsAffected(); err != nil {
 			return err
 		}
-		return ErrPasswordResetOTPInvalid
-	}
 
-	if _, err := tx.ExecContext(ctx, `UPDATE password_reset_otps SET used_at = NOW() WHERE id = $1`, id); err != nil {
-		return err
-	}
-
-	if err := tx.Commit(); err != nil {
+		result, err = s.conn(ctx).ExecContext(ctx, `DELETE FROM password_reset_otps WHERE expires_at < $1 OR used_at < $1`, cutoff)
+		if err != nil {
+			return err
+		}
+		otps, err = result.RowsAffected()
 		return err
-	}
-	return nil
+	})
+	return tokens, otps, err
 }
@@ -1,68 +1,102 @@
 package observability
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"time"
+	"strings"
 )
 
-type Level string
+// LogConfig configures NewLogger.
+type LogConfig struct {
+	// Level is debug, info, warn or error.
+	Level string
+	// RedactKeys lists attribute keys whose values are masked. A key also matches
+	// attributes that end in "_" plus the key, so "email" covers "to_email". Emails and
+	// phone numbers inside other strings are masked when "email" and "phone" are listed.
+	RedactKeys []string
+	// Output defaults to stdout.
+	Output io.Writer
+}
 
-const (
-	LevelDebug Level = "debug"
-	LevelInfo  Level = "info"
-	LevelWarn  Level = "warn"
-	LevelError Level = "error"
-)
+// ParseLevel converts a configured level name into a slog.Level.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// NewLogger returns a JSON logger that adds the request ID, user ID and trace IDs
+// found in the context of *Context calls, and masks sensitive values.
+func NewLogger(cfg LogConfig) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	out := cfg.Output
+	if out == nil {
+		out = os.Stdout
+	}
 
-type LogEntry struct {
-	Timestamp string         `json:"timestamp"`
-	Level     Level          `json:"level"`
-	Message   string         `json:"message"`
-	Fields    map[string]any `json:"fields,omitempty"`
+	handler := slog.NewJSONHandler(out, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: newRedactor(cfg.RedactKeys).replaceAttr,
+	})
+	return slog.New(contextHandler{handler}), nil
 }
 
-type Logger struct {
-	minLevel Level
+// requestInfo is shared by every context derived from a request, so a user ID set by
+// the auth middleware also appears on the request log written by RequestMetrics.
+type requestInfo struct {
+	requestID string
+	userID    string
 }
 
-func NewLogger() *Logger {
-	return &Logger{minLevel: LevelInfo}
+const requestInfoContextKey contextKey = "request_info"
+
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoContextKey).(*requestInfo)
+	return info
 }
 
-func (l *Logger) log(level Level, msg string, fields map[string]any) {
-	entry := LogEntry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Level:     level,
-		Message:   msg,
-		Fields:    fields,
-	}
-	b, err := json.Marshal(entry)
-	if err != nil {
-		log.Printf("logger marshal error: %v", err)
-		return
+// SetUserID records the authenticated user for log entries of the current request.
+func SetUserID(ctx context.Context, userID string) {
+	if info := requestInfoFrom(ctx); info != nil {
+		info.userID = userID
 	}
-	_, _ = os.Stdout.Write(append(b, '\n'))
 }
 
-func (l *Logger) Info(msg string, fields ...map[string]any) {
-	l.log(LevelInfo, msg, mergeFields(fields...))
+type contextHandler struct {
+	slog.Handler
 }
 
-func (l *Logger) Warn(msg string, fields ...map[string]any) {
-	l.log(LevelWarn, msg, mergeFields(fields...))
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if info := requestInfoFrom(ctx); info != nil {
+		record.AddAttrs(slog.String("request_id", info.requestID))
+		if info.userID != "" {
+			record.AddAttrs(slog.String("user_id", info.userID))
+		}
+	}
+	for key, value := range TraceFields(ctx) {
+		record.AddAttrs(slog.Any(key, value))
+	}
+	return h.Handler.Handle(ctx, record)
 }
 
-func (l *Logger) Error(msg string, fields ...map[string]any) {
-	l.log(LevelError, msg, mergeFields(fields...))
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
 }
 
-func mergeFields(fields ...map[string]any) map[string]any {
-	for _, f := range fields {
-		if len(f) > 0 {
-			return f
-		}
-	}
-	return nil
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
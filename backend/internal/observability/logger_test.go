@@ -0,0 +1,141 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testRedactKeys = []string{"email", "phone", "token", "otp", "password", "authorization"}
+
+func discardLogger(t *testing.T) *slog.Logger {
+	t.Helper()
+	logger, err := NewLogger(LogConfig{Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger
+}
+
+func decodeEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(LogConfig{Level: "warn", Output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 || entries[0]["msg"] != "warn" {
+		t.Fatalf("entries = %v, want only the warning", entries)
+	}
+
+	if _, err := NewLogger(LogConfig{Level: "verbose"}); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+}
+
+func TestLoggerRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(LogConfig{Output: &buf, RedactKeys: testRedactKeys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("reset requested by jane.doe@example.com from +91 9876543210",
+		"email", "jane.doe@example.com",
+		"to_email", "sam@example.org",
+		"phone", "9876543210",
+		"otp", "123456",
+		"reset_token", "abc.def.ghi",
+		"user_id", "550e8400-e29b-41d4-a716-446655440000",
+		"error", errors.New("no account for jane.doe@example.com"),
+	)
+
+	entry := decodeEntries(t, &buf)[0]
+	want := map[string]any{
+		"msg":         "reset requested by j***@example.com from **********10",
+		"email":       "j***@example.com",
+		"to_email":    "s***@example.org",
+		"phone":       "********10",
+		"otp":         redacted,
+		"reset_token": redacted,
+		"user_id":     "550e8400-e29b-41d4-a716-446655440000",
+		"error":       "no account for j***@example.com",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+}
+
+func TestLoggerWithoutRedactKeysLogsValues(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(LogConfig{Output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("otp issued", "otp", "123456")
+
+	if entry := decodeEntries(t, &buf)[0]; entry["otp"] != "123456" {
+		t.Fatalf("otp = %v, want it unredacted", entry["otp"])
+	}
+}
+
+func TestLoggerAddsRequestContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(LogConfig{Output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := RequestMetrics(NewMetrics(), logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetUserID(r.Context(), "user-1")
+		logger.InfoContext(r.Context(), "inside handler")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want handler and request logs", len(entries))
+	}
+	for _, entry := range entries {
+		if entry["request_id"] != "req-1" || entry["user_id"] != "user-1" {
+			t.Errorf("entry %v is missing request context", entry)
+		}
+	}
+
+	buf.Reset()
+	logger.InfoContext(context.Background(), "background")
+	if entry := decodeEntries(t, &buf)[0]; entry["request_id"] != nil {
+		t.Errorf("background entry has request_id %v", entry["request_id"])
+	}
+}
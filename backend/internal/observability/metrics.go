@@ -1,74 +1,229 @@
 package observability
 
 import (
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Business events counted by Inc and exported as resellution_<event>_total.
+const (
+	EventRegistration         = "registrations"
+	EventLogin                = "logins"
+	EventLoginFailure         = "login_failures"
+	EventPasswordResetOTPSent = "password_reset_otps_sent"
+	EventListingCreated       = "listings_created"
+)
+
+var businessEvents = []struct{ name, help string }{
+	{EventRegistration, "Accounts registered."},
+	{EventLogin, "Successful logins."},
+	{EventLoginFailure, "Rejected logins."},
+	{EventPasswordResetOTPSent, "Password reset OTPs issued."},
+	{EventListingCreated, "Listings created."},
+}
+
+// latencyBuckets are the upper bounds, in seconds, of the request duration histogram.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// unmatchedRoute labels requests that matched no registered pattern, so unknown paths
+// cannot grow the label set.
+const unmatchedRoute = "unmatched"
+
+type requestKey struct {
+	Method      string
+	Route       string
+	StatusClass string
+}
+
+type requestStats struct {
+	count   int64
+	sum     float64
+	buckets []int64 // cumulative counts per latencyBuckets entry
+}
+
 type Metrics struct {
 	mu sync.RWMutex
 
-	TotalRequests   int64
-	RequestsByPath map[string]int64
-	RequestsByCode map[int]int64
-	TotalDuration  time.Duration
-	RequestCount   int64 // for avg latency
+	requests map[requestKey]*requestStats
+	events   map[string]int64
+	inFlight atomic.Int64
+
+	dbPoolStats func() DBPoolStats
+}
+
+// DBPoolStats is a snapshot of database connection pool usage.
+type DBPoolStats struct {
+	MaxConns             int32
+	TotalConns           int32
+	IdleConns            int32
+	AcquiredConns        int32
+	AcquireCount         int64
+	EmptyAcquireCount    int64
+	CanceledAcquireCount int64
+	AcquireDuration      time.Duration
+}
+
+// SetDBPoolStats registers the function that reports pool usage in snapshots.
+func (m *Metrics) SetDBPoolStats(fn func() DBPoolStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dbPoolStats = fn
 }
 
 func NewMetrics() *Metrics {
+	events := make(map[string]int64, len(businessEvents))
+	for _, event := range businessEvents {
+		events[event.name] = 0
+	}
 	return &Metrics{
-		RequestsByPath: make(map[string]int64),
-		RequestsByCode: make(map[int]int64),
+		requests: make(map[requestKey]*requestStats),
+		events:   events,
+	}
+}
+
+// RecordRequest counts a finished request. route is the ServeMux pattern that matched,
+// or empty when none did.
+func (m *Metrics) RecordRequest(method, route string, statusCode int, duration time.Duration) {
+	key := requestKey{Method: methodLabel(method), Route: routeLabel(route), StatusClass: statusClass(statusCode)}
+	seconds := duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.requests[key]
+	if !ok {
+		stats = &requestStats{buckets: make([]int64, len(latencyBuckets))}
+		m.requests[key] = stats
+	}
+	stats.count++
+	stats.sum += seconds
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			stats.buckets[i]++
+		}
 	}
 }
 
-func (m *Metrics) RecordRequest(path string, statusCode int, duration time.Duration) {
+// Inc counts one business event. It is a no-op on a nil *Metrics so handlers can be
+// used without metrics in tests.
+func (m *Metrics) Inc(event string) {
+	if m == nil {
+		return
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.events[event]++
+}
+
+// routeLabel drops the method from a pattern such as "GET /api/v1/users/{id}"; the
+// method is its own label.
+func routeLabel(pattern string) string {
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return strings.TrimSpace(path)
+	}
+	return pattern
+}
+
+// methodLabel maps methods outside the standard set to "OTHER". Clients choose the
+// method, so labelling with it as sent would let them create unbounded series.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
 
-	m.TotalRequests++
-	m.RequestsByPath[path]++
-	m.RequestsByCode[statusCode]++
-	m.TotalDuration += duration
-	m.RequestCount++
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return string(rune('0'+code/100)) + "xx"
 }
 
+// Snapshot is the JSON view of the metrics.
 func (m *Metrics) Snapshot() map[string]any {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	avgLatencyMs := float64(0)
-	if m.RequestCount > 0 {
-		avgLatencyMs = float64(m.TotalDuration.Microseconds()) / float64(m.RequestCount) / 1000
+	var totalRequests int64
+	var totalSeconds float64
+	byRoute := make(map[string]int64)
+	byClass := make(map[string]int64)
+	for key, stats := range m.requests {
+		totalRequests += stats.count
+		totalSeconds += stats.sum
+		byRoute[key.Method+" "+key.Route] += stats.count
+		byClass[key.StatusClass] += stats.count
 	}
 
-	return map[string]any{
-		"total_requests":   m.TotalRequests,
-		"requests_by_path": copyMapInt64(m.RequestsByPath),
-		"requests_by_code": copyMapInt(m.RequestsByCode),
-		"avg_latency_ms":   round(avgLatencyMs, 2),
-		"uptime_seconds":   int64(time.Since(startTime).Seconds()),
+	avgLatencyMs := float64(0)
+	if totalRequests > 0 {
+		avgLatencyMs = totalSeconds * 1000 / float64(totalRequests)
 	}
-}
 
-var startTime = time.Now()
+	events := make(map[string]int64, len(m.events))
+	for name, count := range m.events {
+		events[name] = count
+	}
 
-func copyMapInt64(m map[string]int64) map[string]int64 {
-	out := make(map[string]int64, len(m))
-	for k, v := range m {
-		out[k] = v
+	snapshot := map[string]any{
+		"total_requests":           totalRequests,
+		"requests_by_route":        byRoute,
+		"requests_by_status_class": byClass,
+		"requests_in_flight":       m.inFlight.Load(),
+		"avg_latency_ms":           round(avgLatencyMs, 2),
+		"uptime_seconds":           int64(time.Since(startTime).Seconds()),
+		"events":                   events,
+	}
+	if m.dbPoolStats != nil {
+		stats := m.dbPoolStats()
+		// empty_acquire_count counts acquires that had to wait for a connection; a
+		// growing value means the pool is too small.
+		snapshot["db_pool"] = map[string]any{
+			"max_conns":              stats.MaxConns,
+			"total_conns":            stats.TotalConns,
+			"idle_conns":             stats.IdleConns,
+			"acquired_conns":         stats.AcquiredConns,
+			"acquire_count":          stats.AcquireCount,
+			"empty_acquire_count":    stats.EmptyAcquireCount,
+			"canceled_acquire_count": stats.CanceledAcquireCount,
+			"acquire_duration_ms":    stats.AcquireDuration.Milliseconds(),
+		}
 	}
-	return out
+	return snapshot
 }
 
-func copyMapInt(m map[int]int64) map[int]int64 {
-	out := make(map[int]int64, len(m))
-	for k, v := range m {
-		out[k] = v
+// sortedRequestKeys orders series so the exposition output is stable between scrapes.
+func (m *Metrics) sortedRequestKeys() []requestKey {
+	keys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
 	}
-	return out
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.StatusClass < b.StatusClass
+	})
+	return keys
 }
 
+var startTime = time.Now()
+
 func round(f float64, decimals int) float64 {
 	pow := 1.0
 	for i := 0; i < decimals; i++ {
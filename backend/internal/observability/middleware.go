@@ -3,11 +3,13 @@ package observability
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
+
+	"resellution/backend/internal/clientip"
 )
 
 type responseRecorder struct {
@@ -29,14 +31,92 @@ func RequestIDFromContext(ctx context.Context) (string, bool) {
 	return id, ok
 }
 
-func RequestMetrics(metrics *Metrics, logger *Logger, next http.Handler) http.Handler {
+// routeCapture carries the matched pattern back out of the ServeMux, which sets
+// r.Pattern on the request it was handed rather than on ours.
+type routeCapture struct {
+	pattern string
+}
+
+const routeCaptureContextKey contextKey = "route_capture"
+
+// withRouteCapture returns the capture already in ctx, so Tracing and RequestMetrics
+// share one, or adds a new one.
+func withRouteCapture(ctx context.Context) (context.Context, *routeCapture) {
+	if capture, ok := ctx.Value(routeCaptureContextKey).(*routeCapture); ok {
+		return ctx, capture
+	}
+	capture := &routeCapture{}
+	return context.WithValue(ctx, routeCaptureContextKey, capture), capture
+}
+
+// CaptureRoute must wrap the ServeMux directly so RequestMetrics can label requests by
+// the pattern that matched instead of the raw path. When unmatched is not nil, it
+// writes the response for requests no pattern matched in place of the mux's plain-text
+// 404 and 405; the mux's Allow header is kept.
+func CaptureRoute(mux http.Handler, unmatched func(w http.ResponseWriter, r *http.Request, status int)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unmatched != nil {
+			w = &unmatchedWriter{ResponseWriter: w, r: r, unmatched: unmatched}
+		}
+		mux.ServeHTTP(w, r)
+		if capture, ok := r.Context().Value(routeCaptureContextKey).(*routeCapture); ok {
+			capture.pattern = r.Pattern
+		}
+	})
+}
+
+// unmatchedWriter hands the mux's own 404 and 405 responses to unmatched and drops
+// their bodies. Handlers of a matched route keep their responses, 404s included.
+type unmatchedWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	unmatched   func(w http.ResponseWriter, r *http.Request, status int)
+	wroteHeader bool
+	replaced    bool
+}
+
+func (u *unmatchedWriter) WriteHeader(status int) {
+	if u.wroteHeader {
+		u.ResponseWriter.WriteHeader(status)
+		return
+	}
+	u.wroteHeader = true
+	if u.r.Pattern == "" && (status == http.StatusNotFound || status == http.StatusMethodNotAllowed) {
+		u.replaced = true
+		u.unmatched(u.ResponseWriter, u.r, status)
+		return
+	}
+	u.ResponseWriter.WriteHeader(status)
+}
+
+func (u *unmatchedWriter) Write(b []byte) (int, error) {
+	if !u.wroteHeader {
+		u.WriteHeader(http.StatusOK)
+	}
+	if u.replaced {
+		return len(b), nil
+	}
+	return u.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (u *unmatchedWriter) Unwrap() http.ResponseWriter {
+	return u.ResponseWriter
+}
+
+func RequestMetrics(metrics *Metrics, logger *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		metrics.inFlight.Add(1)
+		defer metrics.inFlight.Add(-1)
+
 		requestID := r.Header.Get("X-Request-ID")
 		if requestID == "" {
 			requestID = uuid.NewString()
 		}
-		ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+		ctx, capture := withRouteCapture(r.Context())
+		ctx = context.WithValue(ctx, requestIDContextKey, requestID)
+		ctx = context.WithValue(ctx, requestInfoContextKey, &requestInfo{requestID: requestID})
 		rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
 		rec.Header().Set("X-Request-ID", requestID)
 
@@ -48,24 +128,23 @@ func RequestMetrics(metrics *Metrics, logger *Logger, next http.Handler) http.Ha
 			path = "/"
 		}
 
-		metrics.RecordRequest(path, rec.statusCode, duration)
-
-		log.Printf("%s %s -> %d (%s) request_id=%s ip=%s ua=%q",
-			r.Method, path, rec.statusCode, duration.Round(time.Millisecond), requestID, r.RemoteAddr, r.UserAgent())
+		metrics.RecordRequest(r.Method, capture.pattern, rec.statusCode, duration)
 
-		logger.Info("request",
-			map[string]any{
-				"request_id":  requestID,
-				"method":      r.Method,
-				"path":        path,
-				"status":      rec.statusCode,
-				"duration_ms": duration.Milliseconds(),
-				"remote_addr": r.RemoteAddr,
-			},
+		logger.LogAttrs(ctx, slog.LevelInfo, "request",
+			slog.String("method", r.Method),
+			slog.String("path", path),
+			slog.String("route", routeLabel(capture.pattern)),
+			slog.Int("status", rec.statusCode),
+			slog.Int64("duration_ms", duration.Milliseconds()),
+			slog.String("client_ip", clientip.FromRequest(r)),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
 		)
 	})
 }
 
+// MetricsHandler serves the Prometheus text format, or the JSON snapshot when called
+// with ?format=json.
 func MetricsHandler(metrics *Metrics) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -73,9 +152,16 @@ func MetricsHandler(metrics *Metrics) http.HandlerFunc {
 			return
 		}
 
-		snapshot := metrics.Snapshot()
-		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("format") == "json" {
+			snapshot := metrics.Snapshot()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(snapshot)
+			return
+		}
+
+		w.Header().Set("Content-Type", PrometheusContentType)
 		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(snapshot)
+		_ = metrics.WritePrometheus(w)
 	}
 }
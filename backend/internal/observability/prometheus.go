@@ -0,0 +1,164 @@
+package observability
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrometheusContentType is the content type of the text exposition format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WritePrometheus writes every metric in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	m.writeRequests(out)
+	m.writeEvents(out)
+	m.writeDBPool(out)
+	writeRuntime(out)
+	return out.Flush()
+}
+
+func (m *Metrics) writeRequests(out *bufio.Writer) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := m.sortedRequestKeys()
+
+	writeHeader(out, "http_requests_total", "counter", "HTTP requests by method, route pattern and status class.")
+	for _, key := range keys {
+		writeSample(out, "http_requests_total", requestLabels(key), float64(m.requests[key].count))
+	}
+
+	writeHeader(out, "http_request_duration_seconds", "histogram", "HTTP request latency by method, route pattern and status class.")
+	for _, key := range keys {
+		stats := m.requests[key]
+		labels := requestLabels(key)
+		for i, bound := range latencyBuckets {
+			writeSample(out, "http_request_duration_seconds_bucket", append(labels, "le", formatFloat(bound)), float64(stats.buckets[i]))
+		}
+		writeSample(out, "http_request_duration_seconds_bucket", append(labels, "le", "+Inf"), float64(stats.count))
+		writeSample(out, "http_request_duration_seconds_sum", labels, stats.sum)
+		writeSample(out, "http_request_duration_seconds_count", labels, float64(stats.count))
+	}
+
+	writeHeader(out, "http_requests_in_flight", "gauge", "HTTP requests currently being served.")
+	writeSample(out, "http_requests_in_flight", nil, float64(m.inFlight.Load()))
+}
+
+func (m *Metrics) writeEvents(out *bufio.Writer) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, event := range businessEvents {
+		name := "resellution_" + event.name + "_total"
+		writeHeader(out, name, "counter", event.help)
+		writeSample(out, name, nil, float64(m.events[event.name]))
+	}
+}
+
+func (m *Metrics) writeDBPool(out *bufio.Writer) {
+	m.mu.RLock()
+	statsFn := m.dbPoolStats
+	m.mu.RUnlock()
+	if statsFn == nil {
+		return
+	}
+
+	stats := statsFn()
+	gauges := []struct {
+		name, help string
+		value      int32
+	}{
+		{"db_pool_max_conns", "Maximum size of the connection pool.", stats.MaxConns},
+		{"db_pool_total_conns", "Connections currently open.", stats.TotalConns},
+		{"db_pool_idle_conns", "Open connections that are idle.", stats.IdleConns},
+		{"db_pool_acquired_conns", "Connections currently in use.", stats.AcquiredConns},
+	}
+	for _, gauge := range gauges {
+		writeHeader(out, gauge.name, "gauge", gauge.help)
+		writeSample(out, gauge.name, nil, float64(gauge.value))
+	}
+
+	counters := []struct {
+		name, help string
+		value      float64
+	}{
+		{"db_pool_acquires_total", "Connections acquired from the pool.", float64(stats.AcquireCount)},
+		{"db_pool_empty_acquires_total", "Acquires that waited because the pool had no idle connection.", float64(stats.EmptyAcquireCount)},
+		{"db_pool_canceled_acquires_total", "Acquires canceled by their context.", float64(stats.CanceledAcquireCount)},
+		{"db_pool_acquire_duration_seconds_total", "Total time spent acquiring connections.", stats.AcquireDuration.Seconds()},
+	}
+	for _, counter := range counters {
+		writeHeader(out, counter.name, "counter", counter.help)
+		writeSample(out, counter.name, nil, counter.value)
+	}
+}
+
+func writeRuntime(out *bufio.Writer) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	writeHeader(out, "go_info", "gauge", "Go version the binary was built with.")
+	writeSample(out, "go_info", []string{"version", runtime.Version()}, 1)
+
+	writeHeader(out, "go_goroutines", "gauge", "Goroutines that currently exist.")
+	writeSample(out, "go_goroutines", nil, float64(runtime.NumGoroutine()))
+
+	writeHeader(out, "go_memstats_heap_alloc_bytes", "gauge", "Bytes of allocated heap objects.")
+	writeSample(out, "go_memstats_heap_alloc_bytes", nil, float64(mem.HeapAlloc))
+
+	writeHeader(out, "go_memstats_heap_inuse_bytes", "gauge", "Bytes in in-use heap spans.")
+	writeSample(out, "go_memstats_heap_inuse_bytes", nil, float64(mem.HeapInuse))
+
+	writeHeader(out, "go_memstats_sys_bytes", "gauge", "Bytes of memory obtained from the OS.")
+	writeSample(out, "go_memstats_sys_bytes", nil, float64(mem.Sys))
+
+	writeHeader(out, "go_gc_cycles_total", "counter", "Completed GC cycles.")
+	writeSample(out, "go_gc_cycles_total", nil, float64(mem.NumGC))
+
+	writeHeader(out, "go_gc_pause_seconds_total", "counter", "Total stop-the-world GC pause time.")
+	writeSample(out, "go_gc_pause_seconds_total", nil, time.Duration(mem.PauseTotalNs).Seconds())
+
+	writeHeader(out, "process_start_time_seconds", "gauge", "Start time of the process since the Unix epoch.")
+	writeSample(out, "process_start_time_seconds", nil, float64(startTime.Unix()))
+}
+
+func requestLabels(key requestKey) []string {
+	return []string{"method", key.Method, "route", key.Route, "status_class", key.StatusClass}
+}
+
+func writeHeader(out *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample writes one line; labels alternate names and values.
+func writeSample(out *bufio.Writer, name string, labels []string, value float64) {
+	out.WriteString(name)
+	if len(labels) > 0 {
+		out.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.WriteString(labels[i])
+			out.WriteString(`="`)
+			out.WriteString(labelEscaper.Replace(labels[i+1]))
+			out.WriteByte('"')
+		}
+		out.WriteByte('}')
+	}
+	out.WriteByte(' ')
+	out.WriteString(formatFloat(value))
+	out.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,81 @@
+package observability
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestMetricsLabelsByRoutePattern(t *testing.T) {
+	metrics := NewMetrics()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/admin/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := RequestMetrics(metrics, discardLogger(t), CaptureRoute(mux, nil))
+
+	for _, path := range []string{"/api/v1/admin/users/1", "/api/v1/admin/users/2", "/nope/3"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	metrics.Inc(EventRegistration)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(metrics)(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := rec.Header().Get("Content-Type"); got != PrometheusContentType {
+		t.Fatalf("content type = %q", got)
+	}
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/api/v1/admin/users/{id}",status_class="2xx"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status_class="4xx"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/admin/users/{id}",status_class="2xx",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="/api/v1/admin/users/{id}",status_class="2xx"} 2`,
+		"# TYPE http_request_duration_seconds histogram",
+		"http_requests_in_flight 0",
+		"resellution_registrations_total 1",
+		"resellution_logins_total 0",
+		"go_goroutines ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("exposition missing %q", want)
+		}
+	}
+	if strings.Contains(body, "/api/v1/admin/users/1") {
+		t.Error("raw path leaked into labels")
+	}
+}
+
+func TestWriteSampleEscapesLabels(t *testing.T) {
+	var out strings.Builder
+	metrics := NewMetrics()
+	metrics.RecordRequest("GET", "GET /a\"b\\c", http.StatusOK, 0)
+	if err := metrics.WritePrometheus(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `route="/a\"b\\c"`) {
+		t.Fatalf("label not escaped:\n%s", out.String())
+	}
+}
+
+func TestNonstandardMethodsShareALabel(t *testing.T) {
+	var out strings.Builder
+	metrics := NewMetrics()
+	metrics.RecordRequest("FOO1", "", http.StatusMethodNotAllowed, 0)
+	metrics.RecordRequest("get", "", http.StatusMethodNotAllowed, 0)
+	if err := metrics.WritePrometheus(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "FOO1") || strings.Contains(out.String(), `method="get"`) {
+		t.Fatalf("raw method became a label:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), `method="OTHER"`) {
+		t.Fatalf("missing OTHER method label:\n%s", out.String())
+	}
+}
+
+func TestIncOnNilMetrics(t *testing.T) {
+	var metrics *Metrics
+	metrics.Inc(EventLogin)
+}
@@ -0,0 +1,131 @@
+package observability
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// phonePattern matches international numbers and ten-digit Indian mobile numbers.
+	// It deliberately skips longer digit runs so UUIDs and IDs are left alone.
+	phonePattern = regexp.MustCompile(`\+\d{1,3}[\s-]?\d{6,14}\b|\b[6-9]\d{9}\b`)
+)
+
+type redactor struct {
+	keys []string
+}
+
+func newRedactor(keys []string) redactor {
+	normalized := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			normalized = append(normalized, key)
+		}
+	}
+	return redactor{keys: normalized}
+}
+
+func (r redactor) sensitiveKey(key string) (string, bool) {
+	key = strings.ToLower(key)
+	for _, sensitive := range r.keys {
+		if key == sensitive || strings.HasSuffix(key, "_"+sensitive) {
+			return sensitive, true
+		}
+	}
+	return "", false
+}
+
+// replaceAttr masks values of sensitive keys and scrubs emails and phone numbers from
+// every other string, including messages and errors.
+func (r redactor) replaceAttr(_ []string, attr slog.Attr) slog.Attr {
+	if len(r.keys) == 0 {
+		return attr
+	}
+	if sensitive, ok := r.sensitiveKey(attr.Key); ok {
+		return slog.String(attr.Key, maskValue(sensitive, attr.Value))
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(r.scrub(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			attr.Value = slog.StringValue(r.scrub(err.Error()))
+		}
+	}
+	return attr
+}
+
+func (r redactor) scrub(s string) string {
+	if r.masks("email") {
+		s = emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+	}
+	if r.masks("phone") {
+		s = phonePattern.ReplaceAllStringFunc(s, MaskPhone)
+	}
+	return s
+}
+
+func (r redactor) masks(key string) bool {
+	for _, sensitive := range r.keys {
+		if sensitive == key {
+			return true
+		}
+	}
+	return false
+}
+
+func maskValue(sensitive string, value slog.Value) string {
+	raw := value.String()
+	if raw == "" {
+		return ""
+	}
+	switch sensitive {
+	case "email":
+		return MaskEmail(raw)
+	case "phone":
+		return MaskPhone(raw)
+	}
+	return redacted
+}
+
+// MaskEmail keeps the first character of the local part and the domain, so support can
+// still tell accounts apart: "jane.doe@example.com" becomes "j***@example.com".
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return redacted
+	}
+	return local[:1] + "***@" + domain
+}
+
+// MaskPhone keeps the last two digits.
+func MaskPhone(phone string) string {
+	digits := 0
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	if digits <= 2 {
+		return redacted
+	}
+	var b strings.Builder
+	seen := 0
+	for _, c := range phone {
+		if c < '0' || c > '9' {
+			continue
+		}
+		seen++
+		if seen > digits-2 {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('*')
+		}
+	}
+	return b.String()
+}
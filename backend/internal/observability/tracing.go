@@ -0,0 +1,123 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"resellution/backend/internal/clientip"
+)
+
+// Span exporters selectable with TRACING_EXPORTER.
+const (
+	TracingExporterNone     = "none"
+	TracingExporterStdout   = "stdout"
+	TracingExporterOTLPHTTP = "otlphttp"
+)
+
+const (
+	tracerName  = "resellution/backend/http"
+	serviceName = "resellution-backend"
+)
+
+// SetupTracing installs the global tracer provider for exporter and the W3C trace
+// context propagator. With TracingExporterNone no spans are recorded, but incoming
+// traceparent headers are still honoured so logs carry the caller's trace ID.
+//
+// The OTLP/HTTP exporter reads its endpoint and headers from the standard
+// OTEL_EXPORTER_OTLP_* variables (default http://localhost:4318). The returned
+// function flushes buffered spans and must be called before the process exits.
+func SetupTracing(ctx context.Context, exporter string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case TracingExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case TracingExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case TracingExporterOTLPHTTP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Attributes from OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override ours.
+	res, err := resource.Merge(
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)),
+		resource.Default(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracing starts a server span per request, continuing the trace from an incoming
+// traceparent header. The span is named after the matched route pattern, so it must
+// wrap RequestMetrics and the mux must be wrapped in CaptureRoute.
+func Tracing(next http.Handler) http.Handler {
+	tracer := otel.Tracer(tracerName)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, capture := withRouteCapture(ctx)
+		ctx, span := tracer.Start(ctx, methodLabel(r.Method),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(clientip.FromRequest(r)),
+			),
+		)
+		defer span.End()
+
+		rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		if capture.pattern != "" {
+			route := routeLabel(capture.pattern)
+			span.SetName(methodLabel(r.Method) + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.statusCode))
+		if rec.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.statusCode))
+		}
+	})
+}
+
+// TraceFields returns the trace and span IDs of the span in ctx as log fields, or nil
+// when ctx carries no valid span.
+func TraceFields(ctx context.Context) map[string]any {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	return map[string]any{
+		"trace_id": spanContext.TraceID().String(),
+		"span_id":  spanContext.SpanID().String(),
+	}
+}
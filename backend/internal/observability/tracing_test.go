@@ -0,0 +1,91 @@
+package observability
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TestTracingExportsToOTLPCollector uses an httptest server as a stand-in for an
+// OpenTelemetry collector's OTLP/HTTP receiver.
+func TestTracingExportsToOTLPCollector(t *testing.T) {
+	var mu sync.Mutex
+	var exports []*http.Request
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		mu.Lock()
+		exports = append(exports, r)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+	shutdown, err := SetupTracing(context.Background(), TracingExporterOTLPHTTP, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	var handlerFields map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/admin/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerFields = TraceFields(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Tracing(RequestMetrics(NewMetrics(), discardLogger(t), CaptureRoute(mux, nil)))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := handlerFields["trace_id"]; got != traceID {
+		t.Fatalf("handler trace_id = %v, want the incoming %s", got, traceID)
+	}
+	if handlerFields["span_id"] == "00f067aa0ba902b7" {
+		t.Fatal("expected a server span as a child of the incoming span")
+	}
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(exports) == 0 {
+		t.Fatal("collector received no spans")
+	}
+	if exports[0].URL.Path != "/v1/traces" || exports[0].Header.Get("Content-Type") != "application/x-protobuf" {
+		t.Fatalf("unexpected export %s %q", exports[0].URL.Path, exports[0].Header.Get("Content-Type"))
+	}
+}
+
+func TestTracingNoneStillPropagatesTraceparent(t *testing.T) {
+	if _, err := SetupTracing(context.Background(), TracingExporterNone, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]any
+	handler := Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields = TraceFields(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if fields["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace fields = %v", fields)
+	}
+}
+
+func TestSetupTracingRejectsUnknownExporter(t *testing.T) {
+	if _, err := SetupTracing(context.Background(), "jaeger", 1); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,17 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Docs serves a self-contained reference page that renders /openapi.json, so it works
+// without access to a CDN.
+func Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(docsPage)
+}
@@ -0,0 +1,182 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"resellution/backend/internal/problem"
+)
+
+func loadSpec(t *testing.T) *Spec {
+	t.Helper()
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestMatchPrefersLiteralSegments(t *testing.T) {
+	spec := loadSpec(t)
+	op, params, ok := spec.Match(http.MethodPost, "/api/v1/admin/users/7d6c0b3e-4c8e-4a8a-9d6e-3f1e2b8a9c01/suspend")
+	if !ok || op.Path != "/api/v1/admin/users/{id}/suspend" || params["id"] != "7d6c0b3e-4c8e-4a8a-9d6e-3f1e2b8a9c01" {
+		t.Fatalf("match = %v %v %v", op, params, ok)
+	}
+	if _, _, ok := spec.Match(http.MethodGet, "/api/v1/nope"); ok {
+		t.Fatal("matched an undocumented path")
+	}
+}
+
+func TestValidateRequests(t *testing.T) {
+	spec := loadSpec(t)
+	var reached string
+	handler := ValidateRequests(spec, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reached = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name, method, target, body string
+		status                     int
+		fields                     map[string]string
+	}{
+		{"valid", http.MethodPost, "/api/v1/auth/login", `{"email":"a@example.com","password":"x"}`, http.StatusOK, nil},
+		{"missing and wrong type", http.MethodPost, "/api/v1/auth/register", `{"email":"a@example.com","password":12}`, http.StatusBadRequest,
+			map[string]string{"password": problem.FieldInvalid, "full_name": problem.FieldRequired}},
+		{"too long", http.MethodPost, "/api/v1/auth/login", `{"email":"a@example.com","password":"` + strings.Repeat("p", 73) + `"}`, http.StatusBadRequest,
+			map[string]string{"password": problem.FieldTooLong}},
+		{"unknown field", http.MethodPatch, "/api/v1/users/me", `{"nickname":"k"}`, http.StatusBadRequest,
+			map[string]string{"nickname": problem.FieldInvalid}},
+		{"bad query", http.MethodGet, "/api/v1/admin/users?limit=500&include_deleted=maybe&created_after=yesterday", "", http.StatusBadRequest,
+			map[string]string{"limit": problem.FieldInvalid, "include_deleted": problem.FieldInvalid, "created_after": problem.FieldInvalid}},
+		{"good query", http.MethodGet, "/api/v1/admin/users?limit=20&created_after=2024-01-31", "", http.StatusOK, nil},
+		{"optional body", http.MethodPost, "/api/v1/admin/users/1/suspend", "", http.StatusOK, nil},
+		{"undocumented path", http.MethodGet, "/nope", "", http.StatusOK, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reached = ""
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.status, rec.Body)
+			}
+			if tc.status == http.StatusOK {
+				if reached != tc.body {
+					t.Fatalf("handler saw body %q, want %q", reached, tc.body)
+				}
+				return
+			}
+
+			var body struct {
+				Code   string               `json:"code"`
+				Errors []problem.FieldError `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for _, e := range body.Errors {
+				got[e.Field] = e.Code
+			}
+			if body.Code != problem.CodeValidationFailed || len(got) != len(tc.fields) {
+				t.Fatalf("problem = %+v, want fields %v", body, tc.fields)
+			}
+			for field, code := range tc.fields {
+				if got[field] != code {
+					t.Errorf("%s = %q, want %q", field, got[field], code)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRequestsRejectsMalformedJSON(t *testing.T) {
+	handler := ValidateRequests(loadSpec(t), http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader("{")))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), problem.CodeInvalidJSON) {
+		t.Fatalf("status %d body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestValidateResponsesReportsDrift(t *testing.T) {
+	spec := loadSpec(t)
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{"matches", http.StatusOK, "application/json", `{"user":{"id":"1","email":"a@example.com","full_name":"A","role":"user"}}`, ""},
+		{"missing property", http.StatusOK, "application/json", `{"user":{"id":"1","email":"a@example.com","full_name":"A"}}`, "user.role is required"},
+		{"undocumented status", http.StatusTeapot, "application/json", `{}`, "status 418 is not documented"},
+		{"wrong content type", http.StatusUnauthorized, "text/plain", "unauthorized", "does not document content type text/plain"},
+		{"problem", http.StatusUnauthorized, problem.ContentType, `{"type":"/problems/auth.unauthenticated","title":"Unauthorized","status":401,"code":"auth.unauthenticated"}`, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var reported error
+			handler := ValidateResponses(spec, func(r *http.Request, err error) { reported = err }, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.WriteHeader(tc.status)
+				_, _ = io.WriteString(w, tc.body)
+			}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil))
+
+			if rec.Body.String() != tc.body {
+				t.Fatalf("body was altered: %q", rec.Body)
+			}
+			switch {
+			case tc.wantErr == "" && reported != nil:
+				t.Fatalf("unexpected report: %v", reported)
+			case tc.wantErr != "" && (reported == nil || !strings.Contains(reported.Error(), tc.wantErr)):
+				t.Fatalf("report = %v, want %q", reported, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDocumentIsServed(t *testing.T) {
+	spec := loadSpec(t)
+	rec := httptest.NewRecorder()
+	spec.ServeJSON(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil || doc["openapi"] != "3.1.0" {
+		t.Fatalf("document = %v, %v", doc["openapi"], err)
+	}
+
+	rec = httptest.NewRecorder()
+	Docs(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.Contains(rec.Body.String(), "/openapi.json") {
+		t.Fatalf("docs page: %s", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestReferencesResolve(t *testing.T) {
+	spec := loadSpec(t)
+	var walk func(node any, path string)
+	walk = func(node any, path string) {
+		switch v := node.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok && spec.pointer(ref) == nil {
+				t.Errorf("%s: unresolved $ref %s", path, ref)
+			}
+			for key, child := range v {
+				walk(child, path+"/"+key)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child, path)
+			}
+		}
+	}
+	walk(spec.doc, "#")
+}
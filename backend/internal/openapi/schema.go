@@ -0,0 +1,233 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"resellution/backend/internal/problem"
+)
+
+var patterns sync.Map
+
+// validate checks value, decoded from JSON, against the subset of JSON Schema 2020-12
+// the document uses: type, enum, properties, required, additionalProperties, items,
+// minLength, maxLength, pattern, minimum, maximum, allOf, anyOf and $ref. As in 2020-12,
+// format is an annotation and is not enforced. field names the value in errors.
+func (s *Spec) validate(schemaNode any, value any, field string) []problem.FieldError {
+	schema, ok := s.resolve(schemaNode).(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesType(types, value) {
+		return []problem.FieldError{problem.Field(fieldName(field), problem.FieldInvalid, "%s must be %s", fieldName(field), joinTypes(types))}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !contains(enum, value) {
+		return []problem.FieldError{problem.Field(fieldName(field), problem.FieldInvalid, "%s must be one of %v", fieldName(field), enum)}
+	}
+
+	var errs []problem.FieldError
+	for _, sub := range asList(schema["allOf"]) {
+		errs = append(errs, s.validate(sub, value, field)...)
+	}
+	if anyOf := asList(schema["anyOf"]); len(anyOf) > 0 {
+		matched := false
+		for _, sub := range anyOf {
+			if len(s.validate(sub, value, field)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, problem.Field(fieldName(field), problem.FieldInvalid, "%s is not in an accepted format", fieldName(field)))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		errs = append(errs, validateString(schema, v, field)...)
+	case float64:
+		errs = append(errs, validateNumber(schema, v, field)...)
+	case map[string]any:
+		errs = append(errs, s.validateObject(schema, v, field)...)
+	case []any:
+		for i, item := range v {
+			errs = append(errs, s.validate(schema["items"], item, fmt.Sprintf("%s[%d]", field, i))...)
+		}
+	}
+	return errs
+}
+
+func validateString(schema map[string]any, value, field string) []problem.FieldError {
+	length := utf8.RuneCountInString(value)
+	if min, ok := schema["minLength"].(float64); ok && length < int(min) {
+		return []problem.FieldError{problem.Field(fieldName(field), problem.FieldTooShort, "%s must be at least %d characters", fieldName(field), int(min))}
+	}
+	if max, ok := schema["maxLength"].(float64); ok && length > int(max) {
+		return []problem.FieldError{problem.Field(fieldName(field), problem.FieldTooLong, "%s must not exceed %d characters", fieldName(field), int(max))}
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := compile(pattern)
+		if err == nil && !re.MatchString(value) {
+			return []problem.FieldError{problem.Field(fieldName(field), problem.FieldInvalid, "%s has an invalid format", fieldName(field))}
+		}
+	}
+	return nil
+}
+
+func validateNumber(schema map[string]any, value float64, field string) []problem.FieldError {
+	if min, ok := schema["minimum"].(float64); ok && value < min {
+		return []problem.FieldError{problem.Field(fieldName(field), problem.FieldInvalid, "%s must be at least %v", fieldName(field), min)}
+	}
+	if max, ok := schema["maximum"].(float64); ok && value > max {
+		return []problem.FieldError{problem.Field(fieldName(field), problem.FieldInvalid, "%s must be at most %v", fieldName(field), max)}
+	}
+	return nil
+}
+
+func (s *Spec) validateObject(schema map[string]any, value map[string]any, field string) []problem.FieldError {
+	var errs []problem.FieldError
+	for _, raw := range asList(schema["required"]) {
+		name, _ := raw.(string)
+		if _, ok := value[name]; !ok {
+			errs = append(errs, problem.Field(join(field, name), problem.FieldRequired, "%s is required", join(field, name)))
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if propertySchema, ok := properties[name]; ok {
+			errs = append(errs, s.validate(propertySchema, value[name], join(field, name))...)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, problem.Field(join(field, name), problem.FieldInvalid, "%s is not a known field", join(field, name)))
+			}
+		case map[string]any:
+			errs = append(errs, s.validate(additional, value[name], join(field, name))...)
+		}
+	}
+	return errs
+}
+
+func schemaTypes(node any) []string {
+	switch t := node.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(types []string, value any) bool {
+	for _, t := range types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func joinTypes(types []string) string {
+	out := ""
+	for i, t := range types {
+		switch {
+		case i == 0:
+		case i == len(types)-1:
+			out += " or "
+		default:
+			out += ", "
+		}
+		switch t {
+		case "array", "integer", "object":
+			out += "an " + t
+		case "null":
+			out += "null"
+		default:
+			out += "a " + t
+		}
+	}
+	return out
+}
+
+func contains(list []any, value any) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func asList(node any) []any {
+	list, _ := node.([]any)
+	return list
+}
+
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func fieldName(field string) string {
+	if field == "" {
+		return "body"
+	}
+	return field
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
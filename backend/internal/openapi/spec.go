@@ -0,0 +1,227 @@
+// Package openapi serves the API's OpenAPI 3.1 document and validates requests and
+// responses against it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var document []byte
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Spec is a parsed OpenAPI document.
+type Spec struct {
+	raw        []byte
+	doc        map[string]any
+	operations []*Operation
+}
+
+// Operation is one method on one path of the document.
+type Operation struct {
+	Method string
+	// Path is the path template, such as /api/v1/admin/users/{id}.
+	Path     string
+	segments []string
+	spec     *Spec
+	node     map[string]any
+	params   []map[string]any
+}
+
+// Load parses the document embedded in the binary.
+func Load() (*Spec, error) {
+	return Parse(document)
+}
+
+// Parse parses an OpenAPI document in JSON.
+func Parse(raw []byte) (*Spec, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	spec := &Spec{raw: raw, doc: doc}
+
+	paths, _ := doc["paths"].(map[string]any)
+	for path, rawItem := range paths {
+		item, ok := spec.resolve(rawItem).(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("openapi: path %s is not an object", path)
+		}
+		shared := spec.parameters(item["parameters"])
+		for _, method := range methods {
+			node, ok := spec.resolve(item[method]).(map[string]any)
+			if !ok {
+				continue
+			}
+			spec.operations = append(spec.operations, &Operation{
+				Method:   strings.ToUpper(method),
+				Path:     path,
+				segments: strings.Split(strings.Trim(path, "/"), "/"),
+				spec:     spec,
+				node:     node,
+				params:   mergeParameters(shared, spec.parameters(node["parameters"])),
+			})
+		}
+	}
+	// Literal segments sort before parameters so /users/me wins over /users/{id}.
+	sort.SliceStable(spec.operations, func(i, j int) bool {
+		return specificity(spec.operations[i].segments) > specificity(spec.operations[j].segments)
+	})
+	return spec, nil
+}
+
+// Operations lists every operation in the document.
+func (s *Spec) Operations() []*Operation {
+	return s.operations
+}
+
+// Find returns the operation for an exact method and path template, as written in a
+// ServeMux pattern.
+func (s *Spec) Find(method, pathTemplate string) (*Operation, bool) {
+	for _, op := range s.operations {
+		if op.Method == method && op.Path == pathTemplate {
+			return op, true
+		}
+	}
+	return nil, false
+}
+
+// Match returns the operation that serves a request path, and its path parameters.
+func (s *Spec) Match(method, path string) (*Operation, map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, op := range s.operations {
+		if op.Method != method || len(op.segments) != len(segments) {
+			continue
+		}
+		if params, ok := op.match(segments); ok {
+			return op, params, true
+		}
+	}
+	return nil, nil, false
+}
+
+// ServeJSON serves the document.
+func (s *Spec) ServeJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(s.raw)
+}
+
+func (op *Operation) match(segments []string) (map[string]string, bool) {
+	params := map[string]string{}
+	for i, segment := range op.segments {
+		if name, ok := parameterName(segment); ok {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[name] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// response returns the documented response for status, falling back to the 4XX style
+// range and then to default.
+func (op *Operation) response(status int) (map[string]any, bool) {
+	responses, _ := op.node["responses"].(map[string]any)
+	for _, key := range []string{fmt.Sprint(status), fmt.Sprintf("%dXX", status/100), "default"} {
+		if node, ok := op.spec.resolve(responses[key]).(map[string]any); ok {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+func (op *Operation) requestBody() (map[string]any, bool) {
+	body, ok := op.spec.resolve(op.node["requestBody"]).(map[string]any)
+	return body, ok
+}
+
+// resolve follows local $ref pointers such as #/components/schemas/User.
+func (s *Spec) resolve(node any) any {
+	for i := 0; i < 32; i++ {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return node
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return node
+		}
+		node = s.pointer(ref)
+	}
+	return nil
+}
+
+func (s *Spec) pointer(ref string) any {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var node any = s.doc
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = object[token]
+	}
+	return node
+}
+
+func (s *Spec) parameters(node any) []map[string]any {
+	list, _ := node.([]any)
+	params := make([]map[string]any, 0, len(list))
+	for _, raw := range list {
+		if param, ok := s.resolve(raw).(map[string]any); ok {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// mergeParameters lets operation parameters override path item parameters with the
+// same name and location.
+func mergeParameters(shared, own []map[string]any) []map[string]any {
+	merged := append([]map[string]any{}, own...)
+	for _, param := range shared {
+		overridden := false
+		for _, o := range own {
+			if o["name"] == param["name"] && o["in"] == param["in"] {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, param)
+		}
+	}
+	return merged
+}
+
+func parameterName(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+func specificity(segments []string) int {
+	n := 0
+	for _, segment := range segments {
+		if _, ok := parameterName(segment); !ok {
+			n++
+		}
+	}
+	return n
+}
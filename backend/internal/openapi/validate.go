@@ -0,0 +1,230 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"resellution/backend/internal/problem"
+)
+
+// ValidateRequests rejects requests to documented operations whose parameters or JSON
+// body do not match the document, with a validation.failed problem. Requests to paths
+// the document does not describe pass through, so the mux still answers 404 and 405.
+func ValidateRequests(spec *Spec, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, pathParams, ok := spec.Match(r.Method, r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		errs := op.validateParameters(r, pathParams)
+		bodyErrs, err := op.validateBody(r)
+		if err != nil {
+			problem.BodyError(w, r, err)
+			return
+		}
+		errs = append(errs, bodyErrs...)
+		if len(errs) > 0 {
+			problem.Write(w, r, problem.Validation(errs...))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ValidateResponses passes every response through unchanged and calls report when one
+// does not match the document: an undocumented operation, status or content type, or a
+// JSON body that does not match its schema. Tests use it to catch drift between the
+// handlers and the document.
+func ValidateResponses(spec *Spec, report func(r *http.Request, err error), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if err := spec.checkResponse(r, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+			report(r, err)
+		}
+	})
+}
+
+func (op *Operation) validateParameters(r *http.Request, pathParams map[string]string) []problem.FieldError {
+	var errs []problem.FieldError
+	query := r.URL.Query()
+	for _, param := range op.params {
+		name, _ := param["name"].(string)
+		required, _ := param["required"].(bool)
+
+		var raw string
+		var present bool
+		switch param["in"] {
+		case "query":
+			present = query.Has(name)
+			raw = query.Get(name)
+		case "path":
+			raw, present = pathParams[name]
+		case "header":
+			raw = r.Header.Get(name)
+			present = raw != ""
+		default:
+			continue
+		}
+		if !present {
+			if required {
+				errs = append(errs, problem.Field(name, problem.FieldRequired, "%s is required", name))
+			}
+			continue
+		}
+
+		value, ok := op.spec.coerce(param["schema"], raw)
+		if !ok {
+			errs = append(errs, problem.Field(name, problem.FieldInvalid, "%s must be %s", name, joinTypes(schemaTypes(op.spec.schemaType(param["schema"])))))
+			continue
+		}
+		errs = append(errs, op.spec.validate(param["schema"], value, name)...)
+	}
+	return errs
+}
+
+// validateBody restores r.Body after reading it. It returns an error only for a body
+// that cannot be read, such as one over the size limit, or is not JSON.
+func (op *Operation) validateBody(r *http.Request) ([]problem.FieldError, error) {
+	body, ok := op.requestBody()
+	if !ok || r.Body == nil {
+		return nil, nil
+	}
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if required, _ := body["required"].(bool); required {
+			return []problem.FieldError{problem.Field("body", problem.FieldRequired, "request body is required")}, nil
+		}
+		return nil, nil
+	}
+	schema, ok := mediaSchema(body, "application/json")
+	if !ok {
+		return nil, nil
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return op.spec.validate(schema, value, ""), nil
+}
+
+func (s *Spec) checkResponse(r *http.Request, status int, contentType string, body []byte) error {
+	op, _, ok := s.Match(r.Method, r.URL.Path)
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", r.Method, r.URL.Path)
+	}
+	response, ok := op.response(status)
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not documented", op.Method, op.Path, status)
+	}
+	content, _ := response["content"].(map[string]any)
+	if len(content) == 0 {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return fmt.Errorf("%s %s: status %d documents no body", op.Method, op.Path, status)
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%s %s: status %d has content type %q", op.Method, op.Path, status, contentType)
+	}
+	schema, ok := mediaSchema(response, mediaType)
+	if !ok {
+		return fmt.Errorf("%s %s: status %d does not document content type %s", op.Method, op.Path, status, mediaType)
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s %s: status %d body is not JSON: %w", op.Method, op.Path, status, err)
+	}
+	if errs := s.validate(schema, value, ""); len(errs) > 0 {
+		messages := make([]error, len(errs))
+		for i, e := range errs {
+			messages[i] = e
+		}
+		return fmt.Errorf("%s %s: status %d body does not match the schema: %w", op.Method, op.Path, status, errors.Join(messages...))
+	}
+	return nil
+}
+
+func mediaSchema(node map[string]any, mediaType string) (any, bool) {
+	content, _ := node["content"].(map[string]any)
+	media, ok := content[mediaType].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return media["schema"], true
+}
+
+// schemaType returns the type keyword of a schema after following $ref.
+func (s *Spec) schemaType(node any) any {
+	schema, _ := s.resolve(node).(map[string]any)
+	return schema["type"]
+}
+
+// coerce converts a query, path or header value to the type its schema declares.
+func (s *Spec) coerce(schemaNode any, raw string) (any, bool) {
+	types := schemaTypes(s.schemaType(schemaNode))
+	if len(types) == 0 {
+		return raw, true
+	}
+	for _, t := range types {
+		switch t {
+		case "string":
+			return raw, true
+		case "integer":
+			if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				return float64(n), true
+			}
+		case "number":
+			if n, err := strconv.ParseFloat(raw, 64); err == nil {
+				return n, true
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// responseCapture keeps a copy of the response body for ValidateResponses.
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
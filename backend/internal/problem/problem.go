@@ -0,0 +1,193 @@
+// Package problem writes API errors as RFC 9457 problem details. Every error carries a
+// stable code that clients switch on; the detail text is for humans and may change.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"resellution/backend/internal/observability"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// TypeBase prefixes the code to form the problem type URI.
+const TypeBase = "/problems/"
+
+// Stable error codes. Add new codes rather than changing existing ones.
+const (
+	CodeInvalidJSON           = "request.invalid_json"
+	CodeBodyTooLarge          = "request.body_too_large"
+	CodeMethodNotAllowed      = "request.method_not_allowed"
+	CodeRouteNotFound         = "request.not_found"
+	CodePreconditionRequired  = "request.precondition_required"
+	CodePreconditionFailed    = "request.precondition_failed"
+	CodeValidationFailed      = "validation.failed"
+	CodeUnauthenticated       = "auth.unauthenticated"
+	CodeInvalidToken          = "auth.invalid_token"
+	CodeSessionRevoked        = "auth.session_revoked"
+	CodeInvalidCredentials    = "auth.invalid_credentials"
+	CodeAccountSuspended      = "auth.account_suspended"
+	CodePasswordResetRequired = "auth.password_reset_required"
+	CodeForbidden             = "auth.forbidden"
+	CodeEmailTaken            = "user.email_taken"
+	CodeUserNotFound          = "user.not_found"
+	CodeSelfAction            = "user.self_action"
+	CodeCategoryNotFound      = "category.not_found"
+	CodeCategoryConflict      = "category.conflict"
+	CodeInvalidOTP            = "password_reset.invalid_otp"
+	CodeResetCooldown         = "password_reset.cooldown"
+	CodeRateLimited           = "rate_limit.exceeded"
+	CodeIdempotencyKeyInvalid = "idempotency.invalid_key"
+	CodeIdempotencyInProgress = "idempotency.in_progress"
+	CodeIdempotencyKeyReused  = "idempotency.key_reused"
+	CodeFeatureDisabled       = "feature.disabled"
+	CodeFeatureFlagNotFound   = "feature_flag.not_found"
+	CodeInternal              = "internal.error"
+)
+
+// Field error codes used in Problem.Errors.
+const (
+	FieldRequired = "required"
+	FieldTooShort = "too_short"
+	FieldTooLong  = "too_long"
+	FieldInvalid  = "invalid"
+	FieldNotFound = "not_found"
+)
+
+// Problem is an RFC 9457 problem details object with the code, request ID and field
+// errors as extension members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Extensions are extra members such as retry_after_minutes. They cannot replace
+	// the standard members.
+	Extensions map[string]any `json:"-"`
+}
+
+// FieldError describes one invalid request field or query parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// Field returns a FieldError with a formatted message.
+func Field(field, code, format string, args ...any) FieldError {
+	return FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// New returns a problem with the given status, code and detail.
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   TypeBase + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Validation returns a 400 validation.failed problem listing every invalid field. The
+// detail joins the field messages.
+func Validation(errs ...FieldError) *Problem {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Message
+	}
+	p := New(http.StatusBadRequest, CodeValidationFailed, strings.Join(messages, "; "))
+	p.Errors = errs
+	return p
+}
+
+// With adds an extension member.
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) Error() string {
+	return p.Code + ": " + p.Detail
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	encoded, err := json.Marshal((*plain)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return encoded, err
+	}
+	members := make(map[string]any, len(p.Extensions))
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	var standard map[string]any
+	if err := json.Unmarshal(encoded, &standard); err != nil {
+		return nil, err
+	}
+	for key, value := range standard {
+		members[key] = value
+	}
+	return json.Marshal(members)
+}
+
+// Write sends p, filling in the request path and request ID.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID, _ = observability.RequestIDFromContext(r.Context())
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// Error writes a problem built from status, code and detail.
+func Error(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	Write(w, r, New(status, code, detail))
+}
+
+// Unmatched writes the problem for a request that matched no route: 405 when the path
+// exists for other methods, otherwise 404. It is the fallback passed to
+// observability.CaptureRoute.
+func Unmatched(w http.ResponseWriter, r *http.Request, status int) {
+	if status == http.StatusMethodNotAllowed {
+		Error(w, r, status, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	Error(w, r, http.StatusNotFound, CodeRouteNotFound, "not found")
+}
+
+// BodyError writes the problem for a request body that could not be read or decoded:
+// 413 when it is larger than the http.MaxBytesReader limit, otherwise 400 invalid JSON.
+func BodyError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		Error(w, r, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, fmt.Sprintf("request body must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+	detail := "invalid JSON body"
+	// Name the offending field so clients can fix the request.
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		detail += ": unknown field " + field
+	}
+	Error(w, r, http.StatusBadRequest, CodeInvalidJSON, detail)
+}
@@ -0,0 +1,152 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"resellution/backend/internal/observability"
+)
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/password/reset/request", nil)
+	Write(rec, req, New(http.StatusTooManyRequests, CodeRateLimited, "slow down").With("retry_after_minutes", 3))
+
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Fatalf("content type = %q", got)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"type":                "/problems/rate_limit.exceeded",
+		"title":               "Too Many Requests",
+		"status":              float64(http.StatusTooManyRequests),
+		"detail":              "slow down",
+		"instance":            "/api/v1/auth/password/reset/request",
+		"code":                CodeRateLimited,
+		"retry_after_minutes": float64(3),
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("%s = %v, want %v", key, body[key], value)
+		}
+	}
+}
+
+func TestExtensionsCannotReplaceStandardMembers(t *testing.T) {
+	encoded, err := json.Marshal(New(http.StatusNotFound, CodeUserNotFound, "user not found").With("status", 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(encoded, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["status"] != float64(http.StatusNotFound) {
+		t.Fatalf("status = %v", body["status"])
+	}
+}
+
+func TestValidationJoinsFieldMessages(t *testing.T) {
+	p := Validation(
+		Field("email", FieldRequired, "email is required"),
+		Field("password", FieldTooShort, "password must be at least %d characters", 8),
+	)
+	if p.Status != http.StatusBadRequest || p.Code != CodeValidationFailed {
+		t.Fatalf("unexpected problem %+v", p)
+	}
+	if p.Detail != "email is required; password must be at least 8 characters" {
+		t.Fatalf("detail = %q", p.Detail)
+	}
+	if len(p.Errors) != 2 || p.Errors[1].Field != "password" {
+		t.Fatalf("errors = %+v", p.Errors)
+	}
+}
+
+func TestBodyError(t *testing.T) {
+	decode := func(body string, limit int64) error {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		decoder := json.NewDecoder(http.MaxBytesReader(rec, r.Body, limit))
+		decoder.DisallowUnknownFields()
+		var dst struct {
+			Email string `json:"email"`
+		}
+		return decoder.Decode(&dst)
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{"too large", decode(`{"email":"`+strings.Repeat("a", 64)+`"}`, 16), http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "request body must not exceed 16 bytes"},
+		{"unknown field", decode(`{"emial":"a@example.com"}`, 1024), http.StatusBadRequest, CodeInvalidJSON, `invalid JSON body: unknown field "emial"`},
+		{"syntax", decode(`{"email":`, 1024), http.StatusBadRequest, CodeInvalidJSON, "invalid JSON body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			BodyError(rec, httptest.NewRequest(http.MethodPost, "/", nil), tt.err)
+
+			var p Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.wantStatus || p.Code != tt.wantCode || p.Detail != tt.wantDetail {
+				t.Fatalf("got %d %s %q, want %d %s %q", rec.Code, p.Code, p.Detail, tt.wantStatus, tt.wantCode, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestUnmatchedRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/listings/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, http.StatusNotFound, CodeUserNotFound, "listing not found")
+	})
+	handler := observability.CaptureRoute(mux, Unmatched)
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/api/v1/nope", http.StatusNotFound, CodeRouteNotFound},
+		{http.MethodDelete, "/api/v1/listings/1", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{http.MethodGet, "/api/v1/listings/1", http.StatusNotFound, CodeUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != ContentType {
+				t.Fatalf("content type = %q, want %q", got, ContentType)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q: %v", rec.Body.String(), err)
+			}
+			if body["code"] != tt.code {
+				t.Fatalf("code = %v, want %s", body["code"], tt.code)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/listings/1", nil))
+	if got := rec.Header().Get("Allow"); !strings.Contains(got, http.MethodGet) {
+		t.Fatalf("Allow = %q, want it to list GET", got)
+	}
+}
@@ -1,27 +1,28 @@
 package ratelimit
 
 import (
+	"context"
 	"fmt"
-	"net"
 	"net/http"
-	"strings"
-	"sync"
+	"strconv"
 	"time"
-)
 
-type ipEntry struct {
-	count      int
-	windowFrom time.Time
-}
+	"resellution/backend/internal/clientip"
+	"resellution/backend/internal/problem"
+)
 
 type IPRateLimiter struct {
-	mu       sync.Mutex
-	entries  map[string]*ipEntry
-	limit    int
-	window   time.Duration
+	store  Store
+	limit  int
+	window time.Duration
 }
 
+// NewIPRateLimiter returns a limiter backed by an in-memory store.
 func NewIPRateLimiter(limit int, windowMinutes int) *IPRateLimiter {
+	return NewIPRateLimiterWithStore(NewMemoryStore(), limit, windowMinutes)
+}
+
+func NewIPRateLimiterWithStore(store Store, limit int, windowMinutes int) *IPRateLimiter {
 	if limit <= 0 {
 		limit = 5
 	}
@@ -29,69 +30,49 @@ func NewIPRateLimiter(limit int, windowMinutes int) *IPRateLimiter {
 		windowMinutes = 60
 	}
 	return &IPRateLimiter{
-		entries: make(map[string]*ipEntry),
-		limit:   limit,
-		window:  time.Duration(windowMinutes) * time.Minute,
+		store:  store,
+		limit:  limit,
+		window: time.Duration(windowMinutes) * time.Minute,
 	}
 }
 
-func (l *IPRateLimiter) Allow(ip string) bool {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	now := time.Now()
-	e, ok := l.entries[ip]
-	if !ok || now.Sub(e.windowFrom) >= l.window {
-		l.entries[ip] = &ipEntry{count: 1, windowFrom: now}
-		return true
-	}
-	if e.count >= l.limit {
-		return false
+func (l *IPRateLimiter) Allow(ctx context.Context, ip string) (bool, error) {
+	w, err := l.store.Increment(ctx, ip, l.window)
+	if err != nil {
+		return false, err
 	}
-	e.count++
-	return true
+	return w.Count <= l.limit, nil
 }
 
-func (l *IPRateLimiter) RemainingMinutes(ip string) int {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	e, ok := l.entries[ip]
-	if !ok {
-		return 0
+func (l *IPRateLimiter) RemainingMinutes(ctx context.Context, ip string) (int, error) {
+	w, ok, err := l.store.Get(ctx, ip)
+	if err != nil || !ok {
+		return 0, err
 	}
-	elapsed := time.Since(e.windowFrom)
+	elapsed := time.Since(w.Start)
 	if elapsed >= l.window {
-		return 0
+		return 0, nil
 	}
-	return int((l.window - elapsed).Minutes())
-}
-
-func extractIP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		if idx := strings.Index(xff, ","); idx >= 0 {
-			return strings.TrimSpace(xff[:idx])
-		}
-		return strings.TrimSpace(xff)
-	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return host
+	return int((l.window - elapsed).Minutes()), nil
 }
 
 func IPRateLimit(limiter *IPRateLimiter, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ip := extractIP(r)
-		if !limiter.Allow(ip) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			mins := limiter.RemainingMinutes(ip)
+		ip := clientip.FromRequest(r)
+		allowed, err := limiter.Allow(r.Context(), ip)
+		if err != nil {
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "failed to process request")
+			return
+		}
+		if !allowed {
+			mins, _ := limiter.RemainingMinutes(r.Context(), ip)
 			if mins < 1 {
 				mins = 1
 			}
-			_, _ = w.Write([]byte(fmt.Sprintf(`{"error":"Too many password reset requests. Try again in %d minutes"}`, mins)))
+			w.Header().Set("Retry-After", strconv.Itoa(mins*60))
+			detail := fmt.Sprintf("Too many password reset requests. Try again in %d minutes", mins)
+			problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, detail).
+				With("retry_after_minutes", mins))
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -1,21 +1,127 @@
 package ratelimit
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 func TestIPRateLimiter_Allow(t *testing.T) {
-	limiter := NewIPRateLimiter(3, 60)
-	ip := "192.168.1.1"
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			limiter := NewIPRateLimiterWithStore(store, 3, 60)
+			ip := "192.168.1.1-" + uuid.NewString()
+
+			// First 3 should be allowed
+			for i := 0; i < 3; i++ {
+				if allowed, err := limiter.Allow(context.Background(), ip); err != nil || !allowed {
+					t.Errorf("request %d: expected allow, got deny (err=%v)", i+1, err)
+				}
+			}
+			// 4th should be denied
+			if allowed, _ := limiter.Allow(context.Background(), ip); allowed {
+				t.Error("request 4: expected deny, got allow")
+			}
+		})
+	}
+}
+
+func TestMemoryStore_EvictsExpiredWindows(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	if _, err := store.Increment(ctx, "short", time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Increment(ctx, "long", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	store.lastSweep = time.Time{}
+	if _, err := store.Increment(ctx, "other", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := store.Get(ctx, "short"); ok {
+		t.Error("expired window was kept")
+	}
+	if w, ok, _ := store.Get(ctx, "long"); !ok || w.Count != 1 {
+		t.Errorf("live window = %+v, %v; want it kept", w, ok)
+	}
+}
+
+func TestPostgresStore_Purge(t *testing.T) {
+	store, ok := testStores(t)["postgres"].(PostgresStore)
+	if !ok {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	key := "purge-" + uuid.NewString()
+	if _, err := store.Increment(ctx, key, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Purge(ctx, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := store.Get(ctx, key); err != nil || !ok {
+		t.Fatalf("live counter purged: ok=%v err=%v", ok, err)
+	}
+	if _, err := store.Purge(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := store.Get(ctx, key); err != nil || ok {
+		t.Fatalf("expired counter kept: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestFallbackStore_UsesFallbackOnError(t *testing.T) {
+	var reported int
+	store := FallbackStore{
+		Primary:  failingStore{},
+		Fallback: NewMemoryStore(),
+		OnError:  func(error) { reported++ },
+	}
+	limiter := NewIPRateLimiterWithStore(store, 1, 60)
+
+	if allowed, err := limiter.Allow(context.Background(), "10.0.0.1"); err != nil || !allowed {
+		t.Fatalf("request 1: expected allow, got allowed=%v err=%v", allowed, err)
+	}
+	if allowed, err := limiter.Allow(context.Background(), "10.0.0.1"); err != nil || allowed {
+		t.Fatalf("request 2: expected deny, got allowed=%v err=%v", allowed, err)
+	}
+	if reported != 2 {
+		t.Errorf("expected 2 reported errors, got %d", reported)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Increment(context.Context, string, time.Duration) (Window, error) {
+	return Window{}, errors.New("store unavailable")
+}
+
+func (failingStore) Get(context.Context, string) (Window, bool, error) {
+	return Window{}, false, errors.New("store unavailable")
+}
+
+// testStores returns the memory store and, when TEST_DATABASE_URL is set, a Postgres
+// store against a migrated database.
+func testStores(t *testing.T) map[string]Store {
+	stores := map[string]Store{"memory": NewMemoryStore()}
 
-	// First 3 should be allowed
-	for i := 0; i < 3; i++ {
-		if !limiter.Allow(ip) {
-			t.Errorf("request %d: expected allow, got deny", i+1)
-		}
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		return stores
 	}
-	// 4th should be denied
-	if limiter.Allow(ip) {
-		t.Error("request 4: expected deny, got allow")
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
 	}
+	t.Cleanup(func() { db.Close() })
+	stores["postgres"] = PostgresStore{DB: db}
+	return stores
 }
@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Window is the state of a single rate limit counter.
+type Window struct {
+	Count int
+	Start time.Time
+}
+
+// Store persists rate limit counters. Increment must be atomic: it records a hit for
+// key, starting a fresh window when none exists or the current one is older than window.
+type Store interface {
+	Increment(ctx context.Context, key string, window time.Duration) (Window, error)
+	Get(ctx context.Context, key string) (Window, bool, error)
+}
+
+// MemoryStore keeps counters in process memory. Counters are per instance and are lost
+// on restart. Expired windows are evicted as new hits arrive, so one-off clients do not
+// accumulate.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	Window
+	expires time.Time
+}
+
+// sweepInterval bounds how often Increment scans for expired windows.
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Increment(_ context.Context, key string, window time.Duration) (Window, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.evictExpired(now)
+	}
+	e, ok := s.entries[key]
+	if !ok || now.Sub(e.Start) >= window {
+		e = &memoryEntry{Window: Window{Count: 0, Start: now}, expires: now.Add(window)}
+		s.entries[key] = e
+	}
+	e.Count++
+	return e.Window, nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (Window, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return Window{}, false, nil
+	}
+	return e.Window, true, nil
+}
+
+// evictExpired drops every window that has ended. Each entry keeps the window it was
+// started with, so limiters with different windows can share the store.
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// PostgresStore keeps counters in the unlogged rate_limit_counters table so every
+// instance behind the load balancer shares the same limits.
+type PostgresStore struct {
+	DB *sql.DB
+}
+
+func (s PostgresStore) Increment(ctx context.Context, key string, window time.Duration) (Window, error) {
+	query := `
+		INSERT INTO rate_limit_counters (key, count, window_start)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET count = CASE
+				WHEN rate_limit_counters.window_start <= NOW() - $2 * INTERVAL '1 millisecond' THEN 1
+				ELSE rate_limit_counters.count + 1
+			END,
+			window_start = CASE
+				WHEN rate_limit_counters.window_start <= NOW() - $2 * INTERVAL '1 millisecond' THEN NOW()
+				ELSE rate_limit_counters.window_start
+			END
+		RETURNING count, window_start
+	`
+
+	var w Window
+	err := s.DB.QueryRowContext(ctx, query, key, window.Milliseconds()).Scan(&w.Count, &w.Start)
+	if err != nil {
+		return Window{}, err
+	}
+	return w, nil
+}
+
+func (s PostgresStore) Get(ctx context.Context, key string) (Window, bool, error) {
+	query := `
+		SELECT count, window_start
+		FROM rate_limit_counters
+		WHERE key = $1
+	`
+
+	var w Window
+	err := s.DB.QueryRowContext(ctx, query, key).Scan(&w.Count, &w.Start)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Window{}, false, nil
+		}
+		return Window{}, false, err
+	}
+	return w, true, nil
+}
+
+// Purge deletes counters whose window started before cutoff and returns how many were
+// removed. Callers pass the current time minus the longest window in use; the rows are
+// already ignored by then, and this only keeps the table small.
+func (s PostgresStore) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.DB.ExecContext(ctx, `DELETE FROM rate_limit_counters WHERE window_start < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// FallbackStore uses Primary while it answers within Timeout and switches to Fallback
+// (normally a MemoryStore) when it errors or is too slow, so a struggling database
+// degrades limits to per-instance instead of failing requests.
+type FallbackStore struct {
+	Primary  Store
+	Fallback Store
+	Timeout  time.Duration
+	OnError  func(err error)
+}
+
+func (s FallbackStore) Increment(ctx context.Context, key string, window time.Duration) (Window, error) {
+	primaryCtx, cancel := s.primaryContext(ctx)
+	defer cancel()
+
+	w, err := s.Primary.Increment(primaryCtx, key, window)
+	if err == nil {
+		return w, nil
+	}
+	s.reportError(err)
+	return s.Fallback.Increment(ctx, key, window)
+}
+
+func (s FallbackStore) Get(ctx context.Context, key string) (Window, bool, error) {
+	primaryCtx, cancel := s.primaryContext(ctx)
+	defer cancel()
+
+	w, ok, err := s.Primary.Get(primaryCtx, key)
+	if err == nil {
+		return w, ok, nil
+	}
+	s.reportError(err)
+	return s.Fallback.Get(ctx, key)
+}
+
+func (s FallbackStore) primaryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
+
+func (s FallbackStore) reportError(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
@@ -0,0 +1,473 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"resellution/backend/internal/db"
+	"resellution/backend/internal/utils"
+)
+
+// Profiles select how much data Run writes. Reference seeds are applied for every
+// profile; dev and staging also generate synthetic marketplace data.
+const (
+	ProfileProd    = "prod"
+	ProfileStaging = "staging"
+	ProfileDev     = "dev"
+)
+
+// DemoPassword is the password of every generated user.
+const DemoPassword = "Resell123"
+
+type volume struct {
+	users             int
+	listingsPerCombo  int
+	conversations     int
+	favoritesPerUser  int
+	maxImagesPerEntry int
+	// staff adds an admin and a moderator among the users. Only dev does, since
+	// every demo account shares the public DemoPassword.
+	staff bool
+}
+
+var volumes = map[string]volume{
+	ProfileDev:     {users: 40, listingsPerCombo: 1, conversations: 60, favoritesPerUser: 3, maxImagesPerEntry: 4, staff: true},
+	ProfileStaging: {users: 200, listingsPerCombo: 4, conversations: 400, favoritesPerUser: 6, maxImagesPerEntry: 6},
+}
+
+// namespace makes generated IDs stable across runs, so reruns (even with a different
+// random seed) update nothing and insert nothing twice.
+var namespace = uuid.MustParse("6f1d3f8e-2c55-4c1b-9d0e-7a4b1c2d3e4f")
+
+// Seeder applies reference seeds from FS and, for dev and staging, synthetic data
+// generated from RandSeed.
+type Seeder struct {
+	DB       *sql.DB
+	FS       fs.FS
+	Profile  string
+	RandSeed int64
+}
+
+// Summary counts rows inserted by Run. Rows that already existed are not counted.
+type Summary struct {
+	ReferenceFiles int
+	Users          int64
+	Listings       int64
+	Images         int64
+	Conversations  int64
+	Messages       int64
+	Favorites      int64
+}
+
+func (s Seeder) Run(ctx context.Context) (Summary, error) {
+	if s.Profile != ProfileProd && s.Profile != ProfileStaging && s.Profile != ProfileDev {
+		return Summary{}, fmt.Errorf("unknown seed profile %q (expected dev, staging or prod)", s.Profile)
+	}
+
+	var summary Summary
+	err := db.TxManager{DB: s.DB}.WithTx(ctx, func(ctx context.Context) error {
+		applied, err := s.applyReference(ctx)
+		if err != nil {
+			return err
+		}
+		summary.ReferenceFiles = applied
+
+		vol, ok := volumes[s.Profile]
+		if !ok {
+			return nil
+		}
+		g := generator{
+			conn:   db.Conn(ctx, s.DB),
+			rng:    rand.New(rand.NewSource(s.RandSeed)),
+			vol:    vol,
+			now:    time.Now().UTC(),
+			result: &summary,
+		}
+		return g.run(ctx)
+	})
+	return summary, err
+}
+
+func (s Seeder) applyReference(ctx context.Context) (int, error) {
+	names, err := fs.Glob(s.FS, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		content, err := fs.ReadFile(s.FS, name)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := db.Conn(ctx, s.DB).ExecContext(ctx, string(content)); err != nil {
+			return 0, fmt.Errorf("seed %s: %w", name, err)
+		}
+	}
+	return len(names), nil
+}
+
+type generator struct {
+	conn   db.Querier
+	rng    *rand.Rand
+	vol    volume
+	now    time.Time
+	result *Summary
+}
+
+type demoUser struct {
+	id   string
+	city city
+}
+
+type demoListing struct {
+	id       string
+	sellerID string
+	status   string
+}
+
+type city struct {
+	name  string
+	state string
+}
+
+var cities = []city{
+	{"Mumbai", "Maharashtra"},
+	{"Pune", "Maharashtra"},
+	{"Delhi", "Delhi"},
+	{"Bengaluru", "Karnataka"},
+	{"Hyderabad", "Telangana"},
+	{"Chennai", "Tamil Nadu"},
+	{"Kolkata", "West Bengal"},
+	{"Ahmedabad", "Gujarat"},
+	{"Jaipur", "Rajasthan"},
+	{"Lucknow", "Uttar Pradesh"},
+	{"Kochi", "Kerala"},
+	{"Chandigarh", "Chandigarh"},
+	{"Indore", "Madhya Pradesh"},
+	{"Bhubaneswar", "Odisha"},
+}
+
+var firstNames = []string{
+	"Aarav", "Vivaan", "Aditya", "Arjun", "Rohan", "Karthik", "Rahul", "Siddharth", "Ishaan", "Manish",
+	"Ananya", "Diya", "Priya", "Kavya", "Sneha", "Meera", "Pooja", "Riya", "Lakshmi", "Nandini",
+}
+
+var lastNames = []string{
+	"Sharma", "Verma", "Patel", "Reddy", "Iyer", "Nair", "Gupta", "Singh", "Das", "Mukherjee",
+	"Joshi", "Kulkarni", "Menon", "Rao", "Chopra", "Banerjee", "Pillai", "Shah", "Mehta", "Agarwal",
+}
+
+var conditions = []string{"new", "like_new", "good", "fair", "poor"}
+
+var itemsByCategory = map[string][]string{
+	"electronics":          {"iPhone 12", "Samsung Galaxy S21", "Dell Inspiron Laptop", "Sony Bluetooth Headphones", "Canon DSLR Camera", "OnePlus Smart TV"},
+	"furniture":            {"Teak Wood Dining Table", "3-Seater Sofa", "Study Desk", "Queen Size Bed", "Office Chair", "Bookshelf"},
+	"clothing-accessories": {"Silk Saree", "Leather Jacket", "Men's Kurta Set", "Titan Wrist Watch", "Designer Handbag", "Sports Shoes"},
+	"books-media":          {"NCERT Textbook Set", "Harry Potter Box Set", "UPSC Preparation Books", "Guitar Chord Book", "Board Game Collection", "Vinyl Records"},
+	"vehicles":             {"Honda Activa", "Royal Enfield Classic 350", "Hero Cycle", "Maruti Swift", "Bajaj Pulsar", "Electric Scooter"},
+	"home-garden":          {"Pressure Cooker", "Mixer Grinder", "Potted Plants", "Water Purifier", "Ceiling Fan", "Air Cooler"},
+	"sports-outdoors":      {"Cricket Bat", "Badminton Racket", "Yoga Mat", "Camping Tent", "Football", "Dumbbell Set"},
+	"toys-games":           {"LEGO Set", "Remote Control Car", "Carrom Board", "Chess Set", "Soft Toys Bundle", "Kids Bicycle"},
+	"health-beauty":        {"Hair Dryer", "Massage Chair", "Fitness Band", "Trimmer", "Weighing Scale", "Ayurvedic Kit"},
+	"other":                {"Musical Keyboard", "Sewing Machine", "Suitcase", "Study Lamp", "Wall Clock", "Tabla Set"},
+}
+
+var messageBodies = []string{
+	"Hi, is this still available?",
+	"What's the lowest you can do?",
+	"Can I come see it this weekend?",
+	"Yes, it's available.",
+	"Price is slightly negotiable.",
+	"Does it come with the original bill?",
+	"Sure, share a time that works for you.",
+	"Is delivery possible?",
+}
+
+func (g generator) run(ctx context.Context) error {
+	categories, err := g.categories(ctx)
+	if err != nil {
+		return err
+	}
+	passwordHash, err := utils.HashPassword(ctx, DemoPassword)
+	if err != nil {
+		return err
+	}
+
+	users, err := g.users(ctx, passwordHash)
+	if err != nil {
+		return err
+	}
+	listings, err := g.listings(ctx, users, categories)
+	if err != nil {
+		return err
+	}
+	if err := g.conversations(ctx, users, listings); err != nil {
+		return err
+	}
+	return g.favorites(ctx, users, listings)
+}
+
+func (g generator) categories(ctx context.Context) (map[string]string, error) {
+	rows, err := g.conn.QueryContext(ctx, `SELECT id, slug FROM categories ORDER BY slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[string]string)
+	for rows.Next() {
+		var id, slug string
+		if err := rows.Scan(&id, &slug); err != nil {
+			return nil, err
+		}
+		categories[slug] = id
+	}
+	return categories, rows.Err()
+}
+
+func (g generator) users(ctx context.Context, passwordHash string) ([]demoUser, error) {
+	users := make([]demoUser, 0, g.vol.users)
+	for i := 0; i < g.vol.users; i++ {
+		first := firstNames[g.rng.Intn(len(firstNames))]
+		last := lastNames[g.rng.Intn(len(lastNames))]
+		c := cities[i%len(cities)]
+		user := demoUser{id: stableID("user", i), city: c}
+
+		role := "user"
+		email := fmt.Sprintf("demo.user%03d@resellution.dev", i+1)
+		switch {
+		case g.vol.staff && i == 0:
+			role, email = "admin", "demo.admin@resellution.dev"
+		case g.vol.staff && i == 1:
+			role, email = "moderator", "demo.moderator@resellution.dev"
+		}
+
+		query := `
+			INSERT INTO users (id, email, password_hash, full_name, phone, city, state, bio, role, is_verified, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)
+			ON CONFLICT DO NOTHING
+		`
+		result, err := g.conn.ExecContext(ctx, query,
+			user.id,
+			email,
+			passwordHash,
+			first+" "+last,
+			fmt.Sprintf("+9190000%05d", i+1),
+			c.name,
+			c.state,
+			fmt.Sprintf("Buying and selling pre-owned goods in %s.", c.name),
+			role,
+			g.rng.Intn(4) != 0,
+			g.pastTime(180),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("seed user %s: %w", email, err)
+		}
+		g.result.Users += rowsAffected(result)
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// listings creates listingsPerCombo listings for every category and condition pair.
+func (g generator) listings(ctx context.Context, users []demoUser, categories map[string]string) ([]demoListing, error) {
+	slugs := make([]string, 0, len(categories))
+	for slug := range categories {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+
+	var listings []demoListing
+	n := 0
+	for _, slug := range slugs {
+		items := itemsByCategory[slug]
+		if len(items) == 0 {
+			items = itemsByCategory["other"]
+		}
+		for _, condition := range conditions {
+			for k := 0; k < g.vol.listingsPerCombo; k++ {
+				seller := users[g.rng.Intn(len(users))]
+				item := items[g.rng.Intn(len(items))]
+				listing := demoListing{id: stableID("listing", n), sellerID: seller.id, status: g.listingStatus()}
+				createdAt := g.pastTime(90)
+
+				query := `
+					INSERT INTO listings (id, seller_id, category_id, title, description, condition, price, currency, city, state, status, view_count, created_at, updated_at)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, 'INR', $8, $9, $10, $11, $12, $12)
+					ON CONFLICT DO NOTHING
+				`
+				result, err := g.conn.ExecContext(ctx, query,
+					listing.id,
+					seller.id,
+					categories[slug],
+					fmt.Sprintf("%s (%s)", item, strings.ReplaceAll(condition, "_", " ")),
+					fmt.Sprintf("Selling my %s. Condition: %s. Pickup in %s, reasonable offers welcome.", item, strings.ReplaceAll(condition, "_", " "), seller.city.name),
+					condition,
+					g.price(slug),
+					seller.city.name,
+					seller.city.state,
+					listing.status,
+					g.rng.Intn(500),
+					createdAt,
+				)
+				if err != nil {
+					return nil, fmt.Errorf("seed listing %s: %w", listing.id, err)
+				}
+				inserted := rowsAffected(result)
+				g.result.Listings += inserted
+
+				if inserted > 0 {
+					if err := g.images(ctx, listing.id); err != nil {
+						return nil, err
+					}
+				}
+				listings = append(listings, listing)
+				n++
+			}
+		}
+	}
+	return listings, nil
+}
+
+func (g generator) images(ctx context.Context, listingID string) error {
+	count := 1 + g.rng.Intn(g.vol.maxImagesPerEntry)
+	for position := 0; position < count; position++ {
+		query := `
+			INSERT INTO listing_images (id, listing_id, image_url, position)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING
+		`
+		result, err := g.conn.ExecContext(ctx, query,
+			uuid.NewSHA1(namespace, []byte(fmt.Sprintf("image-%s-%d", listingID, position))).String(),
+			listingID,
+			fmt.Sprintf("https://picsum.photos/seed/%s-%d/800/600", listingID[:8], position),
+			position,
+		)
+		if err != nil {
+			return fmt.Errorf("seed image for %s: %w", listingID, err)
+		}
+		g.result.Images += rowsAffected(result)
+	}
+	return nil
+}
+
+func (g generator) conversations(ctx context.Context, users []demoUser, listings []demoListing) error {
+	for i := 0; i < g.vol.conversations; i++ {
+		listing := listings[g.rng.Intn(len(listings))]
+		buyer := users[g.rng.Intn(len(users))]
+		if buyer.id == listing.sellerID {
+			continue
+		}
+
+		conversationID := uuid.NewSHA1(namespace, []byte("conversation-"+listing.id+"-"+buyer.id)).String()
+		startedAt := g.pastTime(30)
+		query := `
+			INSERT INTO conversations (id, listing_id, buyer_id, seller_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $5)
+			ON CONFLICT DO NOTHING
+		`
+		result, err := g.conn.ExecContext(ctx, query, conversationID, listing.id, buyer.id, listing.sellerID, startedAt)
+		if err != nil {
+			return fmt.Errorf("seed conversation: %w", err)
+		}
+		if rowsAffected(result) == 0 {
+			continue
+		}
+		g.result.Conversations++
+
+		messageCount := 2 + g.rng.Intn(5)
+		for m := 0; m < messageCount; m++ {
+			sender := buyer.id
+			if m%2 == 1 {
+				sender = listing.sellerID
+			}
+			_, err := g.conn.ExecContext(ctx, `
+				INSERT INTO messages (id, conversation_id, sender_id, body, is_read, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT DO NOTHING
+			`,
+				uuid.NewSHA1(namespace, []byte(fmt.Sprintf("message-%s-%d", conversationID, m))).String(),
+				conversationID,
+				sender,
+				messageBodies[g.rng.Intn(len(messageBodies))],
+				m < messageCount-1,
+				startedAt.Add(time.Duration(m*17)*time.Minute),
+			)
+			if err != nil {
+				return fmt.Errorf("seed message: %w", err)
+			}
+			g.result.Messages++
+		}
+	}
+	return nil
+}
+
+func (g generator) favorites(ctx context.Context, users []demoUser, listings []demoListing) error {
+	for _, user := range users {
+		for i := 0; i < g.vol.favoritesPerUser; i++ {
+			listing := listings[g.rng.Intn(len(listings))]
+			if listing.sellerID == user.id {
+				continue
+			}
+			result, err := g.conn.ExecContext(ctx,
+				`INSERT INTO favorites (user_id, listing_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+				user.id, listing.id)
+			if err != nil {
+				return fmt.Errorf("seed favorite: %w", err)
+			}
+			g.result.Favorites += rowsAffected(result)
+		}
+	}
+	return nil
+}
+
+func (g generator) listingStatus() string {
+	switch n := g.rng.Intn(10); {
+	case n < 7:
+		return "active"
+	case n < 8:
+		return "reserved"
+	default:
+		return "sold"
+	}
+}
+
+// price returns a plausible INR price for the category, rounded to 50.
+func (g generator) price(slug string) float64 {
+	ranges := map[string][2]int{
+		"electronics": {2000, 80000},
+		"furniture":   {1500, 40000},
+		"vehicles":    {8000, 400000},
+		"books-media": {100, 3000},
+	}
+	r, ok := ranges[slug]
+	if !ok {
+		r = [2]int{200, 10000}
+	}
+	return float64((r[0] + g.rng.Intn(r[1]-r[0])) / 50 * 50)
+}
+
+func (g generator) pastTime(maxDays int) time.Time {
+	return g.now.Add(-time.Duration(g.rng.Int63n(int64(maxDays) * int64(24*time.Hour))))
+}
+
+func stableID(kind string, i int) string {
+	return uuid.NewSHA1(namespace, []byte(fmt.Sprintf("%s-%d", kind, i))).String()
+}
+
+func rowsAffected(result sql.Result) int64 {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return n
+}
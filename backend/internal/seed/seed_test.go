@@ -0,0 +1,43 @@
+package seed
+
+import (
+	"context"
+	"io/fs"
+	"regexp"
+	"testing"
+
+	"resellution/backend/seeds"
+)
+
+func TestRun_UnknownProfile(t *testing.T) {
+	if _, err := (Seeder{Profile: "demo"}).Run(context.Background()); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+}
+
+// Every reference category should have demo items, otherwise its listings fall back to
+// generic "other" titles.
+func TestItemsCoverReferenceCategories(t *testing.T) {
+	content, err := fs.ReadFile(seeds.FS, "001_categories.sql")
+	if err != nil {
+		t.Fatalf("read categories seed: %v", err)
+	}
+	slugs := regexp.MustCompile(`'[^']+', '([a-z-]+)'`).FindAllStringSubmatch(string(content), -1)
+	if len(slugs) == 0 {
+		t.Fatal("no category slugs found in seed")
+	}
+	for _, match := range slugs {
+		if len(itemsByCategory[match[1]]) == 0 {
+			t.Errorf("no demo items for category %q", match[1])
+		}
+	}
+}
+
+func TestStableID(t *testing.T) {
+	if stableID("user", 3) != stableID("user", 3) {
+		t.Error("expected stable IDs across calls")
+	}
+	if stableID("user", 3) == stableID("listing", 3) {
+		t.Error("expected IDs to differ by kind")
+	}
+}
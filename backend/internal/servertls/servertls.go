@@ -0,0 +1,101 @@
+// Package servertls builds the TLS configuration for serving HTTPS directly, without a
+// terminating proxy in front. Serving TLS also enables HTTP/2.
+package servertls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// TLS modes.
+const (
+	// ModeOff serves plain HTTP/1.1, for deployments behind a TLS-terminating proxy.
+	ModeOff = "off"
+	// ModeFiles serves the certificate and key from PEM files.
+	ModeFiles = "files"
+	// ModeSelfSigned serves a certificate generated at startup, for local development.
+	ModeSelfSigned = "self-signed"
+)
+
+// selfSignedHosts are the names a self-signed certificate is valid for.
+var selfSignedHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// Config returns the TLS configuration for mode, or nil for ModeOff. It advertises
+// HTTP/2 ahead of HTTP/1.1 through ALPN.
+func Config(mode, certFile, keyFile string) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	switch mode {
+	case ModeOff:
+		return nil, nil
+	case ModeFiles:
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("TLS certificate and key files are required")
+		}
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+	case ModeSelfSigned:
+		cert, err = SelfSigned(selfSignedHosts, 365*24*time.Hour)
+	default:
+		return nil, fmt.Errorf("unknown TLS mode %q", mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}, nil
+}
+
+// SelfSigned generates an ECDSA P-256 certificate for hosts, which may be DNS names or
+// IP addresses, valid from now for validity. Browsers warn about it; it is for
+// development only.
+func SelfSigned(hosts []string, validity time.Duration) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"ReSellution development"}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
@@ -0,0 +1,102 @@
+package servertls
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	certFile, keyFile := writeKeyPair(t)
+	tests := []struct {
+		name    string
+		mode    string
+		cert    string
+		key     string
+		wantNil bool
+		wantErr bool
+	}{
+		{"off", ModeOff, "", "", true, false},
+		{"files", ModeFiles, certFile, keyFile, false, false},
+		{"files missing key", ModeFiles, certFile, "", false, true},
+		{"files unreadable", ModeFiles, certFile, filepath.Join(t.TempDir(), "missing.pem"), false, true},
+		{"self-signed", ModeSelfSigned, "", "", false, false},
+		{"unknown", "auto", "", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Config(tt.mode, tt.cert, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (cfg == nil) != tt.wantNil {
+				t.Fatalf("config = %v, want nil %v", cfg, tt.wantNil)
+			}
+			if cfg != nil && (len(cfg.Certificates) != 1 || cfg.NextProtos[0] != "h2") {
+				t.Errorf("config = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestSelfSignedServesHTTP2(t *testing.T) {
+	cfg, err := Config(ModeSelfSigned, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.TLS = cfg
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cfg.Certificates[0].Leaf)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, ServerName: "localhost"},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("protocol = %s, want HTTP/2", resp.Proto)
+	}
+}
+
+// writeKeyPair writes a certificate and key as PEM files and returns their paths.
+func writeKeyPair(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	cert, err := SelfSigned([]string{"localhost"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
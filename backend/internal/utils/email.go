@@ -1,13 +1,20 @@
 package utils
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/smtp"
 	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type EmailSender interface {
-	Send(toEmail, subject, body string) error
+	Send(ctx context.Context, toEmail, subject, body string) error
 }
 
 type SMTPEmailSender struct {
@@ -19,7 +26,17 @@ type SMTPEmailSender struct {
 	FromName  string
 }
 
-func (s SMTPEmailSender) Send(toEmail, subject, body string) error {
+func (s SMTPEmailSender) Send(ctx context.Context, toEmail, subject, body string) (err error) {
+	_, span := otel.Tracer(tracerName).Start(ctx, "smtp.send", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("server.address", s.Host), attribute.String("server.port", s.Port)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	if strings.TrimSpace(s.Host) == "" {
 		return fmt.Errorf("smtp host is not configured")
 	}
@@ -47,3 +64,26 @@ func (s SMTPEmailSender) Send(toEmail, subject, body string) error {
 
 	return smtp.SendMail(addr, auth, s.FromEmail, []string{toEmail}, msg)
 }
+
+// Ping connects to the SMTP server and waits for its greeting without sending mail.
+func (s SMTPEmailSender) Ping(ctx context.Context) error {
+	if strings.TrimSpace(s.Host) == "" {
+		return fmt.Errorf("smtp host is not configured")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.Host, s.Port))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	return client.Quit()
+}
@@ -1,8 +1,18 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const tracerName = "resellution/backend/utils"
+
+func HashPassword(ctx context.Context, password string) (string, error) {
+	_, span := otel.Tracer(tracerName).Start(ctx, "bcrypt.hash")
+	defer span.End()
 
-func HashPassword(password string) (string, error) {
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
@@ -10,6 +20,9 @@ func HashPassword(password string) (string, error) {
 	return string(hashed), nil
 }
 
-func VerifyPassword(password, hash string) bool {
+func VerifyPassword(ctx context.Context, password, hash string) bool {
+	_, span := otel.Tracer(tracerName).Start(ctx, "bcrypt.compare")
+	defer span.End()
+
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
 }
@@ -16,8 +16,29 @@ type TokenManager struct {
 }
 
 type tokenPayload struct {
-	Sub string `json:"sub"`
-	Exp int64  `json:"exp"`
+	Sub         string   `json:"sub"`
+	Exp         int64    `json:"exp"`
+	Role        string   `json:"role,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
+}
+
+// Claims are the authorization facts carried in a token. Role and permissions are a
+// snapshot taken when the token was issued.
+type Claims struct {
+	UserID      string
+	Role        string
+	Permissions []string
+	SessionID   string
+}
+
+func (c Claims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
 }
 
 func NewTokenManager(secret string) TokenManager {
@@ -25,13 +46,23 @@ func NewTokenManager(secret string) TokenManager {
 }
 
 func (m TokenManager) Create(userID string, expiresAt time.Time) (string, error) {
+	return m.CreateWithClaims(Claims{UserID: userID}, expiresAt)
+}
+
+func (m TokenManager) CreateWithClaims(claims Claims, expiresAt time.Time) (string, error) {
 	header := map[string]string{"alg": "HS256", "typ": "JWT"}
 	headerJSON, err := json.Marshal(header)
 	if err != nil {
 		return "", err
 	}
 
-	payloadJSON, err := json.Marshal(tokenPayload{Sub: userID, Exp: expiresAt.Unix()})
+	payloadJSON, err := json.Marshal(tokenPayload{
+		Sub:         claims.UserID,
+		Exp:         expiresAt.Unix(),
+		Role:        claims.Role,
+		Permissions: claims.Permissions,
+		SessionID:   claims.SessionID,
+	})
 	if err != nil {
 		return "", err
 	}
@@ -48,39 +79,52 @@ func (m TokenManager) Create(userID string, expiresAt time.Time) (string, error)
 }
 
 func (m TokenManager) Parse(token string) (string, error) {
+	claims, err := m.ParseClaims(token)
+	if err != nil {
+		return "", err
+	}
+	return claims.UserID, nil
+}
+
+func (m TokenManager) ParseClaims(token string) (Claims, error) {
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		return "", errors.New("invalid token format")
+		return Claims{}, errors.New("invalid token format")
 	}
 
 	unsigned := parts[0] + "." + parts[1]
 	expectedSig := sign(unsigned, m.Secret)
 	providedSig, err := base64.RawURLEncoding.DecodeString(parts[2])
 	if err != nil {
-		return "", errors.New("invalid token signature")
+		return Claims{}, errors.New("invalid token signature")
 	}
 	if !hmac.Equal(expectedSig, providedSig) {
-		return "", errors.New("token signature mismatch")
+		return Claims{}, errors.New("token signature mismatch")
 	}
 
 	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return "", errors.New("invalid token payload")
+		return Claims{}, errors.New("invalid token payload")
 	}
 
 	var payload tokenPayload
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-		return "", errors.New("invalid token payload")
+		return Claims{}, errors.New("invalid token payload")
 	}
 
 	if payload.Sub == "" {
-		return "", errors.New("missing subject")
+		return Claims{}, errors.New("missing subject")
 	}
 	if time.Now().Unix() >= payload.Exp {
-		return "", errors.New("token expired")
+		return Claims{}, errors.New("token expired")
 	}
 
-	return payload.Sub, nil
+	return Claims{
+		UserID:      payload.Sub,
+		Role:        payload.Role,
+		Permissions: payload.Permissions,
+		SessionID:   payload.SessionID,
+	}, nil
 }
 
 func sign(unsigned string, secret []byte) []byte {
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversations;
+DROP TABLE IF EXISTS favorites;
+DROP TABLE IF EXISTS listing_images;
+DROP TABLE IF EXISTS listings;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_categories_parent_id;
+DROP INDEX IF EXISTS idx_listings_city;
+DROP INDEX IF EXISTS idx_users_city;
+DROP INDEX IF EXISTS idx_users_phone_unique;
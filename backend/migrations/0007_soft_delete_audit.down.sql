@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_listings_active_status_created;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_users_active_email;
+
+ALTER TABLE listings
+    DROP CONSTRAINT IF EXISTS listings_status_deleted_at_consistency;
+
+ALTER TABLE listings
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deleted_at;
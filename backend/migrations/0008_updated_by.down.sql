@@ -0,0 +1,5 @@
+ALTER TABLE listings
+    DROP COLUMN IF EXISTS updated_by;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS updated_by;
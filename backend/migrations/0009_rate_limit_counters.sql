@@ -0,0 +1,7 @@
+-- Shared rate limit counters. UNLOGGED: counters are disposable and skip WAL for fast upserts.
+
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_counters (
+    key TEXT PRIMARY KEY,
+    count INTEGER NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,47 @@
+-- Role-based access control: every user has one role, roles grant permissions.
+
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('user', 'Regular marketplace user'),
+    ('moderator', 'Can review users and listings'),
+    ('admin', 'Full administrative access')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('users.read', 'View any user account'),
+    ('users.moderate', 'Deactivate and restore user accounts'),
+    ('roles.manage', 'Assign roles to users'),
+    ('categories.manage', 'Create, update and delete categories'),
+    ('listings.moderate', 'Hide and restore any listing')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('moderator', 'users.read'),
+    ('moderator', 'users.moderate'),
+    ('moderator', 'listings.moderate'),
+    ('admin', 'users.read'),
+    ('admin', 'users.moderate'),
+    ('admin', 'roles.manage'),
+    ('admin', 'categories.manage'),
+    ('admin', 'listings.moderate')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user' REFERENCES roles(name);
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users (role) WHERE role <> 'user';
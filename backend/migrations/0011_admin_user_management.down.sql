@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_sessions_user_active;
+DROP INDEX IF EXISTS idx_users_suspended_at;
+DROP INDEX IF EXISTS idx_users_created_at;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip_address,
+    DROP COLUMN IF EXISTS revoked_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS password_reset_required,
+    DROP COLUMN IF EXISTS suspended_reason,
+    DROP COLUMN IF EXISTS suspended_at;
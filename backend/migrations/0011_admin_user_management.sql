@@ -0,0 +1,16 @@
+-- Admin user management: suspension (distinct from self-deactivation via deleted_at),
+-- forced password resets and revocable sessions.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS suspended_reason TEXT,
+    ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS ip_address TEXT,
+    ADD COLUMN IF NOT EXISTS user_agent TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_users_suspended_at ON users (suspended_at) WHERE suspended_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_sessions_user_active ON sessions (user_id) WHERE revoked_at IS NULL;
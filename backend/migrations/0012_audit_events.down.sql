@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission = 'audit.read';
+DELETE FROM permissions WHERE name = 'audit.read';
+
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS audit_events_protect();
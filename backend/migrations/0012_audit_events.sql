@@ -0,0 +1,43 @@
+-- Append-only audit trail. updated_by/deleted_at only keep the latest change; every
+-- change is recorded here with its actor, origin and field-level diff.
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor_id UUID,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT,
+    ip_address TEXT,
+    request_id TEXT,
+    changes JSONB,
+    metadata JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events (occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events (actor_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events (target_type, target_id, occurred_at DESC);
+
+-- Rows can never be updated, and can only be deleted by the retention purge, which sets
+-- audit.allow_purge for its own transaction.
+CREATE OR REPLACE FUNCTION audit_events_protect() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('audit.allow_purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_protect();
+
+INSERT INTO permissions (name, description) VALUES
+    ('audit.read', 'View the audit log')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'audit.read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,18 @@
+-- Idempotency keys for unsafe POSTs. A row is claimed (status NULL) while the first
+-- request runs and then holds its response until expires_at, so retries with the same
+-- key replay it instead of repeating the side effects.
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    status INTEGER,
+    header JSONB,
+    body BYTEA,
+    locked_until TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
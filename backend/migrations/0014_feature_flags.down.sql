@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'feature_flags.manage';
+DELETE FROM permissions WHERE name = 'feature_flags.manage';
+DROP TABLE IF EXISTS feature_flags;
@@ -0,0 +1,28 @@
+-- Runtime feature flags. Flags are created by migrations alongside the code that checks
+-- them; admins only change their targeting.
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    rollout_percentage INT NOT NULL DEFAULT 0 CHECK (rollout_percentage BETWEEN 0 AND 100),
+    allowed_user_ids JSONB NOT NULL DEFAULT '[]',
+    cities JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO feature_flags (key, description) VALUES
+    ('chat', 'Buyer and seller chat on listings'),
+    ('two_factor_auth', 'Two-factor authentication at sign-in'),
+    ('new_search', 'New listing search')
+ON CONFLICT (key) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('feature_flags.manage', 'View and change feature flags')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'feature_flags.manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations so the server binary can apply
+// them without the source tree.
+package migrations
+
+import "embed"
+
+// FS holds NNNN_name.sql (up) and NNNN_name.down.sql (down) files.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,9 @@
+// Package seeds embeds the idempotent reference data seeds.
+package seeds
+
+import "embed"
+
+// FS holds NNN_name.sql files, applied in name order.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -1,5 +1,6 @@
 import type { PublicUser, UpdateProfileRequest } from '../types/user'
 import { logDebug, logError, logInfo } from '../utils/logger'
+import { toApiError } from './errors'
 
 export interface AuthPayload {
   token: string
@@ -25,6 +26,8 @@ export interface ConfirmPasswordResetRequest {
 
 interface MeResponse {
   user: PublicUser
+  // Feature flags evaluated for the user, by key. Only GET /api/v1/auth/me returns them.
+  flags?: Record<string, boolean>
 }
 
 const API_BASE = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080'
@@ -37,6 +40,10 @@ const mockPasswordResetOtps: Record<string, { otp: string; expiresAt: number }>
 const mockPasswordResetLastRequest: Record<string, number> = {}
 const MOCK_COOLDOWN_MS = 5 * 60 * 1000 // 5 minutes
 
+// ETag of the last profile version read or written. Profile updates send it as If-Match
+// so a stale tab cannot overwrite newer changes.
+let profileETag: string | null = null
+
 function mockDelay(): Promise<void> {
   return new Promise((resolve) => setTimeout(resolve, 400))
 }
@@ -70,25 +77,23 @@ async function request<TResponse>(path: string, options: RequestInit = {}): Prom
   const durationMs = Math.round(performance.now() - startedAt)
 
   if (!response.ok) {
-    const message =
-      typeof data === 'object' &&
-      data !== null &&
-      'error' in data &&
-      typeof (data as { error?: unknown }).error === 'string'
-        ? (data as { error: string }).error
-        : 'Request failed'
-
+    const error = toApiError(response.status, data)
     logError('api.request.failed', {
       path,
       method,
       status: response.status,
       duration_ms: durationMs,
-      error: message
+      code: error.code,
+      request_id: error.requestId,
+      error: error.message
     })
-    throw new Error(message)
+    throw error
   }
 
   logInfo('api.request.success', { path, method, status: response.status, duration_ms: durationMs })
+  if (path === '/api/v1/auth/me' || path === '/api/v1/users/me') {
+    profileETag = response.headers.get('ETag')
+  }
   return data as TResponse
 }
 
@@ -269,7 +274,8 @@ export function updateProfile(token: string, updates: UpdateProfileRequest): Pro
   return request<MeResponse>('/api/v1/users/me', {
     method: 'PATCH',
     headers: {
-      Authorization: `Bearer ${token}`
+      Authorization: `Bearer ${token}`,
+      ...(profileETag ? { 'If-Match': profileETag } : {})
     },
     body: JSON.stringify(updates)
   })
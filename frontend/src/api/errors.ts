@@ -0,0 +1,50 @@
+/**
+ * API errors are RFC 9457 problem details (application/problem+json).
+ * Switch on `code`; `message` is the human-readable detail.
+ */
+
+export interface FieldError {
+  field: string
+  code: string
+  message: string
+}
+
+interface ProblemDetails {
+  status?: number
+  code?: string
+  detail?: string
+  title?: string
+  request_id?: string
+  errors?: FieldError[]
+}
+
+export class ApiError extends Error {
+  readonly status: number
+  readonly code: string
+  readonly requestId?: string
+  readonly fieldErrors: FieldError[]
+
+  constructor(status: number, code: string, message: string, requestId?: string, fieldErrors: FieldError[] = []) {
+    super(message)
+    this.name = 'ApiError'
+    this.status = status
+    this.code = code
+    this.requestId = requestId
+    this.fieldErrors = fieldErrors
+  }
+
+  fieldError(field: string): FieldError | undefined {
+    return this.fieldErrors.find((error) => error.field === field)
+  }
+}
+
+export function toApiError(status: number, data: unknown): ApiError {
+  const problem: ProblemDetails = typeof data === 'object' && data !== null ? (data as ProblemDetails) : {}
+  return new ApiError(
+    status,
+    typeof problem.code === 'string' ? problem.code : 'unknown',
+    problem.detail || problem.title || 'Request failed',
+    problem.request_id,
+    Array.isArray(problem.errors) ? problem.errors : []
+  )
+}
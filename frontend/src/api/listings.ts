@@ -10,6 +10,7 @@ import type {
   CreateListingRequest,
   ListingStatus
 } from '../types/listing'
+import { toApiError } from './errors'
 
 const API_BASE = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080'
 const USE_MOCK = import.meta.env.VITE_USE_MOCK === 'true'
@@ -46,14 +47,7 @@ async function request<TResponse>(
   const data: unknown = await response.json().catch(() => ({}))
 
   if (!response.ok) {
-    const message =
-      typeof data === 'object' &&
-      data !== null &&
-      'error' in data &&
-      typeof (data as { error?: unknown }).error === 'string'
-        ? (data as { error: string }).error
-        : 'Request failed'
-    throw new Error(message)
+    throw toApiError(response.status, data)
   }
 
   return data as TResponse
@@ -5,6 +5,7 @@ export interface PublicUser {
   city?: string
   bio?: string
   photo_url?: string
+  role?: 'user' | 'moderator' | 'admin'
 }
 
 export interface UpdateProfileRequest {